@@ -0,0 +1,134 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"sync"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// maxPooledBufferSize is the largest buffer that we will put back into
+// the pool. Anything bigger is left for the garbage collector, so that
+// one large output does not pin its memory for the life of the program.
+const maxPooledBufferSize = 1024 * 1024
+
+// bufferPool holds the empty buffers that WithPooledBuffers hands out
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(pooledBuffer)
+	},
+}
+
+// pooledBuffer is a TextBuffer that came from our bufferPool
+//
+// we use our own type, so that we never put a buffer into the pool
+// that someone else created
+type pooledBuffer struct {
+	ioextra.TextBuffer
+
+	// true while the buffer is sitting in the pool
+	//
+	// this stops us putting the same buffer into the pool twice
+	inPool bool
+}
+
+// WithPooledBuffers returns a PipeOption that makes the pipe take its
+// Stdout and Stderr buffers from a pool shared by all pipes, instead of
+// allocating new ones each time.
+//
+// The pipe puts buffers back into the pool when ResetBuffers,
+// SetNewStdout, SetNewStderr and MoveStdoutToStdin replace them, as long
+// as the pipe is not still using them somewhere else. Once a buffer has
+// been replaced, do not keep using it: it may already belong to another
+// pipe.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithPooledBuffers() PipeOption {
+	return WithBufferFactory(newPooledBuffer)
+}
+
+// newPooledBuffer is a BufferFactory that takes its buffers from our
+// bufferPool
+func newPooledBuffer() ioextra.TextReaderWriter {
+	retval := bufferPool.Get().(*pooledBuffer)
+	retval.inPool = false
+
+	return retval
+}
+
+// releaseBuffer puts the given buffer back into our bufferPool, if it
+// came from there, and if the pipe is no longer using it
+func (p *Pipe) releaseBuffer(buf interface{}) {
+	pooled, ok := buf.(*pooledBuffer)
+	if !ok || pooled.inPool || pooled.Cap() > maxPooledBufferSize || p.isUsingBuffer(pooled) {
+		return
+	}
+
+	pooled.Reset()
+	pooled.inPool = true
+	bufferPool.Put(pooled)
+}
+
+// isUsingBuffer returns true if the given buffer is the pipe's Stdin,
+// Stdout or Stderr, or is on any of the pipe's stacks
+func (p *Pipe) isUsingBuffer(buf interface{}) bool {
+	if p.Stdin == buf || p.Stdout == buf || p.Stderr == buf {
+		return true
+	}
+	for _, stdin := range p.stdinStack {
+		if stdin == buf {
+			return true
+		}
+	}
+	for _, stdout := range p.stdoutStack {
+		if stdout == buf {
+			return true
+		}
+	}
+	for _, stderr := range p.stderrStack {
+		if stderr == buf {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPooledBuffersRunsAPipeline(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithPooledBuffers())
+	unit.SetStdinFromString("hello world\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for i := 0; i < 3; i++ {
+		unit.RunCommand(upperCaseStep)
+		unit.MoveStdoutToStdin()
+		unit.SetNewStderr()
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "HELLO WORLD!!!\n", unit.Stdin.String())
+	assert.Equal(t, "", unit.Stdout.String())
+	assert.Equal(t, "", unit.Stderr.String())
+}
+
+func TestWithPooledBuffersEmptiesRecycledBuffers(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithPooledBuffers())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for i := 0; i < 10; i++ {
+		unit.Stdout.WriteString("left behind")
+		unit.Stderr.WriteString("left behind")
+		unit.ResetBuffers()
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "", unit.Stdout.String())
+	assert.Equal(t, "", unit.Stderr.String())
+}
+
+func TestWithPooledBuffersDoesNotRecycleBuffersStillInUse(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithPooledBuffers())
+	unit.Stdout.WriteString("keep me")
+	saved := unit.Stdout
+	unit.PushStdout(saved)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	// the old Stdout is still on the stack, so it must not go back
+	// into the pool
+	unit.SetNewStdout()
+	for i := 0; i < 10; i++ {
+		other := pipe.NewPipe(pipe.WithPooledBuffers())
+		other.Stdout.WriteString("overwritten")
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	unit.PopStdout()
+	assert.Same(t, saved, unit.Stdout)
+	assert.Equal(t, "keep me", unit.Stdout.String())
+}
+
+// upperCaseStep is a PipeCommand that upper-cases its input, and adds
+// a '!' to the end of each line
+func upperCaseStep(p *pipe.Pipe) (int, error) {
+	for line := range p.Stdin.ReadLines() {
+		p.Stdout.WriteString(strings.ToUpper(line))
+		p.Stdout.WriteString("!\n")
+	}
+
+	return pipe.StatusOkay, nil
+}
+
+// ================================================================
+//
+// Benchmarks
+//
+// ----------------------------------------------------------------
+
+// copyStep is a PipeCommand that copies its input to its output
+func copyStep(p *pipe.Pipe) (int, error) {
+	p.DrainStdinToStdout()
+	return pipe.StatusOkay, nil
+}
+
+var benchmarkInput = strings.Repeat("the quick brown fox jumps over the lazy dog\n", 100)
+
+func BenchmarkPipelineHandoffWithStringCopy(b *testing.B) {
+	p := pipe.NewPipe()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.SetStdinFromString(benchmarkInput)
+		for step := 0; step < 5; step++ {
+			p.RunCommand(copyStep)
+			p.SetStdinFromString(p.Stdout.String())
+			p.SetNewStdout()
+			p.SetNewStderr()
+		}
+		p.ResetBuffers()
+	}
+}
+
+func BenchmarkPipelineHandoffWithPooledBuffers(b *testing.B) {
+	p := pipe.NewPipe(pipe.WithPooledBuffers())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Stdout.WriteString(benchmarkInput)
+		for step := 0; step < 5; step++ {
+			p.MoveStdoutToStdin()
+			p.SetNewStderr()
+			p.RunCommand(copyStep)
+		}
+		p.ResetBuffers()
+	}
+}
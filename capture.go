@@ -0,0 +1,292 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// OutputStream says which of the pipe's outputs a write went to.
+type OutputStream int
+
+const (
+	// StdoutStream is the pipe's Stdout
+	StdoutStream OutputStream = iota
+
+	// StderrStream is the pipe's Stderr
+	StderrStream
+)
+
+// String returns the name of the stream, as "stdout" or "stderr".
+func (s OutputStream) String() string {
+	if s == StderrStream {
+		return "stderr"
+	}
+
+	return "stdout"
+}
+
+// CapturedWrite holds a single write to the pipe's Stdout or Stderr, as
+// seen by a Capture.
+type CapturedWrite struct {
+	// Seq is the position of this write in the Capture, starting at 1
+	Seq uint64
+
+	// Stream is where the write went
+	Stream OutputStream
+
+	// Time is when the write happened
+	Time time.Time
+
+	// Command is the name of the PipeCommand that made the write, as
+	// seen by the middleware
+	Command string
+
+	// Data is what was written
+	Data string
+}
+
+// Capture is a Middleware that records every write that a PipeCommand
+// makes to the pipe's Stdout and Stderr, in the order that they happen.
+//
+// Add it to your pipe using WithCapture:
+//
+//	capture := pipe.NewCapture()
+//	p := pipe.NewPipe(pipe.WithCapture(capture))
+//
+// The writes still go to the pipe's Stdout and Stderr, as normal.
+//
+// If the pipe's Stdout and Stderr are the same, every write is recorded
+// as going to StdoutStream.
+//
+// PipeCommands that run on child pipes (see NewChildPipe) are not
+// recorded on their own. Their output is recorded when the parent
+// pipe's PipeCommand copies it into the parent pipe, so that it is
+// only recorded once.
+type Capture struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// writes holds everything we have captured, oldest first
+	writes []CapturedWrite
+
+	// seq is the sequence number of the last write we captured
+	seq uint64
+}
+
+// NewCapture creates a new, empty Capture.
+func NewCapture() *Capture {
+	return &Capture{}
+}
+
+// WithCapture returns a PipeOption that adds the given Capture to the
+// pipe's middleware.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithCapture(c *Capture) PipeOption {
+	return WithMiddleware(c.Middleware)
+}
+
+// Middleware records every write that the given PipeCommand makes.
+//
+// Pass it into WithMiddleware, or use WithCapture.
+func (c *Capture) Middleware(name string, next PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// the parent pipe will record this for us
+		if p.isChildPipe() {
+			return next(p)
+		}
+
+		restore := interceptOutputFor(
+			p,
+			c,
+			c.interceptor(name, StdoutStream),
+			c.interceptor(name, StderrStream),
+		)
+		defer restore()
+
+		return next(p)
+	}
+}
+
+// Writes returns a copy of every write that the Capture has recorded,
+// oldest first.
+func (c *Capture) Writes() []CapturedWrite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	retval := make([]CapturedWrite, len(c.writes))
+	copy(retval, c.writes)
+	return retval
+}
+
+// Combined returns everything written to Stdout and Stderr, as a single
+// string, in the order that it was written.
+func (c *Capture) Combined() string {
+	return c.join(func(OutputStream) bool { return true })
+}
+
+// Stdout returns everything written to Stdout.
+func (c *Capture) Stdout() string {
+	return c.join(func(s OutputStream) bool { return s == StdoutStream })
+}
+
+// Stderr returns everything written to Stderr.
+func (c *Capture) Stderr() string {
+	return c.join(func(s OutputStream) bool { return s == StderrStream })
+}
+
+// Reset throws away everything that the Capture has recorded, and starts
+// the sequence numbers again.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes = nil
+	c.seq = 0
+}
+
+// WriteLog writes everything that the Capture has recorded to w, one
+// line of output per line, in the order that it was written:
+//
+//	2021-06-01T10:00:00.000000Z     1 stdout hello world
+//	2021-06-01T10:00:00.000100Z     2 stderr something went wrong
+//
+// Use it to show people what happened during a pipe run.
+func (c *Capture) WriteLog(w io.Writer) error {
+	for _, write := range c.Writes() {
+		prefix := fmt.Sprintf(
+			"%s %5d %s ",
+			write.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			write.Seq,
+			write.Stream,
+		)
+
+		for _, line := range strings.SplitAfter(write.Data, "\n") {
+			if line == "" {
+				continue
+			}
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+
+			_, err := io.WriteString(w, prefix+line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// all done
+	return nil
+}
+
+// join returns the data from every write that wanted says yes to
+func (c *Capture) join(wanted func(OutputStream) bool) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var retval strings.Builder
+	for _, write := range c.writes {
+		if wanted(write.Stream) {
+			retval.WriteString(write.Data)
+		}
+	}
+
+	return retval.String()
+}
+
+// interceptor returns an outputInterceptor that records every write to
+// the given stream
+func (c *Capture) interceptor(name string, stream OutputStream) outputInterceptor {
+	return func(next ioextra.TextReaderWriter) func(b []byte) (int, error) {
+		// are we already recording this stream? this happens when a
+		// PipeCommand calls RunCommand on its own pipe
+		if isInterceptedBy(next, c) {
+			// yes - let the interceptor further down do the recording,
+			// so that we do not record the same write twice
+			return next.Write
+		}
+
+		return func(b []byte) (int, error) {
+			// we do not hold the lock across the write, because the
+			// write may end up back in here
+			c.mu.Lock()
+			c.seq++
+			seq := c.seq
+			c.mu.Unlock()
+
+			n, err := next.Write(b)
+			if n > 0 {
+				c.record(CapturedWrite{
+					Seq:     seq,
+					Stream:  stream,
+					Time:    time.Now(),
+					Command: name,
+					Data:    string(b[:n]),
+				})
+			}
+
+			return n, err
+		}
+	}
+}
+
+// record adds the given write to our list, keeping the list in Seq order
+func (c *Capture) record(write CapturedWrite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := len(c.writes)
+	for i > 0 && c.writes[i-1].Seq > write.Seq {
+		i--
+	}
+
+	c.writes = append(c.writes, CapturedWrite{})
+	copy(c.writes[i+1:], c.writes[i:])
+	c.writes[i] = write
+}
@@ -0,0 +1,344 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// interleavedCommand writes to the pipe's Stdout and Stderr in turn
+func interleavedCommand(p *pipe.Pipe) (int, error) {
+	p.Stdout.WriteString("one\n")
+	p.Stderr.WriteString("warning: two\n")
+	p.Stdout.WriteString("three\n")
+	p.Stderr.WriteRune('!')
+
+	return pipe.StatusOkay, nil
+}
+
+func TestCaptureRecordsWritesInOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+
+	expectedStreams := []pipe.OutputStream{
+		pipe.StdoutStream,
+		pipe.StderrStream,
+		pipe.StdoutStream,
+		pipe.StderrStream,
+	}
+	expectedData := []string{"one\n", "warning: two\n", "three\n", "!"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("interleaved", interleavedCommand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	writes := capture.Writes()
+	assert.Len(t, writes, len(expectedData))
+	for i, write := range writes {
+		assert.Equal(t, uint64(i+1), write.Seq)
+		assert.Equal(t, expectedStreams[i], write.Stream)
+		assert.Equal(t, expectedData[i], write.Data)
+		assert.Equal(t, "interleaved", write.Command)
+		assert.False(t, write.Time.IsZero())
+		if i > 0 {
+			assert.False(t, write.Time.Before(writes[i-1].Time))
+		}
+	}
+}
+
+func TestCaptureRendersCombinedAndSeparateOutput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(interleavedCommand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "one\nwarning: two\nthree\n!", capture.Combined())
+	assert.Equal(t, "one\nthree\n", capture.Stdout())
+	assert.Equal(t, "warning: two\n!", capture.Stderr())
+
+	// the writes still reach the pipe
+	assert.Equal(t, "one\nthree\n", unit.Stdout.String())
+	assert.Equal(t, "warning: two\n!", unit.Stderr.String())
+}
+
+func TestCaptureKeepsCountingAcrossCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("first", interleavedCommand)
+	unit.RunNamedCommand("second", interleavedCommand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	writes := capture.Writes()
+	assert.Len(t, writes, 8)
+	assert.Equal(t, "first", writes[3].Command)
+	assert.Equal(t, "second", writes[4].Command)
+	assert.Equal(t, uint64(8), writes[7].Seq)
+}
+
+func TestCaptureCopesWithNestedRunCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+
+	inner := func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("inner\n")
+		return pipe.StatusOkay, nil
+	}
+	outer := func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("outer\n")
+		p.RunCommand(inner)
+		p.Stdout.WriteString("done\n")
+		return pipe.StatusOkay, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	finished := make(chan struct{})
+	go func() {
+		unit.RunNamedCommand("outer", outer)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("nested RunCommand deadlocked")
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	writes := capture.Writes()
+	assert.Len(t, writes, 3)
+	assert.Equal(t, "outer\ninner\ndone\n", capture.Stdout())
+	assert.Equal(t, "outer\ninner\ndone\n", unit.Stdout.String())
+	for i, write := range writes {
+		assert.Equal(t, uint64(i+1), write.Seq)
+	}
+}
+
+func TestCaptureCopesWithEncodeStdout(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture), pipe.EncodeStdout(pipe.UTF8))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	finished := make(chan struct{})
+	go func() {
+		unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+			p.Stdout.WriteString("hello\n")
+			return pipe.StatusOkay, nil
+		})
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunCommand deadlocked")
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, capture.Writes(), 1)
+	assert.Equal(t, "hello\n", capture.Stdout())
+}
+
+// bracketArgs returns a PipeCommand that runs a batch for each word of
+// Stdin, on a child pipe, and writes each word in brackets
+func bracketArgs() pipe.PipeCommand {
+	return pipe.Xargs(
+		func(args []string) pipe.PipeCommand {
+			return func(p *pipe.Pipe) (int, error) {
+				p.Stdout.WriteString("[" + strings.Join(args, " ") + "]\n")
+				return pipe.StatusOkay, nil
+			}
+		},
+		pipe.XargsOptions{MaxArgs: 1},
+	)
+}
+
+func TestCaptureRecordsChildPipeOutputOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+	unit.SetStdinFromString("a b c")
+	expectedResult := "[a]\n[b]\n[c]\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("xargs", bracketArgs())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, unit.Stdout.String())
+	assert.Equal(t, expectedResult, capture.Combined())
+	for _, write := range capture.Writes() {
+		assert.Equal(t, "xargs", write.Command)
+	}
+}
+
+func TestCaptureResetStartsAgain(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+	unit.RunCommand(interleavedCommand)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	capture.Reset()
+	unit.RunCommand(interleavedCommand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	writes := capture.Writes()
+	assert.Len(t, writes, 4)
+	assert.Equal(t, uint64(1), writes[0].Seq)
+}
+
+func TestCaptureWriteLogTagsEachLine(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	capture := pipe.NewCapture()
+	unit := pipe.NewPipe(pipe.WithCapture(capture))
+	unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("one\ntwo\n")
+		p.Stderr.WriteString("oops")
+		return pipe.StatusOkay, nil
+	})
+
+	var buf bytes.Buffer
+	timestamp := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z `)
+
+	expectedResult := []string{
+		"    1 stdout one",
+		"    1 stdout two",
+		"    2 stderr oops",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := capture.WriteLog(&buf)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, len(expectedResult))
+	for i, line := range lines {
+		assert.Regexp(t, timestamp, line)
+		assert.Equal(t, expectedResult[i], timestamp.ReplaceAllString(line, ""))
+	}
+}
+
+func TestOutputStreamString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "stdout", pipe.StdoutStream.String())
+	assert.Equal(t, "stderr", pipe.StderrStream.String())
+}
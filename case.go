@@ -0,0 +1,161 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"regexp"
+	"strings"
+
+	glob "github.com/ganbarodigital/go_glob"
+)
+
+// CaseArm is one of the choices that Case can make. Use GlobArm,
+// RegexArm or DefaultArm to create one.
+type CaseArm struct {
+	// match returns true if this arm should run for the given subject
+	match func(subject string) (bool, error)
+
+	// cmd is what we run if we match
+	cmd PipeCommand
+}
+
+// GlobArm returns a CaseArm that runs cmd if the subject matches the
+// given UNIX shell glob pattern.
+//
+// Like a shell's case statement, the pattern has to match the whole of
+// the subject, and `*` matches any character, including `/`.
+func GlobArm(pattern string, cmd PipeCommand) CaseArm {
+	compiled := glob.NewGlob(pattern)
+
+	return CaseArm{
+		match: compiled.Match,
+		cmd:   cmd,
+	}
+}
+
+// RegexArm returns a CaseArm that runs cmd if the subject matches the
+// given regular expression.
+//
+// The regular expression uses the same syntax as the regexp package. It
+// can match anywhere in the subject; use `^` and `$` to anchor it. If it
+// does not compile, Case returns the error when it gets to this arm.
+func RegexArm(pattern string, cmd PipeCommand) CaseArm {
+	compiled, err := regexp.Compile(pattern)
+
+	return CaseArm{
+		match: func(subject string) (bool, error) {
+			if err != nil {
+				return false, err
+			}
+			return compiled.MatchString(subject), nil
+		},
+		cmd: cmd,
+	}
+}
+
+// DefaultArm returns a CaseArm that always runs cmd. Put it last, like
+// `*)` in a shell's case statement.
+func DefaultArm(cmd PipeCommand) CaseArm {
+	return CaseArm{
+		match: func(string) (bool, error) { return true, nil },
+		cmd:   cmd,
+	}
+}
+
+// Case returns a PipeCommand that works like a UNIX shell's case
+// statement.
+//
+// It calls subject to get the string to match, and then tries each arm
+// in order. It runs the PipeCommand of the first arm that matches against
+// the pipe, and returns its status code and error. If no arm matches,
+// Case returns StatusOkay.
+//
+// Use StdinSubject to match on the contents of the pipe's Stdin, or
+// EnvSubject to match on an environment variable.
+func Case(subject func(*Pipe) string, arms ...CaseArm) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		value := subject(p)
+		for _, arm := range arms {
+			matched, err := arm.match(value)
+			if err != nil {
+				return StatusNotOkay, err
+			}
+			if matched {
+				return arm.cmd(p)
+			}
+		}
+
+		// nothing matched
+		return StatusOkay, nil
+	}
+}
+
+// StdinSubject returns the contents of the pipe's Stdin, without any
+// trailing newlines, for Case to match against. This is the same as
+// `case "$(cat)" in` in a shell script.
+//
+// It puts the contents back into Stdin afterwards, so that the
+// PipeCommand that Case runs can read them too.
+func StdinSubject(p *Pipe) string {
+	stdin, err := p.StdinBytes()
+	if err != nil {
+		return ""
+	}
+	p.SetStdinFromBytes(stdin)
+
+	return strings.TrimRight(string(stdin), "\n")
+}
+
+// EnvSubject returns a function that gets the named variable from the
+// pipe's environment, for Case to match against.
+func EnvSubject(name string) func(*Pipe) string {
+	return func(p *Pipe) string {
+		if p.Env == nil {
+			return ""
+		}
+
+		return p.Env.Getenv(name)
+	}
+}
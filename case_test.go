@@ -0,0 +1,182 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCase(t *testing.T) {
+	t.Parallel()
+
+	arms := []pipe.CaseArm{
+		pipe.GlobArm("*.go", echo("go source")),
+		pipe.GlobArm("[Mm]akefile", echo("makefile")),
+		pipe.RegexArm(`^v\d+\.\d+\.\d+$`, echo("version")),
+		pipe.DefaultArm(echo("something else")),
+	}
+
+	testCases := []struct {
+		subject        string
+		expectedStdout string
+	}{
+		{"main.go", "go source\n"},
+		{"cmd/tool/main.go", "go source\n"},
+		{"main.go.orig", "something else\n"},
+		{"Makefile", "makefile\n"},
+		{"makefile", "makefile\n"},
+		{"v1.2.3", "version\n"},
+		{"v1.2", "something else\n"},
+		{"", "something else\n"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.subject, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe(pipe.WithEmptyEnv)
+			unit.Env.Setenv("FILE", testCase.subject)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.Case(pipe.EnvSubject("FILE"), arms...))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, unit.Error())
+			assert.Equal(t, testCase.expectedStdout, unit.Stdout.String())
+		})
+	}
+}
+
+func TestCaseRunsTheFirstArmThatMatches(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello world\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Case(
+		pipe.StdinSubject,
+		pipe.GlobArm("hello*", exitWith(3)),
+		pipe.RegexArm("world", exitWith(4)),
+	))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 3, unit.StatusCode())
+}
+
+func TestCaseDoesNothingWhenNoArmMatches(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello world\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Case(pipe.StdinSubject, pipe.GlobArm("goodbye*", echo("bye"))))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestCaseArmsCanReadStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello world\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Case(pipe.StdinSubject, pipe.GlobArm("hello world", upperCaseStep)))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "HELLO WORLD!\n", unit.Stdout.String())
+}
+
+func TestCaseReturnsAnErrorForBadRegexes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Case(pipe.StdinSubject, pipe.RegexArm("(", echo("never"))))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.Error(t, unit.Error())
+}
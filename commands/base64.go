@@ -0,0 +1,200 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Base64 returns a PipeCommand that base64-encodes the pipe's Stdin, and
+// writes the result to the pipe's Stdout.
+//
+// It supports these options:
+//
+//	-d       decode instead of encode
+//	-i       when decoding, ignore anything that is not base64
+//	-w COLS  wrap encoded lines after COLS characters (default 76);
+//	         use 0 to turn off wrapping
+//
+// Line breaks are always ignored when decoding.
+func Base64(args ...string) pipe.PipeCommand {
+	const cmdName = "base64"
+
+	opts, operands, err := getopt(cmdName, "diw:", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	decode := false
+	ignoreGarbage := false
+	wrap := 76
+	for _, opt := range opts {
+		switch opt.name {
+		case 'd':
+			decode = true
+		case 'i':
+			ignoreGarbage = true
+		case 'w':
+			wrap, err = strconv.Atoi(opt.value)
+			if err != nil || wrap < 0 {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("invalid wrap size: '%s'", opt.value)})
+			}
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		var err error
+		if decode {
+			var input io.Reader = p.Stdin
+			if ignoreGarbage {
+				input = &base64Filter{r: bufio.NewReader(p.Stdin)}
+			}
+			_, err = io.Copy(p.Stdout, base64.NewDecoder(base64.StdEncoding, input))
+		} else {
+			output := &lineWrapper{w: p.Stdout, width: wrap}
+			encoder := base64.NewEncoder(base64.StdEncoding, output)
+			_, err = io.Copy(encoder, p.Stdin)
+			if err == nil {
+				err = encoder.Close()
+			}
+			if err == nil {
+				err = output.Close()
+			}
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// lineWrapper breaks what is written to it into lines of the given
+// width
+//
+// If width is 0, it does not add any line breaks at all.
+type lineWrapper struct {
+	w     io.Writer
+	width int
+	col   int
+}
+
+func (l *lineWrapper) Write(buf []byte) (int, error) {
+	if l.width == 0 {
+		return l.w.Write(buf)
+	}
+
+	written := 0
+	for len(buf) > 0 {
+		// do we need to start a new line?
+		if l.col == l.width {
+			_, err := l.w.Write([]byte{'\n'})
+			if err != nil {
+				return written, err
+			}
+			l.col = 0
+		}
+
+		n := minInt(l.width-l.col, len(buf))
+		n, err := l.w.Write(buf[:n])
+		written += n
+		l.col += n
+		if err != nil {
+			return written, err
+		}
+		buf = buf[n:]
+	}
+
+	return written, nil
+}
+
+// Close ends the last line
+func (l *lineWrapper) Close() error {
+	if l.col == 0 {
+		return nil
+	}
+
+	_, err := l.w.Write([]byte{'\n'})
+	return err
+}
+
+// base64Filter drops anything that is not part of the base64 alphabet
+type base64Filter struct {
+	r *bufio.Reader
+}
+
+func (f *base64Filter) Read(buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		c, err := f.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		switch {
+		case c >= 'A' && c <= 'Z',
+			c >= 'a' && c <= 'z',
+			c >= '0' && c <= '9',
+			c == '+', c == '/', c == '=':
+			buf[n] = c
+			n++
+		}
+	}
+
+	return n, nil
+}
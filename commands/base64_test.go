@@ -0,0 +1,115 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestBase64(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Base64), []pipetest.Case{
+		{
+			Name:       "encodes Stdin",
+			Stdin:      "hello\n",
+			WantStdout: "aGVsbG8K\n",
+		},
+		{
+			Name:       "encodes binary data",
+			Stdin:      "\x00\xff\xfe\r\n",
+			WantStdout: "AP/+DQo=\n",
+		},
+		{
+			Name:       "wraps at 76 columns by default",
+			Stdin:      strings.Repeat("x", 60),
+			WantStdout: strings.Repeat("eHh4", 19) + "\n" + "eHh4\n",
+		},
+		{
+			Name:       "does not add an extra line when the output fills the last line",
+			Args:       []string{"-w", "4"},
+			Stdin:      "xxxxxx",
+			WantStdout: "eHh4\neHh4\n",
+		},
+		{
+			Name:       "-w 0 turns off wrapping",
+			Args:       []string{"-w0"},
+			Stdin:      strings.Repeat("x", 60),
+			WantStdout: strings.Repeat("eHh4", 20),
+		},
+		{
+			Name:       "writes nothing for empty input",
+			WantStdout: "",
+		},
+		{
+			Name:       "-d decodes Stdin",
+			Args:       []string{"-d"},
+			Stdin:      "AP/+\nDQo=\n",
+			WantStdout: "\x00\xff\xfe\r\n",
+		},
+		{
+			Name:       "-d rejects invalid input",
+			Args:       []string{"-d"},
+			Stdin:      "aGVs*bG8K\n",
+			WantStdout: "hel",
+			WantStderr: "base64: illegal base64 data at input byte 4\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"base64", base64.CorruptInputError(4)},
+		},
+		{
+			Name:       "-i ignores invalid input",
+			Args:       []string{"-di"},
+			Stdin:      "aGVs*bG8K\n",
+			WantStdout: "hello\n",
+		},
+		{
+			Name:       "rejects invalid wrap sizes",
+			Args:       []string{"-w", "wide"},
+			WantStderr: "base64: invalid wrap size: 'wide'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"base64", "invalid wrap size: 'wide'"},
+		},
+	})
+}
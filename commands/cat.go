@@ -0,0 +1,125 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Cat returns a PipeCommand that copies the pipe's Stdin to its Stdout.
+//
+// It supports these options:
+//
+//	-b  number the non-blank output lines
+//	-E  show a '$' at the end of each line
+//	-n  number all output lines
+//	-s  squeeze repeated blank lines into one
+//	-u  ignored (output is never buffered)
+func Cat(args ...string) pipe.PipeCommand {
+	const cmdName = "cat"
+
+	opts, operands, err := getopt(cmdName, "bEnsu", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var numberAll, numberNonBlank, showEnds, squeeze bool
+	for _, opt := range opts {
+		switch opt.name {
+		case 'b':
+			numberNonBlank = true
+		case 'E':
+			showEnds = true
+		case 'n':
+			numberAll = true
+		case 's':
+			squeeze = true
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		// do we need to look at the lines at all?
+		if !numberAll && !numberNonBlank && !showEnds && !squeeze {
+			_, err := io.Copy(p.Stdout, p.Stdin)
+			if err != nil {
+				return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+			}
+			return pipe.StatusOkay, nil
+		}
+
+		lineNo := 0
+		prevBlank := false
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			blank := line == "" && eol
+			if squeeze && blank && prevBlank {
+				return nil
+			}
+			prevBlank = blank
+
+			if numberNonBlank && !blank || numberAll && !numberNonBlank {
+				lineNo++
+				line = fmt.Sprintf("%6d\t%s", lineNo, line)
+			}
+			if showEnds && eol {
+				line += "$"
+			}
+
+			writeLine(p.Stdout, line, eol)
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,104 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestCat(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Cat), []pipetest.Case{
+		{
+			Name:       "copies stdin to stdout",
+			Stdin:      "one\ntwo",
+			WantStdout: "one\ntwo",
+		},
+		{
+			Name:       "accepts - as stdin",
+			Args:       []string{"-u", "-"},
+			Stdin:      "one\n",
+			WantStdout: "one\n",
+		},
+		{
+			Name:       "-n numbers all lines",
+			Args:       []string{"-n"},
+			Stdin:      "one\n\ntwo\n",
+			WantStdout: "     1\tone\n     2\t\n     3\ttwo\n",
+		},
+		{
+			Name:       "-b numbers non-blank lines",
+			Args:       []string{"-b"},
+			Stdin:      "one\n\ntwo\n",
+			WantStdout: "     1\tone\n\n     2\ttwo\n",
+		},
+		{
+			Name:       "-s squeezes blank lines",
+			Args:       []string{"-s"},
+			Stdin:      "one\n\n\n\ntwo\n",
+			WantStdout: "one\n\ntwo\n",
+		},
+		{
+			Name:       "-E shows line endings",
+			Args:       []string{"-E"},
+			Stdin:      "one\ntwo",
+			WantStdout: "one$\ntwo",
+		},
+		{
+			Name:       "rejects unknown options",
+			Args:       []string{"-z"},
+			Stdin:      "one\n",
+			WantStderr: "cat: invalid option -- 'z'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"cat", "invalid option -- 'z'"},
+		},
+		{
+			Name:       "rejects file operands",
+			Args:       []string{"/etc/passwd"},
+			WantStderr: "cat: file operands are not supported: '/etc/passwd'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"cat", "file operands are not supported: '/etc/passwd'"},
+		},
+	})
+}
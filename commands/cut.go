@@ -0,0 +1,234 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// cutRange is a single range from a cut list; end is 0 if the range
+// is open-ended
+type cutRange struct {
+	start int
+	end   int
+}
+
+// cutList is a parsed cut list, eg "1,3-5,7-"
+type cutList []cutRange
+
+// contains returns true if n (counting from 1) is in the list
+func (l cutList) contains(n int) bool {
+	for _, r := range l {
+		if n >= r.start && (r.end == 0 || n <= r.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cut returns a PipeCommand that copies selected parts of each line
+// of the pipe's Stdin to its Stdout.
+//
+// It supports these options:
+//
+//	-b list   select these bytes
+//	-c list   select these characters
+//	-d delim  use 'delim' as the field delimiter (default: tab)
+//	-f list   select these fields
+//	-n        ignored
+//	-s        with -f, skip lines that do not contain the delimiter
+//
+// You must use exactly one of -b, -c or -f. A list is a comma-separated
+// list of numbers and ranges, eg "1,3-5,7-".
+func Cut(args ...string) pipe.PipeCommand {
+	const cmdName = "cut"
+
+	opts, operands, err := getopt(cmdName, "b:c:d:f:ns", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var mode byte
+	var list cutList
+	delim := "\t"
+	onlyDelimited := false
+	haveDelim := false
+	for _, opt := range opts {
+		switch opt.name {
+		case 'b', 'c', 'f':
+			if mode != 0 {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "only one type of list may be specified"})
+			}
+			mode = opt.name
+			list, err = parseCutList(opt.value)
+			if err != nil {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+			}
+		case 'd':
+			if utf8.RuneCountInString(opt.value) != 1 {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "the delimiter must be a single character"})
+			}
+			delim = opt.value
+			haveDelim = true
+		case 's':
+			onlyDelimited = true
+		}
+	}
+
+	switch {
+	case mode == 0:
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "you must specify a list of bytes, characters, or fields"})
+	case mode != 'f' && (haveDelim || onlyDelimited):
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "-d and -s only make sense when cutting fields"})
+	}
+
+	// cutLine returns the parts of the line that we want, and whether or
+	// not we want the line at all
+	cutLine := func(line string) (string, bool) {
+		var buf strings.Builder
+
+		switch mode {
+		case 'b':
+			for i := 0; i < len(line); i++ {
+				if list.contains(i + 1) {
+					buf.WriteByte(line[i])
+				}
+			}
+		case 'c':
+			i := 0
+			for _, r := range line {
+				i++
+				if list.contains(i) {
+					buf.WriteRune(r)
+				}
+			}
+		case 'f':
+			if !strings.Contains(line, delim) {
+				return line, !onlyDelimited
+			}
+
+			var fields []string
+			for i, field := range strings.Split(line, delim) {
+				if list.contains(i + 1) {
+					fields = append(fields, field)
+				}
+			}
+			buf.WriteString(strings.Join(fields, delim))
+		}
+
+		return buf.String(), true
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			newLine, ok := cutLine(line)
+			if ok {
+				p.Stdout.WriteString(newLine + "\n")
+			}
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// parseCutList parses a list such as "1,3-5,7-"
+func parseCutList(list string) (cutList, error) {
+	var retval cutList
+
+	for _, part := range strings.Split(list, ",") {
+		var r cutRange
+		var err error
+
+		// parseNumber parses one end of a range
+		parseNumber := func(s string) int {
+			n, parseErr := strconv.Atoi(s)
+			if parseErr != nil || n < 1 {
+				err = fmt.Errorf("invalid byte, character or field list: '%s'", list)
+			}
+			return n
+		}
+
+		dash := strings.IndexByte(part, '-')
+		switch {
+		case dash < 0:
+			r.start = parseNumber(part)
+			r.end = r.start
+		case part == "-":
+			err = fmt.Errorf("invalid range with no endpoint: -")
+		case dash == 0:
+			r.start = 1
+			r.end = parseNumber(part[1:])
+		case dash == len(part)-1:
+			r.start = parseNumber(part[:dash])
+		default:
+			r.start = parseNumber(part[:dash])
+			r.end = parseNumber(part[dash+1:])
+			if err == nil && r.end < r.start {
+				err = fmt.Errorf("invalid decreasing range: '%s'", part)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, r)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,112 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestCut(t *testing.T) {
+	t.Parallel()
+
+	input := "x:y:z\nno delims here\n1:2:3:4:5\n"
+
+	pipetest.RunCases(t, withArgs(commands.Cut), []pipetest.Case{
+		{
+			Name:       "-c selects characters",
+			Args:       []string{"-c", "2-4"},
+			Stdin:      "héllo\n",
+			WantStdout: "éll\n",
+		},
+		{
+			Name:       "-b selects bytes",
+			Args:       []string{"-b", "1,3"},
+			Stdin:      "abcdef\n",
+			WantStdout: "ac\n",
+		},
+		{
+			Name:       "-f selects fields",
+			Args:       []string{"-d:", "-f2"},
+			Stdin:      input,
+			WantStdout: "y\nno delims here\n2\n",
+		},
+		{
+			Name:       "-f supports open-ended ranges",
+			Args:       []string{"-d", ":", "-f", "1,3-"},
+			Stdin:      input,
+			WantStdout: "x:z\nno delims here\n1:3:4:5\n",
+		},
+		{
+			Name:       "-s skips lines without the delimiter",
+			Args:       []string{"-d:", "-s", "-f", "-2"},
+			Stdin:      input,
+			WantStdout: "x:y\n1:2\n",
+		},
+		{
+			Name:       "uses tab as the default delimiter",
+			Args:       []string{"-f", "2"},
+			Stdin:      "a\tb\tc\n",
+			WantStdout: "b\n",
+		},
+		{
+			Name:       "needs a list",
+			WantStderr: "cut: you must specify a list of bytes, characters, or fields\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"cut", "you must specify a list of bytes, characters, or fields"},
+		},
+		{
+			Name:       "only accepts one list",
+			Args:       []string{"-b", "1", "-f", "1"},
+			WantStderr: "cut: only one type of list may be specified\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"cut", "only one type of list may be specified"},
+		},
+		{
+			Name:       "rejects decreasing ranges",
+			Args:       []string{"-c", "3-1"},
+			WantStderr: "cut: invalid decreasing range: '3-1'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"cut", "invalid decreasing range: '3-1'"},
+		},
+	})
+}
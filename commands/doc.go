@@ -0,0 +1,99 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package commands provides the classic UNIX text-processing commands, and
+a few of their binary-data friends, as PipeCommands.
+
+Each command is a constructor that takes the same command-line arguments
+as its UNIX namesake, and returns a PipeCommand:
+
+	p := pipe.NewPipe()
+	p.SetStdinFromString(input)
+
+	p.RunCommand(commands.Grep("-i", "error"))
+
+The commands only ever work with the pipe: they read from p.Stdin, write
+their results to p.Stdout, and write their diagnostics to p.Stderr. They
+never touch the filesystem, so the only file operand that they accept is
+"-" (ie, the pipe's Stdin).
+
+Where the command allows it, input is processed a line at a time, and
+output is written as soon as it is ready.
+
+# Commands
+
+	Cat        cat [-nbsuE]
+	Head       head [-n lines | -c bytes]
+	Tail       tail [-n [+]lines | -c [+]bytes]
+	Grep       grep [-EFGcilnoqsvwx] [-e pattern]... [pattern]
+	Sort       sort [-bcCdfinrsu] [-t char] [-k keydef]...
+	Uniq       uniq [-cdiu] [-f fields] [-s chars]
+	Wc         wc [-clmw]
+	Cut        cut -b list | -c list | -f list [-d delim] [-s]
+	Tr         tr [-cCds] set1 [set2]
+	Sed        sed [-nE] [-e script]... [script]
+	Rev        rev
+	Tac        tac
+	Nl         nl [-b type] [-i incr] [-n format] [-s sep] [-v start] [-w width]
+	Gzip       gzip [-cd] [-1 ... -9]
+	Gunzip     gunzip [-c]
+	Base64     base64 [-di] [-w cols]
+	Md5sum     md5sum [-bt]
+	Sha1sum    sha1sum [-bt]
+	Sha256sum  sha256sum [-bt]
+	Sha512sum  sha512sum [-bt]
+	Iconv      iconv [-c] [-f encoding] [-t encoding]
+
+Sed only supports the s/// command.
+
+Gzip, Gunzip, Base64 and the checksum commands work on the raw bytes in
+the pipe. Nothing is done to line endings, so they are safe to use with
+binary data.
+
+# Status Codes
+
+The commands return the same status codes as their UNIX namesakes. In
+particular, Grep returns 1 when no lines match, and 2 when something went
+wrong. Sort returns 1 when -c finds that its input is not sorted.
+
+When a command is given arguments that it does not understand, it writes
+a message to p.Stderr, and returns an ErrUsage.
+*/
+package commands
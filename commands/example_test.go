@@ -0,0 +1,95 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"fmt"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+)
+
+func ExampleGrep() {
+	p := pipe.NewPipe()
+	p.SetStdinFromString("INFO starting\nERROR disk full\nINFO stopping\n")
+
+	p.RunCommand(commands.Grep("-n", "ERROR"))
+
+	fmt.Print(p.Stdout.String())
+	fmt.Println(p.StatusCode())
+	// Output:
+	// 2:ERROR disk full
+	// 0
+}
+
+func ExampleSort() {
+	p := pipe.NewPipe()
+	p.SetStdinFromString("pear 3\napple 12\nfig 7\n")
+
+	p.RunCommand(commands.Sort("-k2n"))
+
+	fmt.Print(p.Stdout.String())
+	// Output:
+	// pear 3
+	// fig 7
+	// apple 12
+}
+
+func ExampleSed() {
+	p := pipe.NewPipe()
+	p.SetStdinFromString("hello world\n")
+
+	p.RunCommand(commands.Sed(`s/\(hello\) \(world\)/\2, \1/`))
+
+	fmt.Print(p.Stdout.String())
+	// Output:
+	// world, hello
+}
+
+func ExampleSha256sum() {
+	p := pipe.NewPipe()
+	p.SetStdinFromBytes([]byte{0x1f, 0x8b, 0x08, 0x00})
+
+	p.RunCommand(commands.Sha256sum())
+
+	fmt.Print(p.Stdout.String())
+	// Output:
+	// fd72d30440b0bae1b1c6db6c8ad807f238ef3ca613aa7e8d5329e1e8ddf7da72  -
+}
@@ -0,0 +1,284 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// grep's status codes
+const (
+	grepStatusMatch   = 0
+	grepStatusNoMatch = 1
+	grepStatusError   = 2
+)
+
+// Grep returns a PipeCommand that copies the lines of the pipe's Stdin
+// that match a pattern to its Stdout.
+//
+// It supports these options:
+//
+//	-E          patterns are extended regular expressions
+//	-F          patterns are fixed strings
+//	-G          patterns are basic regular expressions (the default)
+//	-c          only write a count of the matching lines
+//	-e pattern  use 'pattern'; can be given more than once
+//	-i          ignore case
+//	-l          only write "(standard input)" if any line matches
+//	-n          put the line number in front of each matching line
+//	-o          only write the matching parts of each line
+//	-q          write nothing; stop at the first match
+//	-s          ignored (there are no files to complain about)
+//	-v          select the lines that do not match
+//	-w          only match whole words
+//	-x          only match whole lines
+//
+// If there is no -e option, the first operand is the pattern.
+//
+// Grep returns status code 0 if any lines were selected, 1 if no lines
+// were selected, and 2 if something went wrong.
+func Grep(args ...string) pipe.PipeCommand {
+	const cmdName = "grep"
+
+	opts, operands, err := getopt(cmdName, "EFGce:ilnoqsvwx", args)
+	if err != nil {
+		return usageCommand(grepStatusError, err)
+	}
+
+	var extended, fixed, countOnly, ignoreCase, listOnly, lineNumbers bool
+	var onlyMatching, quiet, invert, wordMatch, lineMatch bool
+	var patterns []string
+	havePatterns := false
+	for _, opt := range opts {
+		switch opt.name {
+		case 'E':
+			extended, fixed = true, false
+		case 'F':
+			extended, fixed = false, true
+		case 'G':
+			extended, fixed = false, false
+		case 'c':
+			countOnly = true
+		case 'e':
+			havePatterns = true
+			patterns = append(patterns, strings.Split(opt.value, "\n")...)
+		case 'i':
+			ignoreCase = true
+		case 'l':
+			listOnly = true
+		case 'n':
+			lineNumbers = true
+		case 'o':
+			onlyMatching = true
+		case 'q':
+			quiet = true
+		case 'v':
+			invert = true
+		case 'w':
+			wordMatch = true
+		case 'x':
+			lineMatch = true
+		}
+	}
+
+	// is the pattern an operand?
+	if !havePatterns {
+		if len(operands) == 0 {
+			return usageCommand(grepStatusError, ErrUsage{cmdName, "no pattern given"})
+		}
+		patterns = strings.Split(operands[0], "\n")
+		operands = operands[1:]
+	}
+	err = checkOperands(cmdName, operands)
+	if err != nil {
+		return usageCommand(grepStatusError, err)
+	}
+
+	matcher, err := compileGrepPatterns(patterns, extended, fixed, ignoreCase, lineMatch)
+	if err != nil {
+		return usageCommand(grepStatusError, ErrUsage{cmdName, err.Error()})
+	}
+
+	// findMatches returns the parts of the line that match
+	findMatches := func(line string) [][]int {
+		matches := matcher.FindAllStringIndex(line, -1)
+		if !wordMatch {
+			return matches
+		}
+
+		var retval [][]int
+		for _, match := range matches {
+			if isWordMatch(line, match[0], match[1]) {
+				retval = append(retval, match)
+			}
+		}
+		return retval
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		lineNo := 0
+		selected := 0
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			lineNo++
+
+			matches := findMatches(line)
+			if (len(matches) > 0) == invert {
+				return nil
+			}
+			selected++
+
+			switch {
+			case quiet, listOnly:
+				return errStopReading
+			case countOnly:
+				return nil
+			}
+
+			prefix := ""
+			if lineNumbers {
+				prefix = strconv.Itoa(lineNo) + ":"
+			}
+
+			if !onlyMatching {
+				p.Stdout.WriteString(prefix + line + "\n")
+				return nil
+			}
+
+			// we only get here with -o, and without -v
+			for _, match := range matches {
+				if match[0] != match[1] {
+					p.Stdout.WriteString(prefix + line[match[0]:match[1]] + "\n")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return reportError(p, grepStatusError, ErrCommand{cmdName, err})
+		}
+
+		switch {
+		case quiet:
+			// no output
+		case listOnly:
+			if selected > 0 {
+				p.Stdout.WriteString("(standard input)\n")
+			}
+		case countOnly:
+			p.Stdout.WriteString(fmt.Sprintf("%d\n", selected))
+		}
+
+		if selected == 0 {
+			return grepStatusNoMatch, nil
+		}
+
+		// all done
+		return grepStatusMatch, nil
+	}
+}
+
+// compileGrepPatterns turns grep's patterns into a single regexp
+func compileGrepPatterns(patterns []string, extended, fixed, ignoreCase, lineMatch bool) (*regexp.Regexp, error) {
+	parts := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if fixed {
+			parts[i] = regexp.QuoteMeta(pattern)
+			continue
+		}
+
+		part, err := posixToRE2(pattern, extended)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = part
+	}
+
+	expr := "(?:" + strings.Join(parts, ")|(?:") + ")"
+	if lineMatch {
+		expr = "^(?:" + expr + ")$"
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+
+	retval, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	// POSIX regular expressions find the longest match
+	retval.Longest()
+	return retval, nil
+}
+
+// isWordMatch returns true if line[start:end] is not part of a larger
+// word
+func isWordMatch(line string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(line[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(line) {
+		r, _ := utf8.DecodeRuneInString(line[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWordRune returns true if r can be part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,166 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestGrep(t *testing.T) {
+	t.Parallel()
+
+	input := "foo bar foo\nFOO\nfoobar\nbar-foo\nbaz\n"
+
+	pipetest.RunCases(t, withArgs(commands.Grep), []pipetest.Case{
+		{
+			Name:       "selects matching lines",
+			Args:       []string{"foo"},
+			Stdin:      input,
+			WantStdout: "foo bar foo\nfoobar\nbar-foo\n",
+		},
+		{
+			Name:       "returns status 1 when nothing matches",
+			Args:       []string{"nothing"},
+			Stdin:      input,
+			WantStatus: 1,
+		},
+		{
+			Name:       "-i ignores case",
+			Args:       []string{"-i", "^foo$"},
+			Stdin:      input,
+			WantStdout: "FOO\n",
+		},
+		{
+			Name:       "-v selects non-matching lines",
+			Args:       []string{"-v", "foo"},
+			Stdin:      input,
+			WantStdout: "FOO\nbaz\n",
+		},
+		{
+			Name:       "-c counts matching lines",
+			Args:       []string{"-c", "bar"},
+			Stdin:      input,
+			WantStdout: "3\n",
+		},
+		{
+			Name:       "-c writes 0 when nothing matches",
+			Args:       []string{"-c", "nothing"},
+			Stdin:      input,
+			WantStdout: "0\n",
+			WantStatus: 1,
+		},
+		{
+			Name:       "-n and -o write numbered matches",
+			Args:       []string{"-n", "-o", "fo*"},
+			Stdin:      input,
+			WantStdout: "1:foo\n1:foo\n3:foo\n4:foo\n",
+		},
+		{
+			Name:       "-w matches whole words",
+			Args:       []string{"-w", "foo"},
+			Stdin:      input,
+			WantStdout: "foo bar foo\nbar-foo\n",
+		},
+		{
+			Name:       "-x matches whole lines",
+			Args:       []string{"-x", "baz"},
+			Stdin:      input,
+			WantStdout: "baz\n",
+		},
+		{
+			Name:       "patterns are basic regular expressions",
+			Args:       []string{`o\{2\}b\|^ba\(z\)`},
+			Stdin:      input,
+			WantStdout: "foobar\nbaz\n",
+		},
+		{
+			Name:       "-E uses extended regular expressions",
+			Args:       []string{"-E", "o{2}b|^ba(z)"},
+			Stdin:      input,
+			WantStdout: "foobar\nbaz\n",
+		},
+		{
+			Name:       "-F uses fixed strings",
+			Args:       []string{"-F", "a.b"},
+			Stdin:      "a.b\naxb\n",
+			WantStdout: "a.b\n",
+		},
+		{
+			Name:       "-e can be given more than once",
+			Args:       []string{"-e", "baz", "-e", "FOO"},
+			Stdin:      input,
+			WantStdout: "FOO\nbaz\n",
+		},
+		{
+			Name:       "-l writes (standard input)",
+			Args:       []string{"-l", "foo"},
+			Stdin:      input,
+			WantStdout: "(standard input)\n",
+		},
+		{
+			Name:  "-q writes nothing",
+			Args:  []string{"-q", "foo"},
+			Stdin: input,
+		},
+		{
+			Name:       "complains about a missing pattern",
+			WantStderr: "grep: no pattern given\n",
+			WantStatus: 2,
+			WantErr:    commands.ErrUsage{"grep", "no pattern given"},
+		},
+		{
+			Name:       "complains about back-references",
+			Args:       []string{`\(a\)\1`},
+			WantStderr: "grep: back-references are not supported\n",
+			WantStatus: 2,
+			WantErr:    commands.ErrUsage{"grep", "back-references are not supported"},
+		},
+		{
+			Name:       "complains about invalid options",
+			Args:       []string{"-Z", "foo"},
+			WantStderr: "grep: invalid option -- 'Z'\n",
+			WantStatus: 2,
+			WantErr:    commands.ErrUsage{"grep", "invalid option -- 'Z'"},
+		},
+	})
+}
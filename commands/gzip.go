@@ -0,0 +1,150 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"compress/gzip"
+	"io"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Gzip returns a PipeCommand that compresses the pipe's Stdin, and
+// writes the result to the pipe's Stdout.
+//
+// It supports these options:
+//
+//	-1 ... -9  the compression level, from fastest to best
+//	-c         ignored (output always goes to the pipe's Stdout)
+//	-d         decompress instead, like Gunzip
+func Gzip(args ...string) pipe.PipeCommand {
+	const cmdName = "gzip"
+
+	opts, operands, err := getopt(cmdName, "123456789cd", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	level := gzip.DefaultCompression
+	decompress := false
+	for _, opt := range opts {
+		switch {
+		case opt.name == 'd':
+			decompress = true
+		case opt.name >= '1' && opt.name <= '9':
+			level = int(opt.name - '0')
+		}
+	}
+
+	if decompress {
+		return gunzipCommand(cmdName)
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		// level is always valid, so this cannot fail
+		w, _ := gzip.NewWriterLevel(p.Stdout, level)
+		_, err := io.Copy(w, p.Stdin)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// Gunzip returns a PipeCommand that decompresses the gzip data in the
+// pipe's Stdin, and writes the result to the pipe's Stdout.
+//
+// It supports these options:
+//
+//	-c  ignored (output always goes to the pipe's Stdout)
+//
+// If Stdin holds several gzip streams, one after the other, they are
+// all decompressed.
+func Gunzip(args ...string) pipe.PipeCommand {
+	const cmdName = "gunzip"
+
+	_, operands, err := getopt(cmdName, "c", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	return gunzipCommand(cmdName)
+}
+
+// gunzipCommand does the work for Gunzip and `Gzip -d`
+func gunzipCommand(cmdName string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		r, err := gzip.NewReader(p.Stdin)
+		if err == io.EOF {
+			// an empty Stdin is not valid gzip data
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			_, err = io.Copy(p.Stdout, r)
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,196 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// allBytes holds every possible byte value, to prove that binary data
+// survives untouched
+func allBytes() string {
+	buf := make([]byte, 256)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+
+	return string(buf)
+}
+
+// gzipString returns input, compressed with gzip
+func gzipString(input string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(input))
+	w.Close()
+
+	return buf.String()
+}
+
+func TestGzipCompressesStdin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{
+			name:  "text",
+			input: "hello\r\nworld\n\n",
+		},
+		{
+			name:  "binary data",
+			input: allBytes() + allBytes(),
+		},
+		{
+			name:  "-9 sets the compression level",
+			args:  []string{"-9"},
+			input: allBytes(),
+		},
+		{
+			name:  "empty input",
+			args:  []string{"-c"},
+			input: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromBytes([]byte(testCase.input))
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(commands.Gzip(testCase.args...))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, unit.Error())
+			compressed, err := unit.StdoutBytes()
+			assert.Nil(t, err)
+
+			r, err := gzip.NewReader(bytes.NewReader(compressed))
+			assert.Nil(t, err)
+			actualResult, err := ioutil.ReadAll(r)
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.input, string(actualResult))
+		})
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Gunzip), []pipetest.Case{
+		{
+			Name:       "decompresses Stdin",
+			Stdin:      gzipString("hello\r\nworld\n"),
+			WantStdout: "hello\r\nworld\n",
+		},
+		{
+			Name:       "copes with binary data",
+			Stdin:      gzipString(allBytes()),
+			WantStdout: allBytes(),
+		},
+		{
+			Name:       "decompresses concatenated streams",
+			Stdin:      gzipString("one\n") + gzipString("two\n"),
+			WantStdout: "one\ntwo\n",
+		},
+		{
+			Name:       "rejects data that is not gzipped",
+			Stdin:      "hello world, this is not gzip\n",
+			WantStderr: "gunzip: gzip: invalid header\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"gunzip", gzip.ErrHeader},
+		},
+		{
+			Name:       "rejects empty input",
+			WantStderr: "gunzip: unexpected EOF\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"gunzip", io.ErrUnexpectedEOF},
+		},
+		{
+			Name:       "rejects file operands",
+			Args:       []string{"archive.gz"},
+			WantStderr: "gunzip: file operands are not supported: 'archive.gz'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"gunzip", "file operands are not supported: 'archive.gz'"},
+		},
+	})
+}
+
+func TestGzipDecompressesWithD(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Gzip), []pipetest.Case{
+		{
+			Name:       "-d decompresses Stdin",
+			Args:       []string{"-dc"},
+			Stdin:      gzipString(allBytes()),
+			WantStdout: allBytes(),
+		},
+		{
+			Name:       "rejects unknown options",
+			Args:       []string{"-x"},
+			WantStderr: "gzip: invalid option -- 'x'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"gzip", "invalid option -- 'x'"},
+		},
+	})
+}
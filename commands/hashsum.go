@@ -0,0 +1,130 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Md5sum returns a PipeCommand that writes the MD5 checksum of the
+// pipe's Stdin to the pipe's Stdout, in the same format as md5sum(1).
+//
+// It supports the same options as Sha256sum.
+func Md5sum(args ...string) pipe.PipeCommand {
+	return hashSum("md5sum", md5.New, args)
+}
+
+// Sha1sum returns a PipeCommand that writes the SHA1 checksum of the
+// pipe's Stdin to the pipe's Stdout, in the same format as sha1sum(1).
+//
+// It supports the same options as Sha256sum.
+func Sha1sum(args ...string) pipe.PipeCommand {
+	return hashSum("sha1sum", sha1.New, args)
+}
+
+// Sha256sum returns a PipeCommand that writes the SHA256 checksum of the
+// pipe's Stdin to the pipe's Stdout, in the same format as sha256sum(1):
+//
+//	e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  -
+//
+// It supports these options:
+//
+//	-b  mark the input as binary, with a '*' before the '-'
+//	-t  mark the input as text (the default)
+//
+// The checksum is always worked out from the raw bytes of Stdin.
+func Sha256sum(args ...string) pipe.PipeCommand {
+	return hashSum("sha256sum", sha256.New, args)
+}
+
+// Sha512sum returns a PipeCommand that writes the SHA512 checksum of the
+// pipe's Stdin to the pipe's Stdout, in the same format as sha512sum(1).
+//
+// It supports the same options as Sha256sum.
+func Sha512sum(args ...string) pipe.PipeCommand {
+	return hashSum("sha512sum", sha512.New, args)
+}
+
+// hashSum does the work for all of the checksum commands
+func hashSum(cmdName string, newHash func() hash.Hash, args []string) pipe.PipeCommand {
+	opts, operands, err := getopt(cmdName, "bt", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	mode := " "
+	for _, opt := range opts {
+		switch opt.name {
+		case 'b':
+			mode = "*"
+		case 't':
+			mode = " "
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		h := newHash()
+		_, err := io.Copy(h, p.Stdin)
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		p.Stdout.WriteString(hex.EncodeToString(h.Sum(nil)) + " " + mode + "-\n")
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,112 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestSha256sum(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Sha256sum), []pipetest.Case{
+		{
+			Name:       "hashes Stdin",
+			Stdin:      "abc",
+			WantStdout: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  -\n",
+		},
+		{
+			Name:       "hashes empty input",
+			WantStdout: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  -\n",
+		},
+		{
+			Name:       "hashes binary data",
+			Stdin:      allBytes(),
+			WantStdout: "40aff2e9d2d8922e47afd4648e6967497158785fbd1da870e7110266bf944880  -\n",
+		},
+		{
+			Name:       "does not change line endings",
+			Stdin:      "abc\r\n",
+			WantStdout: "552bab6864c7a7b69a502ed1854b9245c0e1a30f008aaa0b281da62585fdb025  -\n",
+		},
+		{
+			Name:       "-b marks the input as binary",
+			Args:       []string{"-b"},
+			Stdin:      "abc",
+			WantStdout: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad *-\n",
+		},
+		{
+			Name:       "rejects unknown options",
+			Args:       []string{"-c"},
+			WantStderr: "sha256sum: invalid option -- 'c'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"sha256sum", "invalid option -- 'c'"},
+		},
+	})
+}
+
+func TestOtherHashSums(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Md5sum), []pipetest.Case{
+		{
+			Name:       "md5sum",
+			Stdin:      "abc",
+			WantStdout: "900150983cd24fb0d6963f7d28e17f72  -\n",
+		},
+	})
+	pipetest.RunCases(t, withArgs(commands.Sha1sum), []pipetest.Case{
+		{
+			Name:       "sha1sum",
+			Stdin:      "abc",
+			WantStdout: "a9993e364706816aba3e25717850c26c9cd0d89d  -\n",
+		},
+	})
+	pipetest.RunCases(t, withArgs(commands.Sha512sum), []pipetest.Case{
+		{
+			Name:       "sha512sum",
+			Stdin:      "abc",
+			WantStdout: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f  -\n",
+		},
+	})
+}
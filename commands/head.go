@@ -0,0 +1,142 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// obsoleteCountArg matches the old-style "-5" way of giving a count
+var obsoleteCountArg = regexp.MustCompile(`^-[0-9]+$`)
+
+// Head returns a PipeCommand that copies the first part of the pipe's
+// Stdin to its Stdout.
+//
+// It supports these options:
+//
+//	-c bytes  copy the first 'bytes' bytes
+//	-n lines  copy the first 'lines' lines (default: 10)
+//
+// The old-style "-lines" form (eg "-5") is supported too.
+//
+// Head stops reading from the pipe's Stdin as soon as it has copied
+// everything that it needs to.
+func Head(args ...string) pipe.PipeCommand {
+	const cmdName = "head"
+
+	// special case - the obsolete "-5" form
+	if len(args) > 0 && obsoleteCountArg.MatchString(args[0]) {
+		args = append([]string{"-n", args[0][1:]}, args[1:]...)
+	}
+
+	opts, operands, err := getopt(cmdName, "c:n:", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	count := int64(10)
+	countBytes := false
+	for _, opt := range opts {
+		var unit string
+		switch opt.name {
+		case 'c':
+			countBytes = true
+			unit = "bytes"
+		case 'n':
+			countBytes = false
+			unit = "lines"
+		}
+
+		count, err = strconv.ParseInt(opt.value, 10, 64)
+		if err != nil || count < 0 {
+			err = ErrUsage{cmdName, fmt.Sprintf("invalid number of %s: '%s'", unit, opt.value)}
+			return usageCommand(pipe.StatusNotOkay, err)
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		var err error
+		if countBytes {
+			_, err = io.CopyN(p.Stdout, p.Stdin, count)
+			if err == io.EOF {
+				err = nil
+			}
+		} else {
+			err = headLines(p, count)
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// headLines copies the first count lines of the pipe's Stdin to its Stdout
+func headLines(p *pipe.Pipe, count int64) error {
+	if count == 0 {
+		return nil
+	}
+
+	seen := int64(0)
+	return eachLine(p.Stdin, func(line string, eol bool) error {
+		writeLine(p.Stdout, line, eol)
+
+		seen++
+		if seen >= count {
+			return errStopReading
+		}
+		return nil
+	})
+}
@@ -0,0 +1,113 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestHead(t *testing.T) {
+	t.Parallel()
+
+	numbers := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+
+	pipetest.RunCases(t, withArgs(commands.Head), []pipetest.Case{
+		{
+			Name:       "copies the first 10 lines by default",
+			Stdin:      numbers,
+			WantStdout: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+		},
+		{
+			Name:       "-n sets the number of lines",
+			Args:       []string{"-n", "2"},
+			Stdin:      numbers,
+			WantStdout: "1\n2\n",
+		},
+		{
+			Name:       "supports the obsolete -N form",
+			Args:       []string{"-3"},
+			Stdin:      numbers,
+			WantStdout: "1\n2\n3\n",
+		},
+		{
+			Name:       "-c copies bytes",
+			Args:       []string{"-c5"},
+			Stdin:      numbers,
+			WantStdout: "1\n2\n3",
+		},
+		{
+			Name:       "copes with short input",
+			Args:       []string{"-n", "5"},
+			Stdin:      "1\n2",
+			WantStdout: "1\n2",
+		},
+		{
+			Name:       "-n 0 copies nothing",
+			Args:       []string{"-n", "0"},
+			Stdin:      numbers,
+			WantStdout: "",
+		},
+		{
+			Name:       "rejects invalid counts",
+			Args:       []string{"-n", "many"},
+			Stdin:      numbers,
+			WantStderr: "head: invalid number of lines: 'many'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"head", "invalid number of lines: 'many'"},
+		},
+		{
+			Name:       "rejects missing option values",
+			Args:       []string{"-n"},
+			WantStderr: "head: option requires an argument -- 'n'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"head", "option requires an argument -- 'n'"},
+		},
+		{
+			Name:       "copes with very long input",
+			Args:       []string{"-n", "1"},
+			Stdin:      strings.Repeat("x", 100000) + "\nsecond\n",
+			WantStdout: strings.Repeat("x", 100000) + "\n",
+		},
+	})
+}
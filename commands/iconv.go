@@ -0,0 +1,110 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"io"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Iconv returns a PipeCommand that converts the pipe's Stdin from one
+// character encoding to another, and writes the result to the pipe's
+// Stdout.
+//
+// It supports these options:
+//
+//	-f encoding  the encoding of Stdin (default UTF-8)
+//	-t encoding  the encoding to write (default UTF-8)
+//	-c           leave out anything that cannot be converted
+//
+// Without -c, it stops at the first thing that it cannot convert. If
+// Stdin starts with a byte order mark, the BOM decides the encoding of
+// Stdin. See pipe.LookupEncoding for the encodings that we support.
+func Iconv(args ...string) pipe.PipeCommand {
+	const cmdName = "iconv"
+
+	opts, operands, err := getopt(cmdName, "f:t:c", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	from, to := pipe.UTF8, pipe.UTF8
+	policy := pipe.RejectInvalidSequences
+	for _, opt := range opts {
+		switch opt.name {
+		case 'f':
+			from, err = pipe.LookupEncoding(opt.value)
+		case 't':
+			to, err = pipe.LookupEncoding(opt.value)
+		case 'c':
+			policy = pipe.SkipInvalidSequences
+		}
+		if err != nil {
+			return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		// these cannot fail, because LookupEncoding only returns
+		// encodings that we support
+		decoder, _ := pipe.NewDecoder(p.Stdin, from, pipe.OnInvalidSequence(policy))
+		encoder, _ := pipe.NewEncoder(p.Stdout, to, pipe.OnInvalidSequence(policy))
+
+		_, err := io.Copy(encoder, decoder)
+		if err == nil {
+			err = encoder.Close()
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,123 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestIconv(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Iconv), []pipetest.Case{
+		{
+			Name:       "copies UTF-8 by default",
+			Stdin:      "café\n",
+			WantStdout: "café\n",
+		},
+		{
+			Name:       "converts from Latin-1",
+			Args:       []string{"-f", "ISO-8859-1"},
+			Stdin:      "caf\xe9\n",
+			WantStdout: "café\n",
+		},
+		{
+			Name:       "converts from UTF-16 with a BOM",
+			Args:       []string{"-f", "UTF-16"},
+			Stdin:      "\xff\xfeh\x00i\x00\n\x00",
+			WantStdout: "hi\n",
+		},
+		{
+			Name:       "converts to Windows-1252",
+			Args:       []string{"-t", "cp1252"},
+			Stdin:      "5€\n",
+			WantStdout: "5\x80\n",
+		},
+		{
+			Name:       "converts between two encodings",
+			Args:       []string{"-f", "latin1", "-t", "UTF-16LE"},
+			Stdin:      "\xe9",
+			WantStdout: "\xe9\x00",
+		},
+		{
+			Name:       "stops at invalid input",
+			Stdin:      "ab\xffcd",
+			WantStdout: "ab",
+			WantStderr: "iconv: invalid UTF-8 sequence at byte offset 2\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"iconv", pipe.ErrInvalidSequence{Encoding: "UTF-8", Offset: 2}},
+		},
+		{
+			Name:       "stops at truncated UTF-16 input",
+			Args:       []string{"-f", "UTF-16"},
+			Stdin:      "\x00a\x00",
+			WantStdout: "a",
+			WantStderr: "iconv: invalid UTF-16BE sequence at byte offset 2\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"iconv", pipe.ErrInvalidSequence{Encoding: "UTF-16BE", Offset: 2}},
+		},
+		{
+			Name:       "stops at characters that cannot be converted",
+			Args:       []string{"-t", "latin1"},
+			Stdin:      "a→b",
+			WantStdout: "a",
+			WantStderr: "iconv: cannot encode U+2192 in ISO-8859-1\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrCommand{"iconv", pipe.ErrUnencodableCharacter{Encoding: "ISO-8859-1", Char: '→'}},
+		},
+		{
+			Name:       "-c leaves out anything that cannot be converted",
+			Args:       []string{"-c", "-t", "latin1"},
+			Stdin:      "a→b\xff\n",
+			WantStdout: "ab\n",
+		},
+		{
+			Name:       "rejects unknown encodings",
+			Args:       []string{"-f", "EBCDIC"},
+			WantStderr: "iconv: unknown character encoding \"EBCDIC\"\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"iconv", "unknown character encoding \"EBCDIC\""},
+		},
+	})
+}
@@ -0,0 +1,164 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Nl returns a PipeCommand that copies the pipe's Stdin to its Stdout,
+// putting line numbers in front of the lines.
+//
+// It supports these options:
+//
+//	-b type    which lines to number:
+//	           a (all lines), t (non-empty lines, the default),
+//	           n (no lines), or pREGEX (lines that match REGEX)
+//	-i incr    add 'incr' to the line number each time (default: 1)
+//	-n format  how to write the line number:
+//	           ln (left-justified), rn (right-justified, the default),
+//	           or rz (right-justified, with leading zeros)
+//	-s sep     write 'sep' between the line number and the line
+//	           (default: tab)
+//	-v start   start counting from 'start' (default: 1)
+//	-w width   how many characters to use for the line number
+//	           (default: 6)
+//
+// Logical page sections (\:\:\:, \:\: and \:) are not supported.
+func Nl(args ...string) pipe.PipeCommand {
+	const cmdName = "nl"
+
+	opts, operands, err := getopt(cmdName, "b:i:n:s:v:w:", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	// shouldNumber decides which lines get a number
+	shouldNumber := func(line string) bool { return line != "" }
+	increment, start, width := 1, 1, 6
+	format := "rn"
+	separator := "\t"
+
+	for _, opt := range opts {
+		switch opt.name {
+		case 'b':
+			switch {
+			case opt.value == "a":
+				shouldNumber = func(line string) bool { return true }
+			case opt.value == "t":
+				shouldNumber = func(line string) bool { return line != "" }
+			case opt.value == "n":
+				shouldNumber = func(line string) bool { return false }
+			case strings.HasPrefix(opt.value, "p"):
+				expr, err := posixToRE2(opt.value[1:], false)
+				var regex *regexp.Regexp
+				if err == nil {
+					regex, err = regexp.Compile(expr)
+				}
+				if err != nil {
+					return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+				}
+				shouldNumber = regex.MatchString
+			default:
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("invalid line numbering style: '%s'", opt.value)})
+			}
+		case 'n':
+			if opt.value != "ln" && opt.value != "rn" && opt.value != "rz" {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("invalid line number format: '%s'", opt.value)})
+			}
+			format = opt.value
+		case 's':
+			separator = opt.value
+		case 'i', 'v', 'w':
+			value, err := strconv.Atoi(opt.value)
+			if err != nil || (opt.name == 'w' && value < 1) {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("invalid number: '%s'", opt.value)})
+			}
+			switch opt.name {
+			case 'i':
+				increment = value
+			case 'v':
+				start = value
+			case 'w':
+				width = value
+			}
+		}
+	}
+
+	numberFormat := map[string]string{
+		"ln": "%-*d",
+		"rn": "%*d",
+		"rz": "%0*d",
+	}[format]
+	noNumber := strings.Repeat(" ", width+len(separator))
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		lineNo := start
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			if !shouldNumber(line) {
+				p.Stdout.WriteString(noNumber + line + "\n")
+				return nil
+			}
+
+			p.Stdout.WriteString(fmt.Sprintf(numberFormat, width, lineNo) + separator + line + "\n")
+			lineNo += increment
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,92 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestNl(t *testing.T) {
+	t.Parallel()
+
+	input := "one\n\ntwo\n"
+
+	pipetest.RunCases(t, withArgs(commands.Nl), []pipetest.Case{
+		{
+			Name:       "numbers non-empty lines",
+			Stdin:      input,
+			WantStdout: "     1\tone\n       \n     2\ttwo\n",
+		},
+		{
+			Name:       "-ba numbers all lines",
+			Args:       []string{"-ba"},
+			Stdin:      input,
+			WantStdout: "     1\tone\n     2\t\n     3\ttwo\n",
+		},
+		{
+			Name:       "-bp numbers matching lines",
+			Args:       []string{"-b", "pt"},
+			Stdin:      input,
+			WantStdout: "       one\n       \n     1\ttwo\n",
+		},
+		{
+			Name:       "-n, -w and -s change the format",
+			Args:       []string{"-nln", "-w3", "-s:"},
+			Stdin:      input,
+			WantStdout: "1  :one\n    \n2  :two\n",
+		},
+		{
+			Name:       "-v and -i change the numbers",
+			Args:       []string{"-nrz", "-v5", "-i2"},
+			Stdin:      input,
+			WantStdout: "000005\tone\n       \n000007\ttwo\n",
+		},
+		{
+			Name:       "rejects unknown formats",
+			Args:       []string{"-n", "xx"},
+			WantStderr: "nl: invalid line number format: 'xx'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"nl", "invalid line number format: 'xx'"},
+		},
+	})
+}
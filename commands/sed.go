@@ -0,0 +1,327 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// sedSubstitution is a single s/// command
+type sedSubstitution struct {
+	regex       *regexp.Regexp
+	replacement string
+	global      bool
+	occurrence  int
+	print       bool
+}
+
+// apply runs the substitution against line, and returns the result
+// along with whether or not anything was replaced
+func (s sedSubstitution) apply(line string) (string, bool) {
+	matches := s.regex.FindAllStringSubmatchIndex(line, -1)
+
+	var buf strings.Builder
+	last := 0
+	replaced := false
+	for i, match := range matches {
+		n := i + 1
+		if n < s.occurrence || (n > s.occurrence && !s.global) {
+			continue
+		}
+
+		buf.WriteString(line[last:match[0]])
+		buf.WriteString(expandSedReplacement(s.replacement, line, match))
+		last = match[1]
+		replaced = true
+	}
+	buf.WriteString(line[last:])
+
+	return buf.String(), replaced
+}
+
+// expandSedReplacement builds the replacement text for a single match
+func expandSedReplacement(replacement string, line string, match []int) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		switch {
+		case c == '&':
+			buf.WriteString(line[match[0]:match[1]])
+		case c == '\\' && i+1 < len(replacement):
+			i++
+			next := replacement[i]
+			switch {
+			case next >= '0' && next <= '9':
+				group := int(next - '0')
+				if 2*group+1 < len(match) && match[2*group] >= 0 {
+					buf.WriteString(line[match[2*group]:match[2*group+1]])
+				}
+			case next == 'n':
+				buf.WriteByte('\n')
+			case next == 't':
+				buf.WriteByte('\t')
+			default:
+				buf.WriteByte(next)
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.String()
+}
+
+// Sed returns a PipeCommand that copies the pipe's Stdin to its Stdout,
+// editing each line with one or more s/// commands.
+//
+// It supports these options:
+//
+//	-E, -r     regular expressions are extended regular expressions
+//	-e script  add 'script' to the commands to run
+//	-n         only write lines that the p flag tells us to write
+//
+// If there is no -e option, the first operand is the script.
+//
+// Commands are separated by ';' or newlines. The only command supported
+// is s/regex/replacement/flags, where flags are any of:
+//
+//	g  replace every match, not just the first one
+//	N  replace the Nth match (or, with g, the Nth match onwards)
+//	i  ignore case
+//	p  write the line if a replacement was made
+//
+// Any character can be used instead of '/'. In the replacement, '&' is
+// the whole match, and \1 to \9 are the matching groups.
+func Sed(args ...string) pipe.PipeCommand {
+	const cmdName = "sed"
+
+	opts, operands, err := getopt(cmdName, "Ee:nr", args)
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var extended, quiet bool
+	var scripts []string
+	for _, opt := range opts {
+		switch opt.name {
+		case 'E', 'r':
+			extended = true
+		case 'e':
+			scripts = append(scripts, opt.value)
+		case 'n':
+			quiet = true
+		}
+	}
+
+	// is the script an operand?
+	if len(scripts) == 0 {
+		if len(operands) == 0 {
+			return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "no script given"})
+		}
+		scripts = append(scripts, operands[0])
+		operands = operands[1:]
+	}
+	err = checkOperands(cmdName, operands)
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	substitutions, err := parseSedScript(strings.Join(scripts, "\n"), extended)
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			for _, substitution := range substitutions {
+				var replaced bool
+				line, replaced = substitution.apply(line)
+				if replaced && substitution.print {
+					writeLine(p.Stdout, line, true)
+				}
+			}
+
+			if !quiet {
+				writeLine(p.Stdout, line, eol)
+			}
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// parseSedScript parses a script made up of s/// commands
+func parseSedScript(script string, extended bool) ([]sedSubstitution, error) {
+	var retval []sedSubstitution
+
+	i := 0
+	for {
+		// skip anything between commands
+		for i < len(script) && strings.IndexByte(" \t\n;", script[i]) >= 0 {
+			i++
+		}
+		if i >= len(script) {
+			return retval, nil
+		}
+
+		if script[i] != 's' {
+			return nil, fmt.Errorf("unsupported command %c (only s/// is supported)", script[i])
+		}
+		if i+1 >= len(script) || script[i+1] == '\\' || script[i+1] == '\n' {
+			return nil, fmt.Errorf("unterminated `s' command")
+		}
+		delim := script[i+1]
+		i += 2
+
+		pattern, next, err := readSedPart(script, i, delim, true)
+		if err != nil {
+			return nil, err
+		}
+		replacement, next, err := readSedPart(script, next, delim, false)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		substitution := sedSubstitution{replacement: replacement, occurrence: 1}
+		ignoreCase := false
+
+		// flags run until the end of the command
+		for i < len(script) && strings.IndexByte(" \t\n;}", script[i]) < 0 {
+			c := script[i]
+			switch {
+			case c == 'g':
+				substitution.global = true
+			case c == 'p':
+				substitution.print = true
+			case c == 'i' || c == 'I':
+				ignoreCase = true
+			case c >= '1' && c <= '9':
+				end := i
+				for end < len(script) && script[end] >= '0' && script[end] <= '9' {
+					end++
+				}
+				substitution.occurrence, _ = strconv.Atoi(script[i:end])
+				i = end - 1
+			default:
+				return nil, fmt.Errorf("unknown option to `s'")
+			}
+			i++
+		}
+
+		if pattern == "" {
+			return nil, fmt.Errorf("no previous regular expression")
+		}
+		expr, err := posixToRE2(pattern, extended)
+		if err != nil {
+			return nil, err
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		substitution.regex, err = regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		substitution.regex.Longest()
+
+		retval = append(retval, substitution)
+	}
+}
+
+// readSedPart reads the regex or replacement part of an s/// command,
+// starting at script[start], and returns it along with the position
+// just after its closing delimiter
+//
+// An escaped delimiter becomes a plain delimiter. Other escapes are
+// left alone for the next stage to deal with.
+func readSedPart(script string, start int, delim byte, isRegex bool) (string, int, error) {
+	var buf strings.Builder
+
+	for i := start; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == delim:
+			return buf.String(), i + 1, nil
+		case c == '\\' && i+1 < len(script):
+			i++
+			if script[i] == delim {
+				// in a regex, the delimiter may be special, so we
+				// turn it into a bracket expression
+				switch {
+				case !isRegex || delim == '/':
+					buf.WriteByte(delim)
+				case delim == ']' || delim == '^' || delim == '\\':
+					buf.WriteByte('\\')
+					buf.WriteByte(delim)
+				default:
+					buf.WriteByte('[')
+					buf.WriteByte(delim)
+					buf.WriteByte(']')
+				}
+				continue
+			}
+			buf.WriteByte('\\')
+			buf.WriteByte(script[i])
+		case c == '\n' && isRegex:
+			return "", 0, fmt.Errorf("unterminated address regex")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated `s' command")
+}
@@ -0,0 +1,143 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestSed(t *testing.T) {
+	t.Parallel()
+
+	input := "foo bar foo\nFOO\nfoobar\n"
+
+	pipetest.RunCases(t, withArgs(commands.Sed), []pipetest.Case{
+		{
+			Name:       "replaces the first match",
+			Args:       []string{"s/o/0/"},
+			Stdin:      input,
+			WantStdout: "f0o bar foo\nFOO\nf0obar\n",
+		},
+		{
+			Name:       "g replaces every match",
+			Args:       []string{"s/o/0/g"},
+			Stdin:      input,
+			WantStdout: "f00 bar f00\nFOO\nf00bar\n",
+		},
+		{
+			Name:       "N replaces the Nth match",
+			Args:       []string{"s/o/0/3"},
+			Stdin:      input,
+			WantStdout: "foo bar f0o\nFOO\nfoobar\n",
+		},
+		{
+			Name:       "Ng replaces from the Nth match onwards",
+			Args:       []string{"s/o/0/2g"},
+			Stdin:      input,
+			WantStdout: "fo0 bar f00\nFOO\nfo0bar\n",
+		},
+		{
+			Name:       "I ignores case",
+			Args:       []string{"s/foo/x/I"},
+			Stdin:      input,
+			WantStdout: "x bar foo\nx\nxbar\n",
+		},
+		{
+			Name:       "-n and p only write changed lines",
+			Args:       []string{"-n", "s/bar/[&]/p"},
+			Stdin:      input,
+			WantStdout: "foo [bar] foo\nfoo[bar]\n",
+		},
+		{
+			Name:       "supports groups in basic regular expressions",
+			Args:       []string{`s/\(fo*\)\(.*\)/\2\1/`},
+			Stdin:      input,
+			WantStdout: " bar foofoo\nFOO\nbarfoo\n",
+		},
+		{
+			Name:       "-E uses extended regular expressions",
+			Args:       []string{"-E", `s/(o+)(b)/\2\1/`},
+			Stdin:      input,
+			WantStdout: "foo bar foo\nFOO\nfbooar\n",
+		},
+		{
+			Name:       "runs several commands",
+			Args:       []string{"-e", "s/^/> /", "-e", "s/$/ </; s/FOO/foo/"},
+			Stdin:      input,
+			WantStdout: "> foo bar foo <\n> foo <\n> foobar <\n",
+		},
+		{
+			Name:       "supports other delimiters",
+			Args:       []string{`s|a\|b|X|`},
+			Stdin:      "a|b\n",
+			WantStdout: "X\n",
+		},
+		{
+			Name:       "keeps a missing final newline",
+			Args:       []string{"s/b/B/"},
+			Stdin:      "a\nb",
+			WantStdout: "a\nB",
+		},
+		{
+			Name:       "only supports the s command",
+			Args:       []string{"1d"},
+			WantStderr: "sed: unsupported command 1 (only s/// is supported)\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"sed", "unsupported command 1 (only s/// is supported)"},
+		},
+		{
+			Name:       "complains about unterminated commands",
+			Args:       []string{"s/a/b"},
+			WantStderr: "sed: unterminated `s' command\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"sed", "unterminated `s' command"},
+		},
+		{
+			Name:       "complains about unknown flags",
+			Args:       []string{"s/a/b/q"},
+			WantStderr: "sed: unknown option to `s'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"sed", "unknown option to `s'"},
+		},
+	})
+}
@@ -0,0 +1,493 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// sort's status codes
+const (
+	sortStatusDisorder = 1
+	sortStatusError    = 2
+)
+
+// sortFlags are the ordering options that can apply to the whole line,
+// or to a single sort key
+type sortFlags struct {
+	ignoreBlanks    bool
+	dictionary      bool
+	foldCase        bool
+	ignoreNonPrint  bool
+	numeric         bool
+	reverse         bool
+	ignoreEndBlanks bool
+	hasOwnFlags     bool
+}
+
+// sortKey describes a single -k option
+type sortKey struct {
+	startField int
+	startChar  int
+	endField   int
+	endChar    int
+	flags      sortFlags
+}
+
+// Sort returns a PipeCommand that sorts the lines of the pipe's Stdin,
+// and writes them to its Stdout.
+//
+// It supports these options:
+//
+//	-b         ignore leading blanks
+//	-c         check that the input is sorted; write nothing else
+//	-C         like -c, but do not report the first out-of-order line
+//	-d         only consider blanks and alphanumeric characters
+//	-f         fold lower case to upper case
+//	-i         ignore non-printing characters
+//	-k keydef  sort on the given key; can be given more than once
+//	-n         compare by numeric value
+//	-r         reverse the result of comparisons
+//	-s         keep lines with equal keys in their original order
+//	-t char    use 'char' as the field separator
+//	-u         only write the first of any lines with equal keys
+//
+// A keydef is "field[.char][flags][,field[.char][flags]]", where flags
+// are any of the letters "bdfinr".
+//
+// Sort has to read all of the pipe's Stdin before it can write anything.
+func Sort(args ...string) pipe.PipeCommand {
+	const cmdName = "sort"
+
+	opts, operands, err := getopt(cmdName, "bcCdfik:nrst:u", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(sortStatusError, err)
+	}
+
+	var globalFlags sortFlags
+	var keys []sortKey
+	var check, quietCheck, stable, unique bool
+	separator := ""
+	for _, opt := range opts {
+		switch opt.name {
+		case 'c':
+			check = true
+		case 'C':
+			check, quietCheck = true, true
+		case 's':
+			stable = true
+		case 'u':
+			unique = true
+		case 't':
+			if utf8.RuneCountInString(opt.value) != 1 {
+				return usageCommand(sortStatusError, ErrUsage{cmdName, "the field separator must be a single character"})
+			}
+			separator = opt.value
+		case 'k':
+			key, err := parseSortKey(opt.value)
+			if err != nil {
+				return usageCommand(sortStatusError, ErrUsage{cmdName, err.Error()})
+			}
+			keys = append(keys, key)
+		default:
+			setSortFlag(&globalFlags, opt.name)
+		}
+	}
+
+	// keys without flags of their own use the global flags
+	for i := range keys {
+		if !keys[i].flags.hasOwnFlags {
+			keys[i].flags = globalFlags
+			keys[i].flags.ignoreEndBlanks = globalFlags.ignoreBlanks
+		}
+	}
+
+	sorter := lineSorter{
+		keys:      keys,
+		global:    globalFlags,
+		separator: separator,
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return reportError(p, sortStatusError, ErrCommand{cmdName, err})
+		}
+
+		// are we only checking?
+		if check {
+			for i := 1; i < len(lines); i++ {
+				cmp := sorter.compareKeys(lines[i-1], lines[i])
+				if cmp == 0 && !unique && !stable {
+					cmp = sorter.compareLines(lines[i-1], lines[i])
+				}
+				if cmp > 0 || (cmp == 0 && unique) {
+					if !quietCheck && p.Stderr != nil {
+						p.Stderr.WriteString(fmt.Sprintf("%s: -:%d: disorder: %s\n", cmdName, i+1, lines[i]))
+					}
+					return sortStatusDisorder, nil
+				}
+			}
+			return pipe.StatusOkay, nil
+		}
+
+		sort.SliceStable(lines, func(i, j int) bool {
+			cmp := sorter.compareKeys(lines[i], lines[j])
+			if cmp == 0 && !unique && !stable {
+				cmp = sorter.compareLines(lines[i], lines[j])
+			}
+			return cmp < 0
+		})
+
+		for i, line := range lines {
+			if unique && i > 0 && sorter.compareKeys(lines[i-1], line) == 0 {
+				continue
+			}
+			p.Stdout.WriteString(line + "\n")
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// setSortFlag sets the sortFlags option for the given option letter
+func setSortFlag(flags *sortFlags, name byte) bool {
+	switch name {
+	case 'b':
+		flags.ignoreBlanks = true
+	case 'd':
+		flags.dictionary = true
+	case 'f':
+		flags.foldCase = true
+	case 'i':
+		flags.ignoreNonPrint = true
+	case 'n':
+		flags.numeric = true
+	case 'r':
+		flags.reverse = true
+	default:
+		return false
+	}
+
+	return true
+}
+
+// parseSortKey parses a -k keydef
+func parseSortKey(keydef string) (sortKey, error) {
+	retval := sortKey{}
+	invalid := fmt.Errorf("invalid key definition: '%s'", keydef)
+
+	parts := strings.SplitN(keydef, ",", 2)
+
+	// parsePos parses "field[.char][flags]"
+	parsePos := func(pos string, isEnd bool) (int, int, error) {
+		// split off the flags
+		i := 0
+		for i < len(pos) && (pos[i] == '.' || pos[i] >= '0' && pos[i] <= '9') {
+			i++
+		}
+		for _, flag := range []byte(pos[i:]) {
+			if flag == 'b' && isEnd {
+				retval.flags.ignoreEndBlanks = true
+			} else if !setSortFlag(&retval.flags, flag) {
+				return 0, 0, invalid
+			}
+			retval.flags.hasOwnFlags = true
+		}
+		pos = pos[:i]
+
+		fieldPart, charPart := pos, ""
+		if dot := strings.IndexByte(pos, '.'); dot >= 0 {
+			fieldPart, charPart = pos[:dot], pos[dot+1:]
+		}
+
+		field, err := strconv.Atoi(fieldPart)
+		if err != nil || field < 1 {
+			return 0, 0, invalid
+		}
+
+		char := 0
+		if charPart != "" {
+			char, err = strconv.Atoi(charPart)
+			if err != nil || char < 0 || (char == 0 && !isEnd) {
+				return 0, 0, invalid
+			}
+		}
+
+		return field, char, nil
+	}
+
+	var err error
+	retval.startField, retval.startChar, err = parsePos(parts[0], false)
+	if err != nil {
+		return sortKey{}, err
+	}
+	if retval.startChar == 0 {
+		retval.startChar = 1
+	}
+
+	if len(parts) > 1 {
+		retval.endField, retval.endChar, err = parsePos(parts[1], true)
+		if err != nil {
+			return sortKey{}, err
+		}
+	}
+
+	return retval, nil
+}
+
+// lineSorter knows how to compare two lines
+type lineSorter struct {
+	keys      []sortKey
+	global    sortFlags
+	separator string
+}
+
+// compareKeys compares a and b using the sort keys, or the whole line
+// if there are no sort keys
+func (s lineSorter) compareKeys(a, b string) int {
+	if len(s.keys) == 0 {
+		return compareSortValues(a, b, s.global)
+	}
+
+	for _, key := range s.keys {
+		cmp := compareSortValues(s.extractKey(a, key), s.extractKey(b, key), key.flags)
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	return 0
+}
+
+// compareLines is the last-resort comparison, when all of the keys
+// are equal
+func (s lineSorter) compareLines(a, b string) int {
+	cmp := strings.Compare(a, b)
+	if s.global.reverse {
+		return -cmp
+	}
+	return cmp
+}
+
+// fieldBounds returns the start and end of each field in line
+func (s lineSorter) fieldBounds(line string) [][2]int {
+	var retval [][2]int
+
+	// with a separator, fields sit between the separators
+	if s.separator != "" {
+		start := 0
+		for {
+			pos := strings.Index(line[start:], s.separator)
+			if pos < 0 {
+				return append(retval, [2]int{start, len(line)})
+			}
+			retval = append(retval, [2]int{start, start + pos})
+			start += pos + len(s.separator)
+		}
+	}
+
+	// without a separator, each field includes the blanks in front of it
+	pos := 0
+	for pos < len(line) {
+		start := pos
+		for pos < len(line) && isBlank(line[pos]) {
+			pos++
+		}
+		for pos < len(line) && !isBlank(line[pos]) {
+			pos++
+		}
+		retval = append(retval, [2]int{start, pos})
+	}
+
+	return retval
+}
+
+// extractKey returns the part of line described by key
+func (s lineSorter) extractKey(line string, key sortKey) string {
+	fields := s.fieldBounds(line)
+
+	// where does the key start?
+	if key.startField > len(fields) {
+		return ""
+	}
+	field := fields[key.startField-1]
+	start := field[0]
+	if key.flags.ignoreBlanks {
+		for start < field[1] && isBlank(line[start]) {
+			start++
+		}
+	}
+	start = minInt(start+key.startChar-1, field[1])
+
+	// where does the key end?
+	end := len(line)
+	if key.endField > 0 && key.endField <= len(fields) {
+		field = fields[key.endField-1]
+		if key.endChar == 0 {
+			end = field[1]
+		} else {
+			end = field[0]
+			if key.flags.ignoreEndBlanks {
+				for end < field[1] && isBlank(line[end]) {
+					end++
+				}
+			}
+			end = minInt(end+key.endChar, field[1])
+		}
+	}
+
+	if end < start {
+		return ""
+	}
+	return line[start:end]
+}
+
+// compareSortValues compares a and b, using the given flags
+func compareSortValues(a, b string, flags sortFlags) int {
+	if flags.ignoreBlanks {
+		a = strings.TrimLeft(a, " \t")
+		b = strings.TrimLeft(b, " \t")
+	}
+
+	var cmp int
+	if flags.numeric {
+		cmp = compareNumbers(parseLeadingNumber(a), parseLeadingNumber(b))
+	} else {
+		a = normaliseSortValue(a, flags)
+		b = normaliseSortValue(b, flags)
+		cmp = strings.Compare(a, b)
+	}
+
+	if flags.reverse {
+		return -cmp
+	}
+	return cmp
+}
+
+// normaliseSortValue applies the -d, -f and -i flags to s
+func normaliseSortValue(s string, flags sortFlags) string {
+	if !flags.dictionary && !flags.foldCase && !flags.ignoreNonPrint {
+		return s
+	}
+
+	return strings.Map(
+		func(r rune) rune {
+			if flags.dictionary && !(r == ' ' || r == '\t' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return -1
+			}
+			if flags.ignoreNonPrint && !unicode.IsPrint(r) {
+				return -1
+			}
+			if flags.foldCase {
+				return unicode.ToUpper(r)
+			}
+			return r
+		},
+		s,
+	)
+}
+
+// parseLeadingNumber returns the number at the start of s, or 0 if
+// there isn't one
+func parseLeadingNumber(s string) float64 {
+	s = strings.TrimLeft(s, " \t")
+
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+	}
+
+	retval, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0
+	}
+	return retval
+}
+
+// compareNumbers compares a and b
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isBlank returns true if c is a space or a tab
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+
+	input := "Apple 10\napple 9\nbanana 100\n  cherry -3\nBanana 2.5\napple 9\n"
+
+	pipetest.RunCases(t, withArgs(commands.Sort), []pipetest.Case{
+		{
+			Name:       "sorts lines",
+			Stdin:      input,
+			WantStdout: "  cherry -3\nApple 10\nBanana 2.5\napple 9\napple 9\nbanana 100\n",
+		},
+		{
+			Name:       "-r reverses the order",
+			Args:       []string{"-r"},
+			Stdin:      "a\nc\nb",
+			WantStdout: "c\nb\na\n",
+		},
+		{
+			Name:       "-f folds case",
+			Args:       []string{"-f"},
+			Stdin:      input,
+			WantStdout: "  cherry -3\nApple 10\napple 9\napple 9\nbanana 100\nBanana 2.5\n",
+		},
+		{
+			Name:       "-u removes duplicates",
+			Args:       []string{"-u"},
+			Stdin:      "b\na\nb\na\n",
+			WantStdout: "a\nb\n",
+		},
+		{
+			Name:       "-fu treats different cases as duplicates",
+			Args:       []string{"-fu"},
+			Stdin:      "b\nA\nB\na\n",
+			WantStdout: "A\nb\n",
+		},
+		{
+			Name:       "-n sorts numerically",
+			Args:       []string{"-n"},
+			Stdin:      "10\n9\n-1\n2.5\nabc\n",
+			WantStdout: "-1\nabc\n2.5\n9\n10\n",
+		},
+		{
+			Name:       "-k sorts on a key",
+			Args:       []string{"-k2n"},
+			Stdin:      input,
+			WantStdout: "  cherry -3\nBanana 2.5\napple 9\napple 9\nApple 10\nbanana 100\n",
+		},
+		{
+			Name:       "-k with -t uses a field separator",
+			Args:       []string{"-t:", "-k", "2,2"},
+			Stdin:      "x:b:1\ny:a:2\nz:c:0\n",
+			WantStdout: "y:a:2\nx:b:1\nz:c:0\n",
+		},
+		{
+			Name:       "-k can pick characters",
+			Args:       []string{"-k1.2,1.2"},
+			Stdin:      "ac\nbb\nca\n",
+			WantStdout: "ca\nbb\nac\n",
+		},
+		{
+			Name:       "-s keeps the original order of equal keys",
+			Args:       []string{"-s", "-k1,1"},
+			Stdin:      "a 2\nb 1\na 1\n",
+			WantStdout: "a 2\na 1\nb 1\n",
+		},
+		{
+			Name:  "-c is quiet when the input is sorted",
+			Args:  []string{"-c"},
+			Stdin: "a\nb\n",
+		},
+		{
+			Name:       "-c reports the first out-of-order line",
+			Args:       []string{"-c"},
+			Stdin:      "a\nc\nb\n",
+			WantStderr: "sort: -:3: disorder: b\n",
+			WantStatus: 1,
+		},
+		{
+			Name:       "-C does not report anything",
+			Args:       []string{"-C"},
+			Stdin:      "a\nc\nb\n",
+			WantStatus: 1,
+		},
+		{
+			Name:       "complains about invalid keys",
+			Args:       []string{"-k", "0"},
+			WantStderr: "sort: invalid key definition: '0'\n",
+			WantStatus: 2,
+			WantErr:    commands.ErrUsage{"sort", "invalid key definition: '0'"},
+		},
+	})
+}
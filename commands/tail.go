@@ -0,0 +1,230 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// obsoleteTailArg matches the old-style "-5" and "+5" ways of giving
+// tail a count
+var obsoleteTailArg = regexp.MustCompile(`^[-+][0-9]+$`)
+
+// Tail returns a PipeCommand that copies the last part of the pipe's
+// Stdin to its Stdout.
+//
+// It supports these options:
+//
+//	-c [+]bytes  copy the last 'bytes' bytes, or from byte '+bytes' onwards
+//	-n [+]lines  copy the last 'lines' lines, or from line '+lines' onwards
+//	             (default: 10)
+//
+// The old-style "-lines" and "+lines" forms (eg "-5") are supported too.
+//
+// Tail streams its output when it is copying from a given line or byte
+// onwards. Otherwise, it has to wait until it has read all of the pipe's
+// Stdin.
+func Tail(args ...string) pipe.PipeCommand {
+	const cmdName = "tail"
+
+	// special case - the obsolete "-5" and "+5" forms
+	if len(args) > 0 && obsoleteTailArg.MatchString(args[0]) {
+		value := args[0]
+		if value[0] == '-' {
+			value = value[1:]
+		}
+		args = append([]string{"-n", value}, args[1:]...)
+	}
+
+	opts, operands, err := getopt(cmdName, "c:n:", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	count := int64(10)
+	countBytes := false
+	fromStart := false
+	for _, opt := range opts {
+		var unit string
+		switch opt.name {
+		case 'c':
+			countBytes = true
+			unit = "bytes"
+		case 'n':
+			countBytes = false
+			unit = "lines"
+		}
+
+		value := opt.value
+		fromStart = strings.HasPrefix(value, "+")
+		value = strings.TrimPrefix(strings.TrimPrefix(value, "+"), "-")
+
+		count, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || count < 0 {
+			err = ErrUsage{cmdName, fmt.Sprintf("invalid number of %s: '%s'", unit, opt.value)}
+			return usageCommand(pipe.StatusNotOkay, err)
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		var err error
+		switch {
+		case countBytes && fromStart:
+			err = tailBytesFrom(p, count)
+		case countBytes:
+			err = tailBytes(p, count)
+		case fromStart:
+			err = tailLinesFrom(p, count)
+		default:
+			err = tailLines(p, count)
+		}
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// tailBytesFrom copies the pipe's Stdin to its Stdout, starting at the
+// given byte (counting from 1)
+func tailBytesFrom(p *pipe.Pipe, start int64) error {
+	if start > 1 {
+		_, err := io.CopyN(ioutil.Discard, p.Stdin, start-1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := io.Copy(p.Stdout, p.Stdin)
+	return err
+}
+
+// tailBytes copies the last count bytes of the pipe's Stdin to its Stdout
+func tailBytes(p *pipe.Pipe, count int64) error {
+	input, err := ioutil.ReadAll(p.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(input)) > count {
+		input = input[int64(len(input))-count:]
+	}
+	p.Stdout.Write(input)
+
+	return nil
+}
+
+// tailLinesFrom copies the pipe's Stdin to its Stdout, starting at the
+// given line (counting from 1)
+func tailLinesFrom(p *pipe.Pipe, start int64) error {
+	lineNo := int64(0)
+	return eachLine(p.Stdin, func(line string, eol bool) error {
+		lineNo++
+		if lineNo >= start {
+			writeLine(p.Stdout, line, eol)
+		}
+		return nil
+	})
+}
+
+// tailLines copies the last count lines of the pipe's Stdin to its Stdout
+func tailLines(p *pipe.Pipe, count int64) error {
+	if count == 0 {
+		_, err := io.Copy(ioutil.Discard, p.Stdin)
+		return err
+	}
+
+	// we only keep the lines that we might need
+	type tailLine struct {
+		text string
+		eol  bool
+	}
+	ring := make([]tailLine, 0, minInt64(count, 1024))
+	next := 0
+
+	err := eachLine(p.Stdin, func(line string, eol bool) error {
+		if int64(len(ring)) < count {
+			ring = append(ring, tailLine{line, eol})
+			return nil
+		}
+
+		ring[next] = tailLine{line, eol}
+		next = (next + 1) % len(ring)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(ring); i++ {
+		line := ring[(next+i)%len(ring)]
+		writeLine(p.Stdout, line.text, line.eol)
+	}
+
+	return nil
+}
+
+// minInt64 returns the smaller of a and b
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,351 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// trClasses holds the characters in each of the [:class:] names that
+// tr understands, in the order that tr uses them
+var trClasses = map[string]string{}
+
+func init() {
+	classes := map[string]func(c byte) bool{
+		"alnum": func(c byte) bool { return isASCIIDigit(c) || isASCIILower(c) || isASCIIUpper(c) },
+		"alpha": func(c byte) bool { return isASCIILower(c) || isASCIIUpper(c) },
+		"blank": func(c byte) bool { return c == ' ' || c == '\t' },
+		"cntrl": func(c byte) bool { return c < 32 || c == 127 },
+		"digit": isASCIIDigit,
+		"graph": func(c byte) bool { return c > 32 && c < 127 },
+		"lower": isASCIILower,
+		"print": func(c byte) bool { return c >= 32 && c < 127 },
+		"punct": func(c byte) bool {
+			return c > 32 && c < 127 && !isASCIIDigit(c) && !isASCIILower(c) && !isASCIIUpper(c)
+		},
+		"space":  func(c byte) bool { return c == ' ' || (c >= '\t' && c <= '\r') },
+		"upper":  isASCIIUpper,
+		"xdigit": func(c byte) bool { return isASCIIDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') },
+	}
+
+	for name, fn := range classes {
+		var buf strings.Builder
+		for c := 0; c < 128; c++ {
+			if fn(byte(c)) {
+				buf.WriteByte(byte(c))
+			}
+		}
+		trClasses[name] = buf.String()
+	}
+}
+
+func isASCIIDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isASCIILower(c byte) bool { return c >= 'a' && c <= 'z' }
+func isASCIIUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+// Tr returns a PipeCommand that copies the pipe's Stdin to its Stdout,
+// translating, deleting or squeezing characters as it goes.
+//
+// It supports these options:
+//
+//	-c, -C  use the complement of set1
+//	-d      delete the characters in set1
+//	-s      squeeze runs of the same character into one
+//
+// Sets can contain ranges ("a-z"), character classes ("[:upper:]"),
+// repeats ("[x*5]", or "[x*]" to fill set2), and the escapes \\, \a,
+// \b, \f, \n, \r, \t, \v and \NNN (octal).
+//
+// Character classes only contain ASCII characters.
+func Tr(args ...string) pipe.PipeCommand {
+	const cmdName = "tr"
+
+	opts, operands, err := getopt(cmdName, "cCds", args)
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var complement, deleteChars, squeeze bool
+	for _, opt := range opts {
+		switch opt.name {
+		case 'c', 'C':
+			complement = true
+		case 'd':
+			deleteChars = true
+		case 's':
+			squeeze = true
+		}
+	}
+
+	// how many sets do we need?
+	wantSets := 2
+	if deleteChars && !squeeze || squeeze && !deleteChars && len(operands) < 2 {
+		wantSets = 1
+	}
+	switch {
+	case len(operands) < wantSets:
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "missing operand"})
+	case len(operands) > wantSets:
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("extra operand '%s'", operands[wantSets])})
+	}
+
+	set1, err := parseTrSet(operands[0], 0)
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+	}
+	var set2 []rune
+	if wantSets == 2 {
+		set2, err = parseTrSet(operands[1], len(set1))
+		if err != nil {
+			return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, err.Error()})
+		}
+		if len(set2) == 0 && !deleteChars {
+			return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, "when translating, set2 must not be empty"})
+		}
+	}
+
+	inSet1 := trMembership(set1, complement)
+
+	// work out what we are translating to
+	var translate func(r rune) rune
+	if !deleteChars && len(set2) > 0 {
+		if complement {
+			last := set2[len(set2)-1]
+			translate = func(r rune) rune {
+				if inSet1(r) {
+					return last
+				}
+				return r
+			}
+		} else {
+			mapping := map[rune]rune{}
+			for i, r := range set1 {
+				if i < len(set2) {
+					mapping[r] = set2[i]
+				} else {
+					mapping[r] = set2[len(set2)-1]
+				}
+			}
+			translate = func(r rune) rune {
+				if newR, ok := mapping[r]; ok {
+					return newR
+				}
+				return r
+			}
+		}
+	}
+
+	// work out what we are squeezing
+	var inSqueezeSet func(r rune) bool
+	if squeeze {
+		if len(set2) > 0 {
+			inSqueezeSet = trMembership(set2, false)
+		} else {
+			inSqueezeSet = inSet1
+		}
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		lastRune := rune(-1)
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			if eol {
+				line += "\n"
+			}
+
+			var buf strings.Builder
+			for _, r := range line {
+				if deleteChars && inSet1(r) {
+					continue
+				}
+				if translate != nil {
+					r = translate(r)
+				}
+				if inSqueezeSet != nil && r == lastRune && inSqueezeSet(r) {
+					continue
+				}
+
+				lastRune = r
+				buf.WriteRune(r)
+			}
+
+			p.Stdout.WriteString(buf.String())
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
+
+// trMembership returns a function that tells us if a rune is in set
+// (or not in set, if complement is true)
+func trMembership(set []rune, complement bool) func(r rune) bool {
+	members := make(map[rune]bool, len(set))
+	for _, r := range set {
+		members[r] = true
+	}
+
+	return func(r rune) bool {
+		return members[r] != complement
+	}
+}
+
+// parseTrSet expands a tr set into the list of characters that it
+// contains
+//
+// fillTo is the length of set1, used to expand "[x*]" in set2
+func parseTrSet(set string, fillTo int) ([]rune, error) {
+	var retval []rune
+	fillPos, fillRune := -1, rune(0)
+
+	input := []rune(set)
+	for i := 0; i < len(input); i++ {
+		// character class?
+		if input[i] == '[' && i+1 < len(input) && input[i+1] == ':' {
+			end := strings.Index(string(input[i+2:]), ":]")
+			if end >= 0 {
+				name := string(input[i+2:])[:end]
+				chars, ok := trClasses[name]
+				if !ok {
+					return nil, fmt.Errorf("invalid character class '%s'", name)
+				}
+				retval = append(retval, []rune(chars)...)
+				i += 2 + utf8.RuneCountInString(name) + 1
+				continue
+			}
+		}
+
+		// repeat?
+		if input[i] == '[' && i+2 < len(input) {
+			r, next := parseTrChar(input, i+1)
+			if next < len(input) && input[next] == '*' {
+				end := next + 1
+				for end < len(input) && input[end] != ']' {
+					end++
+				}
+				if end < len(input) {
+					countStr := string(input[next+1 : end])
+					if countStr == "" {
+						fillPos, fillRune = len(retval), r
+					} else {
+						count, err := strconv.ParseInt(countStr, 0, 32)
+						if err != nil || count < 0 {
+							return nil, fmt.Errorf("invalid repeat count '%s' in [c*n] construct", countStr)
+						}
+						for j := int64(0); j < count; j++ {
+							retval = append(retval, r)
+						}
+					}
+					i = end
+					continue
+				}
+			}
+		}
+
+		// single character, or a range
+		r, next := parseTrChar(input, i)
+		if next+1 < len(input) && input[next] == '-' {
+			end, afterEnd := parseTrChar(input, next+1)
+			if end < r {
+				return nil, fmt.Errorf("range-endpoints of '%c-%c' are in reverse collating sequence order", r, end)
+			}
+			for c := r; c <= end; c++ {
+				retval = append(retval, c)
+			}
+			i = afterEnd - 1
+			continue
+		}
+
+		retval = append(retval, r)
+		i = next - 1
+	}
+
+	// do we need to fill in a "[x*]"?
+	if fillPos >= 0 && fillTo > len(retval) {
+		fill := make([]rune, fillTo-len(retval))
+		for i := range fill {
+			fill[i] = fillRune
+		}
+		retval = append(retval[:fillPos], append(fill, retval[fillPos:]...)...)
+	}
+
+	return retval, nil
+}
+
+// parseTrChar parses the (possibly escaped) character at input[i], and
+// returns it along with the position of whatever comes next
+func parseTrChar(input []rune, i int) (rune, int) {
+	if input[i] != '\\' || i+1 >= len(input) {
+		return input[i], i + 1
+	}
+
+	// octal escape?
+	if input[i+1] >= '0' && input[i+1] <= '7' {
+		value := rune(0)
+		j := i + 1
+		for ; j < len(input) && j < i+4 && input[j] >= '0' && input[j] <= '7'; j++ {
+			value = value*8 + input[j] - '0'
+		}
+		return value, j
+	}
+
+	escapes := map[rune]rune{
+		'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n',
+		'r': '\r', 't': '\t', 'v': '\v',
+	}
+	if r, ok := escapes[input[i+1]]; ok {
+		return r, i + 2
+	}
+
+	return input[i+1], i + 2
+}
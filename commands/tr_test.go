@@ -0,0 +1,129 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestTr(t *testing.T) {
+	t.Parallel()
+
+	pipetest.RunCases(t, withArgs(commands.Tr), []pipetest.Case{
+		{
+			Name:       "translates ranges",
+			Args:       []string{"a-z", "A-Z"},
+			Stdin:      "hello, world\n",
+			WantStdout: "HELLO, WORLD\n",
+		},
+		{
+			Name:       "translates character classes",
+			Args:       []string{"[:upper:]", "[:lower:]"},
+			Stdin:      "Hello, World\n",
+			WantStdout: "hello, world\n",
+		},
+		{
+			Name:       "pads set2 with its last character",
+			Args:       []string{"abc", "x"},
+			Stdin:      "aabbcc\n",
+			WantStdout: "xxxxxx\n",
+		},
+		{
+			Name:       "supports [x*] in set2",
+			Args:       []string{"a-e", "[x*]"},
+			Stdin:      "abcdef\n",
+			WantStdout: "xxxxxf\n",
+		},
+		{
+			Name:       "supports escapes",
+			Args:       []string{`\n`, `\040`},
+			Stdin:      "one\ntwo\n",
+			WantStdout: "one two ",
+		},
+		{
+			Name:       "-d deletes characters",
+			Args:       []string{"-d", "aeiou"},
+			Stdin:      "hello, world\n",
+			WantStdout: "hll, wrld\n",
+		},
+		{
+			Name:       "-s squeezes characters",
+			Args:       []string{"-s", " "},
+			Stdin:      "a   b  c\n",
+			WantStdout: "a b c\n",
+		},
+		{
+			Name:       "-cs turns words into lines",
+			Args:       []string{"-cs", "[:alpha:]", `\n`},
+			Stdin:      "one, two; three\n",
+			WantStdout: "one\ntwo\nthree\n",
+		},
+		{
+			Name:       "-ds deletes, then squeezes",
+			Args:       []string{"-ds", "a", "l"},
+			Stdin:      "hallo all\n",
+			WantStdout: "hlo l\n",
+		},
+		{
+			Name:       "needs set2 when translating",
+			Args:       []string{"a-z"},
+			WantStderr: "tr: missing operand\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"tr", "missing operand"},
+		},
+		{
+			Name:       "rejects reversed ranges",
+			Args:       []string{"z-a", "x"},
+			WantStderr: "tr: range-endpoints of 'z-a' are in reverse collating sequence order\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"tr", "range-endpoints of 'z-a' are in reverse collating sequence order"},
+		},
+		{
+			Name:       "rejects unknown character classes",
+			Args:       []string{"-d", "[:vowel:]"},
+			WantStderr: "tr: invalid character class 'vowel'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"tr", "invalid character class 'vowel'"},
+		},
+	})
+}
@@ -0,0 +1,165 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Uniq returns a PipeCommand that copies the pipe's Stdin to its Stdout,
+// writing each run of adjacent identical lines only once.
+//
+// It supports these options:
+//
+//	-c         put the number of times each line appeared in front of it
+//	-d         only write lines that are repeated
+//	-f fields  ignore the first 'fields' fields when comparing lines
+//	-i         ignore case when comparing lines
+//	-s chars   ignore the first 'chars' characters when comparing lines
+//	-u         only write lines that are not repeated
+//
+// Uniq streams its output: it writes each line as soon as it has seen
+// the end of the run.
+func Uniq(args ...string) pipe.PipeCommand {
+	const cmdName = "uniq"
+
+	opts, operands, err := getopt(cmdName, "cdf:is:u", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var showCounts, onlyRepeated, onlyUnique, ignoreCase bool
+	var skipFields, skipChars int
+	for _, opt := range opts {
+		switch opt.name {
+		case 'c':
+			showCounts = true
+		case 'd':
+			onlyRepeated = true
+		case 'i':
+			ignoreCase = true
+		case 'u':
+			onlyUnique = true
+		case 'f', 's':
+			value, err := strconv.Atoi(opt.value)
+			if err != nil || value < 0 {
+				return usageCommand(pipe.StatusNotOkay, ErrUsage{cmdName, fmt.Sprintf("invalid number: '%s'", opt.value)})
+			}
+			if opt.name == 'f' {
+				skipFields = value
+			} else {
+				skipChars = value
+			}
+		}
+	}
+
+	// compareKey returns the part of the line that we compare
+	compareKey := func(line string) string {
+		for i := 0; i < skipFields; i++ {
+			line = strings.TrimLeft(line, " \t")
+			pos := strings.IndexAny(line, " \t")
+			if pos < 0 {
+				line = ""
+				break
+			}
+			line = line[pos:]
+		}
+
+		runes := []rune(line)
+		if skipChars >= len(runes) {
+			return ""
+		}
+		line = string(runes[skipChars:])
+
+		if ignoreCase {
+			return strings.ToLower(line)
+		}
+		return line
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		var current, currentKey string
+		count := 0
+
+		// writeCurrent writes out the line for the run we have just seen
+		writeCurrent := func() {
+			if count == 0 || onlyRepeated && count == 1 || onlyUnique && count > 1 {
+				return
+			}
+
+			if showCounts {
+				p.Stdout.WriteString(fmt.Sprintf("%7d %s\n", count, current))
+				return
+			}
+			p.Stdout.WriteString(current + "\n")
+		}
+
+		err := eachLine(p.Stdin, func(line string, eol bool) error {
+			key := compareKey(line)
+			if count > 0 && key == currentKey {
+				count++
+				return nil
+			}
+
+			writeCurrent()
+			current, currentKey, count = line, key, 1
+			return nil
+		})
+		if err != nil {
+			return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+		}
+		writeCurrent()
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
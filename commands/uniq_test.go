@@ -0,0 +1,104 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestUniq(t *testing.T) {
+	t.Parallel()
+
+	input := "a\na\nb\nA\nc\nc\nc\n"
+
+	pipetest.RunCases(t, withArgs(commands.Uniq), []pipetest.Case{
+		{
+			Name:       "removes adjacent duplicates",
+			Stdin:      input,
+			WantStdout: "a\nb\nA\nc\n",
+		},
+		{
+			Name:       "-c counts each line",
+			Args:       []string{"-c"},
+			Stdin:      input,
+			WantStdout: "      2 a\n      1 b\n      1 A\n      3 c\n",
+		},
+		{
+			Name:       "-d only writes repeated lines",
+			Args:       []string{"-d"},
+			Stdin:      input,
+			WantStdout: "a\nc\n",
+		},
+		{
+			Name:       "-u only writes unique lines",
+			Args:       []string{"-u"},
+			Stdin:      input,
+			WantStdout: "b\nA\n",
+		},
+		{
+			Name:       "-i ignores case",
+			Args:       []string{"-i"},
+			Stdin:      "b\nB\nb\n",
+			WantStdout: "b\n",
+		},
+		{
+			Name:       "-f skips fields",
+			Args:       []string{"-f", "1"},
+			Stdin:      "1 apple\n2 apple\n3 pear\n",
+			WantStdout: "1 apple\n3 pear\n",
+		},
+		{
+			Name:       "-s skips characters",
+			Args:       []string{"-s", "2"},
+			Stdin:      "1 apple\n2 apple\n3 pear\n",
+			WantStdout: "1 apple\n3 pear\n",
+		},
+		{
+			Name:       "complains about invalid numbers",
+			Args:       []string{"-f", "x"},
+			WantStderr: "uniq: invalid number: 'x'\n",
+			WantStatus: 1,
+			WantErr:    commands.ErrUsage{"uniq", "invalid number: 'x'"},
+		},
+	})
+}
@@ -0,0 +1,145 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// option is a single option found by getopt
+type option struct {
+	name  byte
+	value string
+}
+
+// getopt parses args in the style of POSIX getopt(3).
+//
+// spec lists the option letters that the command accepts. A letter
+// followed by a ':' takes a value, which can be attached ("-n5") or
+// the next argument ("-n 5").
+//
+// Options stop at the first operand, or at "--". A lone "-" is an
+// operand.
+func getopt(cmdName string, spec string, args []string) ([]option, []string, error) {
+	var opts []option
+
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+
+		// have we run out of options?
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+
+		// this arg can hold several options, eg "-nb"
+		for j := 1; j < len(arg); j++ {
+			name := arg[j]
+			pos := strings.IndexByte(spec, name)
+			if pos < 0 || name == ':' {
+				return nil, nil, ErrUsage{cmdName, fmt.Sprintf("invalid option -- '%c'", name)}
+			}
+
+			// does this option take a value?
+			if pos+1 >= len(spec) || spec[pos+1] != ':' {
+				opts = append(opts, option{name: name})
+				continue
+			}
+
+			// is the value attached?
+			if j+1 < len(arg) {
+				opts = append(opts, option{name, arg[j+1:]})
+				break
+			}
+
+			// no, it is the next arg
+			i++
+			if i >= len(args) {
+				return nil, nil, ErrUsage{cmdName, fmt.Sprintf("option requires an argument -- '%c'", name)}
+			}
+			opts = append(opts, option{name, args[i]})
+		}
+	}
+
+	// all done
+	return opts, args[i:], nil
+}
+
+// checkOperands makes sure that the only operands are "-", which means
+// the pipe's Stdin
+func checkOperands(cmdName string, operands []string) error {
+	for _, operand := range operands {
+		if operand != "-" {
+			return ErrUsage{cmdName, fmt.Sprintf("file operands are not supported: '%s'", operand)}
+		}
+	}
+
+	return nil
+}
+
+// reportError writes err to the pipe's Stderr, and returns it along
+// with the given status code
+func reportError(p *pipe.Pipe, statusCode int, err error) (int, error) {
+	if p.Stderr != nil {
+		p.Stderr.WriteString(err.Error())
+		p.Stderr.WriteRune('\n')
+	}
+
+	return statusCode, err
+}
+
+// usageCommand returns a PipeCommand that reports the given usage error
+func usageCommand(statusCode int, err error) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return statusCode, err
+		}
+
+		return reportError(p, statusCode, err)
+	}
+}
@@ -0,0 +1,109 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// errStopReading is returned by an eachLine callback, when it does not
+// need to see any more lines
+var errStopReading = errors.New("stop reading")
+
+// eachLine calls fn for each line that it reads from r, as soon as it
+// has read that line.
+//
+// fn receives the line without its line ending. eol tells fn whether
+// or not the line had a line ending; only the last line in r can be
+// without one.
+//
+// If fn returns errStopReading, eachLine stops, and returns nil.
+func eachLine(r io.Reader, fn func(line string, eol bool) error) error {
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			eol := strings.HasSuffix(line, "\n")
+			if eol {
+				line = line[:len(line)-1]
+			}
+
+			fnErr := fn(line, eol)
+			if fnErr == errStopReading {
+				return nil
+			}
+			if fnErr != nil {
+				return fnErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLines returns all of the lines in r, without their line endings
+func readLines(r io.Reader) ([]string, error) {
+	var retval []string
+	err := eachLine(r, func(line string, eol bool) error {
+		retval = append(retval, line)
+		return nil
+	})
+
+	return retval, err
+}
+
+// writeLine writes line to w, followed by a line ending if eol is true
+func writeLine(w ioextra.TextWriter, line string, eol bool) {
+	if eol {
+		line += "\n"
+	}
+	w.WriteString(line)
+}
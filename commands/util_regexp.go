@@ -0,0 +1,204 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// posixToRE2 turns a POSIX regular expression into one that Golang's
+// regexp package understands.
+//
+// If extended is false, pattern is a basic regular expression (BRE), as
+// used by grep and sed by default. Otherwise, it is an extended regular
+// expression (ERE), as used by grep -E and sed -E.
+//
+// We also support the common GNU extensions: \+, \? and \| in BREs, and
+// \<, \>, \w, \W, \s, \S, \b and \B in both.
+func posixToRE2(pattern string, extended bool) (string, error) {
+	var buf strings.Builder
+
+	// atStart is true wherever a '*' would be a literal in a BRE
+	atStart := true
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		wasAtStart := atStart
+		atStart = false
+
+		switch c {
+		case '\\':
+			i++
+			if i >= len(pattern) {
+				return "", fmt.Errorf("trailing backslash (\\)")
+			}
+			next := pattern[i]
+
+			switch {
+			case next >= '1' && next <= '9':
+				return "", fmt.Errorf("back-references are not supported")
+			case next == '<' || next == '>':
+				buf.WriteString(`\b`)
+			case strings.IndexByte("wWsSbB", next) >= 0:
+				buf.WriteByte('\\')
+				buf.WriteByte(next)
+			case !extended && strings.IndexByte("(){}|+?", next) >= 0:
+				// these are operators in a BRE, when escaped
+				buf.WriteByte(next)
+				atStart = next == '(' || next == '|'
+			case next == 'n':
+				buf.WriteString(`\n`)
+			case next == 't':
+				buf.WriteString(`\t`)
+			default:
+				writeLiteral(&buf, next)
+			}
+
+		case '[':
+			end, err := copyBracketExpression(&buf, pattern, i)
+			if err != nil {
+				return "", err
+			}
+			i = end
+
+		case '(', ')', '{', '}', '|', '+', '?':
+			// these are operators in an ERE, and literals in a BRE
+			if extended {
+				buf.WriteByte(c)
+				atStart = c == '(' || c == '|'
+			} else {
+				writeLiteral(&buf, c)
+			}
+
+		case '*':
+			if wasAtStart && !extended {
+				buf.WriteString(`\*`)
+			} else {
+				buf.WriteByte(c)
+			}
+
+		case '^':
+			if wasAtStart || extended {
+				buf.WriteByte(c)
+				atStart = true
+			} else {
+				buf.WriteString(`\^`)
+			}
+
+		case '$':
+			if extended || isEndOfBRE(pattern, i+1) {
+				buf.WriteByte(c)
+			} else {
+				buf.WriteString(`\$`)
+			}
+
+		case '.':
+			buf.WriteByte(c)
+
+		default:
+			writeLiteral(&buf, c)
+		}
+	}
+
+	// all done
+	return buf.String(), nil
+}
+
+// isEndOfBRE returns true if pos is where a '$' anchor would be
+// recognised in a BRE
+func isEndOfBRE(pattern string, pos int) bool {
+	rest := pattern[pos:]
+	return rest == "" || strings.HasPrefix(rest, `\)`) || strings.HasPrefix(rest, `\|`)
+}
+
+// writeLiteral writes c to buf, escaping it if Golang's regexp package
+// would otherwise treat it as special
+func writeLiteral(buf *strings.Builder, c byte) {
+	if strings.IndexByte(`\.+*?()|[]{}^$`, c) >= 0 {
+		buf.WriteByte('\\')
+	}
+	buf.WriteByte(c)
+}
+
+// copyBracketExpression copies the bracket expression that starts at
+// pattern[start] into buf, and returns the position of its closing ']'
+//
+// Inside a POSIX bracket expression, a backslash is a literal. Golang
+// treats it as an escape, so we have to escape it.
+func copyBracketExpression(buf *strings.Builder, pattern string, start int) (int, error) {
+	buf.WriteByte('[')
+
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		buf.WriteByte('^')
+		i++
+	}
+
+	// a ']' straight after the '[' (or '[^') is a literal
+	if i < len(pattern) && pattern[i] == ']' {
+		buf.WriteString(`\]`)
+		i++
+	}
+
+	for ; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == ']':
+			buf.WriteByte(']')
+			return i, nil
+		case c == '[' && i+1 < len(pattern) && pattern[i+1] == ':':
+			// a character class, eg [:alpha:]
+			end := strings.Index(pattern[i+2:], ":]")
+			if end < 0 {
+				return 0, fmt.Errorf("unterminated character class")
+			}
+			buf.WriteString(pattern[i : i+2+end+2])
+			i += 2 + end + 1
+		case c == '\\' || c == '[':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return 0, fmt.Errorf("unmatched [, [^, [:, [., or [=")
+}
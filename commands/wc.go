@@ -0,0 +1,156 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Wc returns a PipeCommand that counts the lines, words and bytes in
+// the pipe's Stdin, and writes the counts to its Stdout.
+//
+// It supports these options:
+//
+//	-c  count bytes
+//	-l  count lines
+//	-m  count characters
+//	-w  count words
+//
+// With no options, it counts lines, words and bytes. The counts are
+// always written in the order lines, words, characters, bytes.
+func Wc(args ...string) pipe.PipeCommand {
+	const cmdName = "wc"
+
+	opts, operands, err := getopt(cmdName, "clmw", args)
+	if err == nil {
+		err = checkOperands(cmdName, operands)
+	}
+	if err != nil {
+		return usageCommand(pipe.StatusNotOkay, err)
+	}
+
+	var countBytes, countLines, countChars, countWords bool
+	for _, opt := range opts {
+		switch opt.name {
+		case 'c':
+			countBytes = true
+		case 'l':
+			countLines = true
+		case 'm':
+			countChars = true
+		case 'w':
+			countWords = true
+		}
+	}
+	if len(opts) == 0 {
+		countLines, countWords, countBytes = true, true, true
+	}
+
+	return func(p *pipe.Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return pipe.StatusOkay, nil
+		}
+
+		var lines, words, chars, bytes int64
+		inWord := false
+
+		reader := bufio.NewReader(p.Stdin)
+		for {
+			r, size, err := reader.ReadRune()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return reportError(p, pipe.StatusNotOkay, ErrCommand{cmdName, err})
+			}
+
+			bytes += int64(size)
+			if r != utf8.RuneError || size > 1 {
+				chars++
+			}
+			if r == '\n' {
+				lines++
+			}
+
+			if unicode.IsSpace(r) {
+				inWord = false
+			} else if !inWord {
+				inWord = true
+				words++
+			}
+		}
+
+		var counts []int64
+		if countLines {
+			counts = append(counts, lines)
+		}
+		if countWords {
+			counts = append(counts, words)
+		}
+		if countChars {
+			counts = append(counts, chars)
+		}
+		if countBytes {
+			counts = append(counts, bytes)
+		}
+
+		// a single count is written as-is; several counts line up
+		format := "%d"
+		if len(counts) > 1 {
+			format = "%7d"
+		}
+		parts := make([]string, len(counts))
+		for i, count := range counts {
+			parts[i] = fmt.Sprintf(format, count)
+		}
+		p.Stdout.WriteString(strings.Join(parts, " ") + "\n")
+
+		// all done
+		return pipe.StatusOkay, nil
+	}
+}
@@ -0,0 +1,89 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/commands"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func TestWc(t *testing.T) {
+	t.Parallel()
+
+	input := "one two\n\nthree  four\tfive\nhéllo"
+
+	pipetest.RunCases(t, withArgs(commands.Wc), []pipetest.Case{
+		{
+			Name:       "counts lines, words and bytes",
+			Stdin:      input,
+			WantStdout: "      3       6      32\n",
+		},
+		{
+			Name:       "-l counts lines",
+			Args:       []string{"-l"},
+			Stdin:      input,
+			WantStdout: "3\n",
+		},
+		{
+			Name:       "-w counts words",
+			Args:       []string{"-w"},
+			Stdin:      input,
+			WantStdout: "6\n",
+		},
+		{
+			Name:       "-m counts characters",
+			Args:       []string{"-m"},
+			Stdin:      input,
+			WantStdout: "31\n",
+		},
+		{
+			Name:       "always writes counts in the same order",
+			Args:       []string{"-c", "-l"},
+			Stdin:      input,
+			WantStdout: "      3      32\n",
+		},
+		{
+			Name:       "copes with empty input",
+			WantStdout: "      0       0       0\n",
+		},
+	})
+}
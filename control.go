@@ -0,0 +1,217 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import "errors"
+
+// If returns a PipeCommand that runs cond, and then runs thenCmd if cond
+// succeeds, or elseCmd if it fails. elseCmd can be nil.
+//
+// A PipeCommand succeeds if it returns StatusOkay and no error, just like
+// a shell command that exits with status code 0.
+//
+// If returns the status code and error of whichever branch it ran. If it
+// did not run a branch, it returns StatusOkay.
+func If(cond, thenCmd, elseCmd PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		statusCode, err := cond(p)
+		if isLoopControl(err) {
+			return statusCode, err
+		}
+
+		switch {
+		case succeeded(statusCode, err):
+			return thenCmd(p)
+		case elseCmd != nil:
+			return elseCmd(p)
+		default:
+			return StatusOkay, nil
+		}
+	}
+}
+
+// While returns a PipeCommand that keeps running body for as long as
+// cond succeeds.
+//
+// body can return ErrBreak to stop the loop, or ErrContinue to skip
+// straight to the next check of cond. If the pipe has errexit turned on
+// (see WithErrexit), the loop stops as soon as body fails.
+//
+// While returns the status code and error of the last time that body
+// ran, or StatusOkay if body never ran. It stops early if the pipe's
+// context is cancelled.
+func While(cond, body PipeCommand) PipeCommand {
+	return loop(func(p *Pipe) (bool, error) {
+		statusCode, err := cond(p)
+		return succeeded(statusCode, err), err
+	}, body)
+}
+
+// Until returns a PipeCommand that keeps running body for as long as
+// cond fails.
+//
+// Apart from that, it works just like While.
+func Until(cond, body PipeCommand) PipeCommand {
+	return loop(func(p *Pipe) (bool, error) {
+		statusCode, err := cond(p)
+		return !succeeded(statusCode, err), err
+	}, body)
+}
+
+// ForEach returns a PipeCommand that runs a PipeCommand for each of the
+// given values, in order. body is called to build each PipeCommand.
+//
+// The PipeCommands can return ErrBreak and ErrContinue, and the pipe's
+// errexit setting is respected, just like While.
+//
+// To loop over the lines of the pipe's Stdin, use ForEachLine.
+func ForEach(values []string, body func(value string) PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		next := 0
+		return loop(
+			func(p *Pipe) (bool, error) {
+				return next < len(values), nil
+			},
+			func(p *Pipe) (int, error) {
+				value := values[next]
+				next++
+				return body(value)(p)
+			},
+		)(p)
+	}
+}
+
+// Break is a PipeCommand that stops the loop that it is in.
+//
+// If you are using ForEachLine, return ErrBreak from your function
+// instead.
+func Break(p *Pipe) (int, error) {
+	return StatusOkay, ErrBreak{}
+}
+
+// Continue is a PipeCommand that skips to the next time around the loop
+// that it is in.
+func Continue(p *Pipe) (int, error) {
+	return StatusOkay, ErrContinue{}
+}
+
+// WithErrexit returns a PipeOption that turns errexit on or off, like
+// `set -e` in a UNIX shell.
+//
+// When errexit is on, While, Until and ForEach stop as soon as one of
+// their PipeCommands fails. When it is off (the default), they
+// keep going, and report the result of the last PipeCommand that they
+// ran.
+//
+// Child pipes start with their parent's setting.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithErrexit(enabled bool) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		// yes we do
+		p.errexit = enabled
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// loop does the work for all of our loops
+//
+// cond returns true if we should run body again, and any error that it
+// got from running the loop's condition
+func loop(cond func(p *Pipe) (bool, error), body PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		statusCode, err := StatusOkay, error(nil)
+		for {
+			// has someone told us to stop?
+			if ctxErr := p.Context().Err(); ctxErr != nil {
+				return StatusNotOkay, ctxErr
+			}
+
+			again, condErr := cond(p)
+			switch {
+			case errors.As(condErr, &ErrBreak{}):
+				return statusCode, err
+			case errors.As(condErr, &ErrContinue{}):
+				continue
+			case !again:
+				return statusCode, err
+			}
+
+			statusCode, err = body(p)
+			switch {
+			case errors.As(err, &ErrBreak{}):
+				return StatusOkay, nil
+			case errors.As(err, &ErrContinue{}):
+				statusCode, err = StatusOkay, nil
+			case p.errexit && !succeeded(statusCode, err):
+				return statusCode, err
+			}
+		}
+	}
+}
+
+// succeeded returns true if a PipeCommand's return values mean that it
+// worked
+func succeeded(statusCode int, err error) bool {
+	return statusCode == StatusOkay && err == nil
+}
+
+// isLoopControl returns true if err is ErrBreak or ErrContinue
+func isLoopControl(err error) bool {
+	return errors.As(err, &ErrBreak{}) || errors.As(err, &ErrContinue{})
+}
@@ -0,0 +1,406 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// echo returns a PipeCommand that writes msg and a newline to Stdout
+func echo(msg string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString(msg + "\n")
+		return pipe.StatusOkay, nil
+	}
+}
+
+// exitWith returns a PipeCommand that returns the given status code
+func exitWith(statusCode int) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		return statusCode, nil
+	}
+}
+
+// counter returns a PipeCommand that succeeds while the count is below
+// limit, and a pointer to the count
+func counter(limit int) (pipe.PipeCommand, *int) {
+	count := 0
+	return func(p *pipe.Pipe) (int, error) {
+		count++
+		if count <= limit {
+			return pipe.StatusOkay, nil
+		}
+		return pipe.StatusNotOkay, nil
+	}, &count
+}
+
+func TestIf(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		cmd                pipe.PipeCommand
+		expectedStdout     string
+		expectedStatusCode int
+	}{
+		{
+			name:           "runs the then branch when the condition succeeds",
+			cmd:            pipe.If(exitWith(0), echo("then"), echo("else")),
+			expectedStdout: "then\n",
+		},
+		{
+			name:           "runs the else branch when the condition fails",
+			cmd:            pipe.If(exitWith(1), echo("then"), echo("else")),
+			expectedStdout: "else\n",
+		},
+		{
+			name:           "an error counts as failing",
+			cmd:            pipe.If(func(p *pipe.Pipe) (int, error) { return 0, errors.New("oops") }, echo("then"), echo("else")),
+			expectedStdout: "else\n",
+		},
+		{
+			name:           "the else branch is optional",
+			cmd:            pipe.If(exitWith(1), echo("then"), nil),
+			expectedStdout: "",
+		},
+		{
+			name:               "returns the status code of the branch",
+			cmd:                pipe.If(exitWith(0), exitWith(3), nil),
+			expectedStatusCode: 3,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(testCase.cmd)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedStatusCode, unit.StatusCode())
+			assert.Equal(t, testCase.expectedStdout, unit.Stdout.String())
+		})
+	}
+}
+
+func TestWhileLoopsUntilTheConditionFails(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cond, count := counter(3)
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.While(cond, echo("again")))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, 4, *count)
+	assert.Equal(t, "again\nagain\nagain\n", unit.Stdout.String())
+}
+
+func TestUntilLoopsUntilTheConditionSucceeds(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	count := 0
+	cond := func(p *pipe.Pipe) (int, error) {
+		count++
+		if count > 2 {
+			return pipe.StatusOkay, nil
+		}
+		return pipe.StatusNotOkay, nil
+	}
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Until(cond, echo("again")))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "again\nagain\n", unit.Stdout.String())
+}
+
+func TestForEachRunsTheBodyForEachValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEach([]string{"a", "b", "c"}, echo))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "a\nb\nc\n", unit.Stdout.String())
+}
+
+func TestLoopsSupportBreakAndContinue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	values := []string{"1", "2", "3", "4", "5", "6"}
+	body := func(value string) pipe.PipeCommand {
+		n, _ := strconv.Atoi(value)
+		switch {
+		case n == 5:
+			return pipe.Break
+		case n%2 == 0:
+			return pipe.Continue
+		default:
+			return echo(value)
+		}
+	}
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEach(values, body))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "1\n3\n", unit.Stdout.String())
+}
+
+func TestBreakInsideIfStopsTheEnclosingLoop(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cond, count := counter(100)
+	unit := pipe.NewPipe()
+	seen := 0
+	body := pipe.If(
+		func(p *pipe.Pipe) (int, error) {
+			seen++
+			if seen == 3 {
+				return pipe.StatusOkay, nil
+			}
+			return pipe.StatusNotOkay, nil
+		},
+		pipe.Break,
+		echo("again"),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.While(cond, body))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, 3, *count)
+	assert.Equal(t, "again\nagain\n", unit.Stdout.String())
+}
+
+func TestBreakOnlyStopsTheInnermostLoop(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	inner := func(outer string) pipe.PipeCommand {
+		return pipe.ForEach([]string{"x", "y"}, func(value string) pipe.PipeCommand {
+			if value == "y" {
+				return pipe.Break
+			}
+			return echo(outer + value)
+		})
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEach([]string{"a", "b"}, inner))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "ax\nbx\n", unit.Stdout.String())
+}
+
+func TestLoopsRespectErrexit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		errexit            bool
+		expectedStdout     string
+		expectedStatusCode int
+	}{
+		{
+			name:               "without errexit, loops keep going",
+			errexit:            false,
+			expectedStdout:     "a\nc\n",
+			expectedStatusCode: pipe.StatusOkay,
+		},
+		{
+			name:               "with errexit, loops stop at the first failure",
+			errexit:            true,
+			expectedStdout:     "a\n",
+			expectedStatusCode: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe(pipe.WithErrexit(testCase.errexit))
+			body := func(value string) pipe.PipeCommand {
+				if value == "b" {
+					return exitWith(2)
+				}
+				return echo(value)
+			}
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.ForEach([]string{"a", "b", "c"}, body))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedStatusCode, unit.StatusCode())
+			assert.Equal(t, testCase.expectedStdout, unit.Stdout.String())
+		})
+	}
+}
+
+func TestLoopsReturnTheLastBodyStatus(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	body := func(value string) pipe.PipeCommand {
+		return exitWith(len(value))
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEach([]string{"", "abc"}, body))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 3, unit.StatusCode())
+}
+
+func TestLoopsStopWhenTheContextIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+	runs := 0
+	body := func(p *pipe.Pipe) (int, error) {
+		runs++
+		if runs == 3 {
+			cancel()
+		}
+		return pipe.StatusOkay, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.While(exitWith(0), body))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, context.Canceled, unit.Error())
+}
@@ -0,0 +1,577 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RecordFormat describes how records are laid out in the pipe's Stdin
+// or Stdout.
+type RecordFormat int
+
+const (
+	// SameFormat means "the same as the input". As an input format, it
+	// means CSVFormat.
+	SameFormat RecordFormat = iota
+
+	// CSVFormat is comma-separated values, with quoting as described
+	// in RFC 4180
+	CSVFormat
+
+	// TSVFormat is tab-separated values. Fields are not quoted. Instead,
+	// tabs, newlines, carriage returns and backslashes in a field are
+	// written as \t, \n, \r and \\.
+	TSVFormat
+
+	// JSONLinesFormat writes each record as a JSON object, on a line of
+	// its own. It can only be used for output.
+	JSONLinesFormat
+)
+
+// CSVHeader tells CSV and TSV whether or not the first record in the
+// pipe's Stdin holds the column names.
+type CSVHeader int
+
+const (
+	// DetectHeader looks at the first few records, to work out if the
+	// first record is a header or not. It needs at least one column of
+	// numbers to spot a header; if every column holds text, use HasHeader
+	DetectHeader CSVHeader = iota
+
+	// HasHeader means that the first record is always a header
+	HasHeader
+
+	// NoHeader means that there is no header. The columns are named
+	// "1", "2", "3" and so on.
+	NoHeader
+)
+
+// csvSniffRows is how many records DetectHeader looks at
+const csvSniffRows = 20
+
+// CSVOptions tells CSV and TSV how to read, change and write records.
+type CSVOptions struct {
+	// Format is the layout of the pipe's Stdin. It must be CSVFormat
+	// or TSVFormat.
+	Format RecordFormat
+
+	// Delimiter replaces the default field delimiter for Format, if
+	// it is set
+	Delimiter rune
+
+	// Header says whether or not the first record holds column names
+	Header CSVHeader
+
+	// Filter decides which records are kept. It sees every column,
+	// before Columns and Rename are applied. If Filter is nil, every
+	// record is kept.
+	Filter func(row CSVRow) bool
+
+	// Columns lists the columns to write, in the order to write them.
+	// Use a column's name or its position (counting from 1). If Columns
+	// is empty, every column is written.
+	Columns []string
+
+	// Rename maps column names to the names that we write instead
+	Rename map[string]string
+
+	// OutputFormat is the layout to use when writing to the pipe's
+	// Stdout
+	OutputFormat RecordFormat
+
+	// OutputDelimiter replaces the default field delimiter for
+	// OutputFormat, if it is set
+	OutputDelimiter rune
+
+	// OmitHeader stops us writing a header to the pipe's Stdout. We
+	// only ever write a header if the pipe's Stdin had one.
+	OmitHeader bool
+}
+
+// CSVRow is a single record from the pipe's Stdin.
+type CSVRow struct {
+	// RecordNo is the position of this record in the pipe's Stdin,
+	// counting from 1 and not including any header
+	RecordNo int
+
+	// Columns holds the name of each column
+	Columns []string
+
+	// Fields holds the value of each column
+	Fields []string
+}
+
+// Get returns the value of the given column, or an empty string if
+// this row does not have that column.
+//
+// column can be the column's name, or its position (counting from 1).
+func (r CSVRow) Get(column string) string {
+	i := findColumn(r.Columns, column)
+	if i < 0 || i >= len(r.Fields) {
+		return ""
+	}
+
+	return r.Fields[i]
+}
+
+// CSV returns a PipeCommand that reads records from the pipe's Stdin,
+// filters them, selects, reorders and renames their columns, and writes
+// them to the pipe's Stdout.
+//
+// Records are processed as they are read. The only exception is when
+// opts.Header is DetectHeader: then, we read the first few records
+// before we write anything.
+func CSV(opts CSVOptions) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return StatusOkay, nil
+		}
+
+		err := processCSV(p, opts)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// TSV is the same as CSV, except that the pipe's Stdin holds TSVFormat
+// records.
+func TSV(opts CSVOptions) PipeCommand {
+	opts.Format = TSVFormat
+	return CSV(opts)
+}
+
+// processCSV does the work for CSV and TSV
+func processCSV(p *Pipe, opts CSVOptions) error {
+	reader, err := newRecordReader(p.Stdin, opts.Format, opts.Delimiter)
+	if err != nil {
+		return err
+	}
+
+	// work out what we are writing
+	outputFormat := opts.OutputFormat
+	outputDelimiter := opts.OutputDelimiter
+	if outputFormat == SameFormat {
+		outputFormat = reader.format
+		if outputDelimiter == 0 {
+			outputDelimiter = opts.Delimiter
+		}
+	}
+	writer, err := newRecordWriter(p.Stdout, outputFormat, outputDelimiter)
+	if err != nil {
+		return err
+	}
+
+	// we may need to look ahead to find the header
+	var pending [][]string
+	hasHeader := opts.Header == HasHeader
+	if opts.Header == DetectHeader {
+		for len(pending) < csvSniffRows {
+			record, err := reader.read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			pending = append(pending, record)
+		}
+		hasHeader = looksLikeHeader(pending)
+	}
+
+	// nextRecord returns the pending records first
+	nextRecord := func() ([]string, error) {
+		if len(pending) > 0 {
+			retval := pending[0]
+			pending = pending[1:]
+			return retval, nil
+		}
+		return reader.read()
+	}
+
+	var columns []string
+	var selected []int
+	recordNo := 0
+	for {
+		record, err := nextRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// is this the first record?
+		if columns == nil {
+			if hasHeader {
+				columns = record
+			} else {
+				columns = make([]string, len(record))
+				for i := range columns {
+					columns[i] = strconv.Itoa(i + 1)
+				}
+			}
+
+			selected, err = selectColumns(columns, opts.Columns)
+			if err != nil {
+				return err
+			}
+			names := make([]string, len(selected))
+			for i, column := range selected {
+				names[i] = columns[column]
+				if newName, ok := opts.Rename[names[i]]; ok {
+					names[i] = newName
+				}
+			}
+			writer.setColumns(names)
+
+			if hasHeader {
+				if !opts.OmitHeader {
+					err = writer.writeHeader()
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+
+		recordNo++
+		row := CSVRow{RecordNo: recordNo, Columns: columns, Fields: record}
+		if opts.Filter != nil && !opts.Filter(row) {
+			continue
+		}
+
+		fields := make([]string, len(selected))
+		for i, column := range selected {
+			if column < len(record) {
+				fields[i] = record[column]
+			}
+		}
+		err = writer.write(fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.flush()
+}
+
+// findColumn returns the position of the given column, or -1 if there
+// is no such column
+//
+// column can be a name, or a position (counting from 1). Names win.
+func findColumn(columns []string, column string) int {
+	for i, name := range columns {
+		if name == column {
+			return i
+		}
+	}
+
+	pos, err := strconv.Atoi(column)
+	if err == nil && pos >= 1 && pos <= len(columns) {
+		return pos - 1
+	}
+
+	return -1
+}
+
+// selectColumns returns the positions of the columns that we want
+func selectColumns(columns []string, wanted []string) ([]int, error) {
+	// do we want everything?
+	if len(wanted) == 0 {
+		retval := make([]int, len(columns))
+		for i := range retval {
+			retval[i] = i
+		}
+		return retval, nil
+	}
+
+	retval := make([]int, len(wanted))
+	for i, column := range wanted {
+		retval[i] = findColumn(columns, column)
+		if retval[i] < 0 {
+			return nil, ErrUnknownColumn{column}
+		}
+	}
+
+	return retval, nil
+}
+
+// looksLikeHeader decides whether or not the first record is a header
+//
+// A header must have unique, non-empty names, and none of them can be
+// numbers. After that, we look at each column: if the values below the
+// header are all numbers, that's a vote for it being a header. If the
+// values are all the same length as the header, that's a vote against.
+// We need more votes for than against, so a tie means there is no header.
+//
+// Text on its own is never a vote for a header: a column of text looks
+// just the same with or without one.
+//
+// A single record is never a header, because there is nothing to
+// compare it against.
+func looksLikeHeader(records [][]string) bool {
+	if len(records) == 0 {
+		return false
+	}
+	header := records[0]
+
+	seen := map[string]bool{}
+	for _, name := range header {
+		if name == "" || seen[name] || isNumeric(name) {
+			return false
+		}
+		seen[name] = true
+	}
+
+	// with nothing to compare against, we cannot tell
+	if len(records) == 1 {
+		return false
+	}
+
+	votes := 0
+	for i, name := range header {
+		allNumeric := true
+		length := -1
+		for _, record := range records[1:] {
+			if i >= len(record) {
+				continue
+			}
+			allNumeric = allNumeric && isNumeric(record[i])
+			switch {
+			case length == -1:
+				length = len(record[i])
+			case length != len(record[i]):
+				length = -2
+			}
+		}
+
+		switch {
+		case allNumeric:
+			votes++
+		case length >= 0 && length == len(name):
+			votes--
+		}
+	}
+
+	return votes > 0
+}
+
+// isNumeric returns true if s holds a number
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
+
+// recordReader reads records in CSV or TSV format
+type recordReader struct {
+	format RecordFormat
+	read   func() ([]string, error)
+}
+
+func newRecordReader(r io.Reader, format RecordFormat, delimiter rune) (*recordReader, error) {
+	switch format {
+	case SameFormat, CSVFormat:
+		csvReader := csv.NewReader(r)
+		csvReader.FieldsPerRecord = -1
+		if delimiter != 0 {
+			csvReader.Comma = delimiter
+		}
+		return &recordReader{CSVFormat, csvReader.Read}, nil
+
+	case TSVFormat:
+		if delimiter == 0 {
+			delimiter = '\t'
+		}
+		scanner := bufio.NewReader(r)
+		return &recordReader{
+			format: TSVFormat,
+			read: func() ([]string, error) {
+				line, err := scanner.ReadString('\n')
+				if err == io.EOF && line != "" {
+					err = nil
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+				fields := strings.Split(line, string(delimiter))
+				for i, field := range fields {
+					fields[i] = unescapeTSVField(field)
+				}
+				return fields, nil
+			},
+		}, nil
+
+	default:
+		return nil, ErrUnsupportedRecordFormat{format}
+	}
+}
+
+// recordWriter writes records in CSV, TSV or JSON Lines format
+type recordWriter struct {
+	w         io.Writer
+	format    RecordFormat
+	delimiter rune
+	csvWriter *csv.Writer
+	columns   []string
+}
+
+func newRecordWriter(w io.Writer, format RecordFormat, delimiter rune) (*recordWriter, error) {
+	retval := recordWriter{w: w, format: format, delimiter: delimiter}
+
+	switch format {
+	case CSVFormat:
+		retval.csvWriter = csv.NewWriter(w)
+		if delimiter != 0 {
+			retval.csvWriter.Comma = delimiter
+		}
+	case TSVFormat:
+		if delimiter == 0 {
+			retval.delimiter = '\t'
+		}
+	case JSONLinesFormat:
+		// nothing to set up
+	default:
+		return nil, ErrUnsupportedRecordFormat{format}
+	}
+
+	return &retval, nil
+}
+
+// setColumns tells the writer what the columns are called
+func (w *recordWriter) setColumns(columns []string) {
+	w.columns = columns
+}
+
+// writeHeader writes the column names, if the format has a header
+func (w *recordWriter) writeHeader() error {
+	if w.format == JSONLinesFormat {
+		return nil
+	}
+
+	return w.write(w.columns)
+}
+
+// write writes a single record
+//
+// We flush after every record, so that the output appears as soon as
+// it is ready.
+func (w *recordWriter) write(fields []string) error {
+	switch w.format {
+	case CSVFormat:
+		w.csvWriter.Write(fields)
+		return w.flush()
+
+	case TSVFormat:
+		escaped := make([]string, len(fields))
+		for i, field := range fields {
+			escaped[i] = escapeTSVField(field)
+		}
+		_, err := io.WriteString(w.w, strings.Join(escaped, string(w.delimiter))+"\n")
+		return err
+
+	default:
+		// JSON Lines, with the fields in column order
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, field := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(w.columns[i])
+			value, _ := json.Marshal(field)
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteString("}\n")
+		_, err := w.w.Write(buf.Bytes())
+		return err
+	}
+}
+
+// flush makes sure that everything we have written has reached the
+// underlying writer
+func (w *recordWriter) flush() error {
+	if w.csvWriter == nil {
+		return nil
+	}
+
+	w.csvWriter.Flush()
+	return w.csvWriter.Error()
+}
+
+// tsvEscapes turns special characters into TSV escape sequences
+var tsvEscapes = strings.NewReplacer(
+	"\\", `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// tsvUnescapes turns TSV escape sequences back into special characters
+var tsvUnescapes = strings.NewReplacer(
+	`\\`, "\\",
+	`\t`, "\t",
+	`\n`, "\n",
+	`\r`, "\r",
+)
+
+func escapeTSVField(field string) string {
+	return tsvEscapes.Replace(field)
+}
+
+func unescapeTSVField(field string) string {
+	return tsvUnescapes.Replace(field)
+}
@@ -0,0 +1,417 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVProcessesRecords(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		input          string
+		command        pipe.PipeCommand
+		expectedResult string
+	}{
+		{
+			name:           "copies CSV unchanged by default",
+			input:          "name,age\nalice,30\nbob,25\n",
+			command:        pipe.CSV(pipe.CSVOptions{}),
+			expectedResult: "name,age\nalice,30\nbob,25\n",
+		},
+		{
+			name:           "handles quoted fields",
+			input:          "name,quote\nalice,\"hello, \"\"world\"\"\"\nbob,\"two\nlines\"\n",
+			command:        pipe.CSV(pipe.CSVOptions{Header: pipe.HasHeader, Columns: []string{"quote"}}),
+			expectedResult: "quote\n\"hello, \"\"world\"\"\"\n\"two\nlines\"\n",
+		},
+		{
+			name:  "selects and reorders columns by name",
+			input: "name,age,city\nalice,30,leeds\nbob,25,york\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Columns: []string{"city", "name"},
+			}),
+			expectedResult: "city,name\nleeds,alice\nyork,bob\n",
+		},
+		{
+			name:  "selects columns by position",
+			input: "alice,30,leeds\nbob,25,york\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Header:  pipe.NoHeader,
+				Columns: []string{"3", "1"},
+			}),
+			expectedResult: "leeds,alice\nyork,bob\n",
+		},
+		{
+			name:  "renames columns",
+			input: "name,age\nalice,30\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Rename: map[string]string{"name": "who"},
+			}),
+			expectedResult: "who,age\nalice,30\n",
+		},
+		{
+			name:  "filters rows",
+			input: "name,age\nalice,30\nbob,25\ncarol,41\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Columns: []string{"name"},
+				Filter: func(row pipe.CSVRow) bool {
+					return row.Get("age") > "29"
+				},
+			}),
+			expectedResult: "name\nalice\ncarol\n",
+		},
+		{
+			name:  "omits the header",
+			input: "name,age\nalice,30\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OmitHeader: true,
+			}),
+			expectedResult: "alice,30\n",
+		},
+		{
+			name:  "uses a custom delimiter for input and output",
+			input: "name;age\nalice;30\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Delimiter: ';',
+				Columns:   []string{"age", "name"},
+			}),
+			expectedResult: "age;name\n30;alice\n",
+		},
+		{
+			name:  "converts CSV to TSV",
+			input: "name,note\nalice,\"tab\there\"\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.TSVFormat,
+			}),
+			expectedResult: "name\tnote\nalice\ttab\\there\n",
+		},
+		{
+			name:  "converts TSV to CSV",
+			input: "name\tnote\nalice\tsaid \"hi\"\\nthen left\n",
+			command: pipe.TSV(pipe.CSVOptions{
+				OutputFormat: pipe.CSVFormat,
+			}),
+			expectedResult: "name,note\nalice,\"said \"\"hi\"\"\nthen left\"\n",
+		},
+		{
+			name:  "converts CSV to JSON Lines, in column order",
+			input: "name,age\nalice,30\nbob\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+				Rename:       map[string]string{"name": "who"},
+			}),
+			expectedResult: "{\"who\":\"alice\",\"age\":\"30\"}\n{\"who\":\"bob\",\"age\":\"\"}\n",
+		},
+		{
+			name:  "names columns by position when there is no header",
+			input: "alice,30\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Header:       pipe.NoHeader,
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"alice\",\"2\":\"30\"}\n",
+		},
+		{
+			name:  "treats the first record as a header when told to",
+			input: "1,2\n3,4\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Header:       pipe.HasHeader,
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"3\",\"2\":\"4\"}\n",
+		},
+		{
+			name:  "detects a header above numeric columns",
+			input: "name,age\nalice,30\nbob,25\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"name\":\"alice\",\"age\":\"30\"}\n{\"name\":\"bob\",\"age\":\"25\"}\n",
+		},
+		{
+			name:  "detects a missing header",
+			input: "alice,30\nbob,25\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"alice\",\"2\":\"30\"}\n{\"1\":\"bob\",\"2\":\"25\"}\n",
+		},
+		{
+			name:  "does not treat text above same-length values as a header",
+			input: "code,country\nGB,United Kingdom\nFR,France\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				Columns: []string{"2"},
+			}),
+			expectedResult: "country\nUnited Kingdom\nFrance\n",
+		},
+		{
+			name:  "does not treat text above text as a header",
+			input: "apple,red\nbanana,yellow\ncherry,dark red\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"apple\",\"2\":\"red\"}\n{\"1\":\"banana\",\"2\":\"yellow\"}\n{\"1\":\"cherry\",\"2\":\"dark red\"}\n",
+		},
+		{
+			name:  "does not treat a single record as a header",
+			input: "apple,red\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"apple\",\"2\":\"red\"}\n",
+		},
+		{
+			name:  "does not treat numbers as a header",
+			input: "1,2\n3,4\n",
+			command: pipe.CSV(pipe.CSVOptions{
+				OutputFormat: pipe.JSONLinesFormat,
+			}),
+			expectedResult: "{\"1\":\"1\",\"2\":\"2\"}\n{\"1\":\"3\",\"2\":\"4\"}\n",
+		},
+		{
+			name:           "writes nothing for empty input",
+			input:          "",
+			command:        pipe.CSV(pipe.CSVOptions{}),
+			expectedResult: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString(testCase.input)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(testCase.command)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, unit.Error())
+			assert.Equal(t, testCase.expectedResult, unit.Stdout.String())
+		})
+	}
+}
+
+func TestCSVReturnsErrUnknownColumn(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("name,age\nalice,30\n")
+	expectedErr := pipe.ErrUnknownColumn{"price"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.CSV(pipe.CSVOptions{
+		Columns: []string{"name", "price"},
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestCSVReturnsParseErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("name,age\nalice,30\nbob,\"25\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.CSV(pipe.CSVOptions{Header: pipe.HasHeader}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	_, ok := err.(*csv.ParseError)
+	assert.True(t, ok)
+	assert.Equal(t, "name,age\nalice,30\n", unit.Stdout.String())
+}
+
+func TestCSVRejectsUnsupportedFormats(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		options     pipe.CSVOptions
+		expectedErr error
+	}{
+		{
+			name:        "JSON Lines input",
+			options:     pipe.CSVOptions{Format: pipe.JSONLinesFormat},
+			expectedErr: pipe.ErrUnsupportedRecordFormat{pipe.JSONLinesFormat},
+		},
+		{
+			name:        "unknown output",
+			options:     pipe.CSVOptions{OutputFormat: pipe.RecordFormat(99)},
+			expectedErr: pipe.ErrUnsupportedRecordFormat{pipe.RecordFormat(99)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString("name\nalice\n")
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.CSV(testCase.options))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedErr, unit.Error())
+		})
+	}
+}
+
+func TestCSVRowGetFindsColumnsByNameOrPosition(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.CSVRow{
+		RecordNo: 1,
+		Columns:  []string{"name", "age", "2"},
+		Fields:   []string{"alice", "30"},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	name := unit.Get("name")
+	first := unit.Get("1")
+	named2 := unit.Get("2")
+	missing := unit.Get("city")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "alice", name)
+	assert.Equal(t, "alice", first)
+	assert.Equal(t, "", named2)
+	assert.Equal(t, "", missing)
+}
+
+func TestCSVReadsStdinIncrementally(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	defer r.Close()
+
+	unit := pipe.NewPipe()
+	unit.Stdin = ioextra.NewTextFile(r)
+
+	seen := make(chan string)
+	done := make(chan struct{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	go func() {
+		unit.RunCommand(pipe.CSV(pipe.CSVOptions{
+			Header: pipe.HasHeader,
+			Filter: func(row pipe.CSVRow) bool {
+				seen <- row.Get("name")
+				return true
+			},
+		}))
+		close(done)
+	}()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// each record must arrive before we have finished writing
+	w.WriteString("name,age\nalice,30\n")
+	assert.Equal(t, "alice", <-seen)
+
+	w.WriteString("bob,25\n")
+	assert.Equal(t, "bob", <-seen)
+
+	w.Close()
+	<-done
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "name,age\nalice,30\nbob,25\n", unit.Stdout.String())
+}
@@ -112,11 +112,9 @@ can build your own solution for composing standardised command functions.
 
 	// helper function
 	func preparePipeForNextCommand(p *Pipe) {
-		// the output from our previous command becomes the input to the next
-		p.SetStdinFromString(p.Stdout.String())
-
-		// the next command starts with no output
-		p.SetNewStdout()
+		// the output from our previous command becomes the input to the
+		// next, and the next command starts with no output
+		p.MoveStdoutToStdin()
 
 		// we throw away any errors that have been written here
 		p.SetNewStderr()
@@ -143,6 +141,21 @@ The new pipe:
 
 * has a `p.Env` that works directly with your program's environment variables
 
+If you don't want your PipeCommands to see (or change) your program's
+environment variables, pass one of the environment options into `NewPipe()`:
+
+  // start with no variables at all
+  p := NewPipe(WithEmptyEnv)
+
+  // start with only the variables that you provide
+  p := NewPipe(WithEnvFromMap(map[string]string{"HOME": "/tmp"}))
+
+  // start with only the variables defined in a .env file
+  p := NewPipe(WithEnvFromFile("app.env"))
+
+  // start with a copy of some of your program's environment variables
+  p := NewPipe(WithInheritedEnv("PATH", "LC_*"))
+
 Using A Pipe
 
 `PipeCommand` is the signature of any function that will work with our Pipe.
@@ -173,6 +186,363 @@ Once you have defined your command function, pass it into your Pipe's RunCommand
   p.RunCommand(Sort)
 
 
+Working Line By Line
+
+`p.Stdin.Strings()` reads the whole of Stdin into memory. When your command
+only needs to look at one line at a time, use one of the line helpers
+instead. They read Stdin incrementally, and write to Stdout as they go:
+
+  // change each line
+  p.RunCommand(MapLines(func(line string) (string, error) {
+      return strings.ToUpper(line), nil
+  }))
+
+  // only keep some lines
+  p.RunCommand(FilterLines(func(line string) bool {
+      return line != ""
+  }))
+
+  // do whatever you want with each line
+  p.RunCommand(ForEachLine(func(p *Pipe, line string) error {
+      p.Stdout.WriteString(line)
+      p.Stdout.WriteRune('\n')
+      return nil
+  }))
+
+By default, lines can be up to 64 KB long. Use `WithMaxLineLength()` to
+change this. Longer lines stop the command with an `ErrLineTooLong` error.
+
+
+Working With JSON Lines
+
+If your pipe carries JSON Lines (one JSON record per line), you can work
+with records instead of text:
+
+  // turn each Order into an Invoice
+  p.RunCommand(MapJSON(func(o Order) (Invoice, error) {
+      return NewInvoice(o), nil
+  }))
+
+  // only keep the big orders
+  p.RunCommand(FilterJSON(func(o Order) bool {
+      return o.Total > 100
+  }))
+
+By default, these stop at the first record that cannot be decoded. Pass
+`OnBadRecord(SkipBadRecords)` to report bad records to Stderr and carry on.
+
+Use `DecodeJSONLines()` and `EncodeJSONLines()` to move records between
+Golang slices and the pipe.
+
+
+Working With CSV And TSV
+
+`CSV()` and `TSV()` read delimited records from Stdin, and write them to
+Stdout. Along the way, they can filter the records, and select, reorder
+and rename the columns:
+
+  p.RunCommand(CSV(CSVOptions{
+      Filter: func(row CSVRow) bool {
+          return row.Get("country") == "GB"
+      },
+      Columns:      []string{"email", "name"},
+      Rename:       map[string]string{"email": "address"},
+      OutputFormat: JSONLinesFormat,
+  }))
+
+Records are processed one at a time, so that you can work with very large
+inputs. By default, we look at the first few records to work out if there
+is a header; this needs at least one column of numbers. Set `Header` to
+`HasHeader` or `NoHeader` if you know.
+
+
+Working With Binary Data
+
+The pipe's Stdin, Stdout and Stderr are all io.Reader / io.Writer, so
+they can carry any data at all. But helpers like `Strings()` and
+`ReadLines()` treat the data as text. Use these instead for binary data,
+such as compressed files or images:
+
+  // put bytes into Stdin, exactly as they are
+  p.SetStdinFromBytes(data)
+
+  // read everything that's left in Stdin
+  data, err := p.StdinBytes()
+
+  // get everything that has been written to Stdout
+  data, err := p.StdoutBytes()
+
+The `commands` package has `Gzip()`, `Gunzip()`, `Base64()` and
+`Sha256sum()` (amongst others), which all work on raw bytes.
+
+
+Working With Character Encodings
+
+PipeCommands expect text to be UTF-8. If your input uses another encoding,
+use `DecodeStdin()` to convert it as it is read:
+
+  p := pipe.NewPipe(
+      pipe.AttachOsStdin,
+      pipe.DecodeStdin(pipe.UTF16),
+  )
+
+If the input starts with a byte order mark (BOM), the BOM decides the
+encoding, and it is removed. Use `EncodeStdout()` to convert everything
+written to Stdout from UTF-8 into another encoding.
+
+By default, anything that cannot be converted is replaced. Pass
+`OnInvalidSequence(SkipInvalidSequences)` to leave it out instead, or
+`OnInvalidSequence(RejectInvalidSequences)` to stop with an error.
+
+
+Working With Large Outputs
+
+By default, the pipe's Stdout and Stderr are in-memory buffers, with no
+upper limit. If your PipeCommands can write more than you want to keep in
+memory, use `WithSpillBuffers()`:
+
+  // keep up to 64 MB in memory, then spill into a temporary file
+  p := pipe.NewPipe(pipe.WithSpillBuffers(64*1024*1024, ""))
+  defer p.Close()
+
+Call `p.Close()` when you have finished with the pipe, to remove any
+temporary files. `p.ResetBuffers()` removes them too.
+
+If you want to use your own buffers, pass a function that creates them
+into `WithBufferFactory()`.
+
+If you run a lot of PipeCommands in a tight loop, use `WithPooledBuffers()`
+to reuse Stdout and Stderr buffers instead of allocating new ones:
+
+  p := pipe.NewPipe(pipe.WithPooledBuffers())
+
+Buffers go back into the pool when `p.ResetBuffers()`, `p.SetNewStdout()`,
+`p.SetNewStderr()` or `p.MoveStdoutToStdin()` replace them. Don't keep
+using a buffer after it has been replaced.
+
+
+Limiting Output
+
+A PipeCommand that goes wrong can write far more than you expect. Use
+`WithMaxOutput()` to put a limit on how many bytes each PipeCommand can
+write to the pipe's Stdout and Stderr:
+
+  // up to 1 MB of output, and 64 KB of errors
+  p := pipe.NewPipe(pipe.WithMaxOutput(1024*1024, 64*1024, pipe.FailOnOutputLimit))
+
+The last parameter says what happens when a PipeCommand goes over the
+limit:
+
+  Policy                     | What Happens
+  ---------------------------|-------------
+  `TruncateOutput`           | extra output is thrown away
+  `TruncateOutputWithMarker` | extra output is thrown away, and a marker is written
+  `FailOnOutputLimit`        | the PipeCommand fails with `ErrOutputLimitExceeded`
+
+With `FailOnOutputLimit`, the PipeCommand's status code is
+`StatusOutputLimitExceeded`.
+
+
+Capturing Output In Order
+
+The pipe keeps Stdout and Stderr in separate buffers, so you can't tell
+which order things were written in. Use a `Capture` when you need to know:
+
+  capture := pipe.NewCapture()
+  p := pipe.NewPipe(pipe.WithCapture(capture))
+
+  p.RunCommand(myCommand)
+
+  // everything, in the order it was written
+  fmt.Print(capture.Combined())
+
+  // just one of the streams
+  fmt.Print(capture.Stdout())
+  fmt.Print(capture.Stderr())
+
+`capture.Writes()` returns each write, with its stream, sequence number,
+timestamp and the name of the PipeCommand that made it.
+`capture.WriteLog()` writes them out as a log, one tagged line at a time.
+
+
+Tracing
+
+Use `WithTracer()` to open a tracing span around every PipeCommand that
+the pipe runs:
+
+  p := pipe.NewPipe(
+      pipe.WithContext(r.Context()),
+      pipe.WithTracer(myTracer),
+  )
+
+`pipe.Tracer` and `pipe.Span` are small interfaces, modelled on
+OpenTelemetry, so that you can plug in the tracing library of your choice.
+Each span is named after the PipeCommand, and records its status code,
+error, how many bytes it read and wrote, and the depths of the pipe's
+stacks.
+
+Spans for PipeCommands that run inside another PipeCommand (on the same
+pipe, or on a child pipe) are children of the outer PipeCommand's span.
+
+In your tests, use `pipe.NewMemoryTracer()` to see what spans were
+created.
+
+
+Collecting Metrics
+
+Use `WithMetrics()` to find out how often each PipeCommand runs, what
+status codes it returns, how long it takes, and how many bytes it reads
+and writes:
+
+  metrics := pipe.NewMemoryMetrics()
+  p := pipe.NewPipe(pipe.WithMetrics(metrics))
+
+  // serve them up for Prometheus
+  http.Handle("/metrics", metrics.Handler())
+
+If you already use a metrics library, implement the `pipe.Metrics`
+interface instead, and pass that into `WithMetrics()`.
+
+
+Logging
+
+Use `WithLogger()` to find out what the pipe is doing:
+
+  p := pipe.NewPipe(pipe.WithLogger(slog.Default()))
+
+The pipe logs each PipeCommand that it runs, the names of any environment
+variables that a PipeCommand changes, and every push and pop on its
+stacks. Each event carries the PipeCommand's name, and (once it has
+finished) its status code and error.
+
+`pipe.Logger` uses key/value pairs, in the same style as `log/slog`. In
+your tests, use `pipe.NewMemoryLogger()` to see what was logged.
+
+
+Retrying PipeCommands
+
+Use `Retry()` to run a PipeCommand again when it fails:
+
+  p.RunCommand(pipe.Retry(fetchCommand, pipe.RetryPolicy{
+      Attempts:     5,
+      InitialDelay: 100 * time.Millisecond,
+      MaxDelay:     5 * time.Second,
+      Jitter:       0.2,
+      ReplayStdin:  true,
+  }))
+
+By default, the delay between attempts doubles each time. Set
+`Retryable` to choose which failures are worth retrying, and `OnAttempt`
+to find out how each attempt went. Only the output from the last attempt
+ends up in the pipe's Stdout and Stderr.
+
+
+Timing Out PipeCommands
+
+Use `Timeout()` to give up on a PipeCommand that takes too long:
+
+  p.RunCommand(pipe.Timeout(5*time.Second, slowCommand))
+
+If the PipeCommand hasn't finished in time, the pipe's status code is
+`StatusTimeout` (124, the same as `timeout(1)`), and its error is an
+`ErrTimeout`. Anything that the PipeCommand wrote is thrown away.
+
+The PipeCommand's context is cancelled when the time is up. Long-running
+PipeCommands should watch `p.Context().Done()`, and stop when it closes.
+
+
+Control Flow
+
+You can use PipeCommands as conditions, just like commands in a UNIX
+shell script. A PipeCommand succeeds if it returns `StatusOkay` and no
+error.
+
+  Shell                           | Pipe
+  --------------------------------|-----
+  `if cond; then a; else b; fi`   | `pipe.If(cond, a, b)`
+  `while cond; do body; done`     | `pipe.While(cond, body)`
+  `until cond; do body; done`     | `pipe.Until(cond, body)`
+  `for x in a b c; do ...; done`  | `pipe.ForEach([]string{"a", "b", "c"}, fn)`
+  `while read line; do ...; done` | `pipe.ForEachLine(fn)`
+  `break`, `continue`             | `pipe.Break`, `pipe.Continue`
+  `set -e`                        | `pipe.WithErrexit(true)`
+
+Loops keep going when their body fails, unless you turn on errexit.
+
+Use `Case()` for a shell's `case` statement:
+
+  p.RunCommand(pipe.Case(
+      pipe.EnvSubject("FILE"),
+      pipe.GlobArm("*.go", buildCommand),
+      pipe.RegexArm(`^v\d+`, releaseCommand),
+      pipe.DefaultArm(skipCommand),
+  ))
+
+`pipe.StdinSubject` matches on the contents of Stdin instead.
+
+
+Cleaning Up With Traps
+
+Use `p.Trap()` to run clean-up PipeCommands when something happens, like
+the `trap` command in a UNIX shell:
+
+  p := pipe.NewPipe()
+  defer p.Exit()
+
+  p.Trap(pipe.TrapExit, removeTempFiles)
+  p.Trap(pipe.TrapErr, reportFailure)
+
+  Event        | When The Traps Run
+  -------------|-------------------
+  `TrapExit`   | when you call `p.Exit()`, even if a PipeCommand panicked
+  `TrapErr`    | each time a PipeCommand run by `p.RunCommand()` fails
+  `TrapCancel` | the first time `p.RunCommand()` finds the context is done
+
+Traps can call `p.StatusCode()` and `p.Error()` to find out what happened.
+
+
+Handling Signals
+
+Use `WithSignals()` to stop a pipe when your program gets SIGINT or SIGTERM:
+
+  p := pipe.NewPipe(pipe.WithSignals())
+  defer p.Exit()
+
+When a signal arrives, the pipe's context is cancelled, the signal is passed
+on to any process registered with `p.TrackProcess()`, and `p.RunCommand()`
+reports a status code of 128 plus the signal's number (130 for SIGINT), just
+like a UNIX shell. Your `TrapCancel` traps run too.
+
+PipeCommands that start external processes should register them:
+
+  cmd := exec.CommandContext(p.Context(), "make")
+  cmd.Start()
+  untrack := p.TrackProcess(cmd.Process)
+  defer untrack()
+
+
+Heredocs And Here-Strings
+
+Use `HereDoc()` and `HereString()` to give a PipeCommand some input, like
+a UNIX shell's `<<EOF` and `<<<`:
+
+  p.RunCommand(pipe.HereDoc(`
+  	Hello $NAME,
+  	your order has shipped.
+  	`, pipe.HereDocOptions{StripTabs: true}))
+
+  Shell       | go_pipe
+  ------------|--------------------------------------------------
+  `<<EOF`     | `pipe.HereDoc(text, pipe.HereDocOptions{})`
+  `<<-EOF`    | `pipe.HereDoc(text, pipe.HereDocOptions{StripTabs: true})`
+  `<<'EOF'`   | `pipe.HereDoc(text, pipe.HereDocOptions{Quoted: true})`
+  `<<<"$VAR"` | `pipe.HereString("$VAR")`
+
+Both of them expand `$VAR` and `${VAR}` using the pipe's Env, and push the
+result onto the pipe's Stdin stack. Call `p.PopStdin()` to go back to the
+pipe's previous Stdin.
+
+
 Using The Stdin, Stdout And Stderr Stacks
 
 Sometimes, you may want to temporarily replace the pipe's Stdin, Stdout or
@@ -0,0 +1,642 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a character encoding that we can convert to and from
+// UTF-8.
+type Encoding int
+
+const (
+	// UTF8 is UTF-8, the encoding that Golang strings use
+	UTF8 Encoding = iota
+
+	// UTF16 is UTF-16, in whichever byte order its BOM says. Without a
+	// BOM, it is big-endian. When encoding, we always write a BOM.
+	UTF16
+
+	// UTF16LE is little-endian UTF-16
+	UTF16LE
+
+	// UTF16BE is big-endian UTF-16
+	UTF16BE
+
+	// Latin1 is ISO-8859-1
+	Latin1
+
+	// Windows1252 is the Windows code page 1252, a superset of
+	// ISO-8859-1
+	Windows1252
+)
+
+// encodingNames maps each Encoding to its canonical name
+var encodingNames = map[Encoding]string{
+	UTF8:        "UTF-8",
+	UTF16:       "UTF-16",
+	UTF16LE:     "UTF-16LE",
+	UTF16BE:     "UTF-16BE",
+	Latin1:      "ISO-8859-1",
+	Windows1252: "windows-1252",
+}
+
+// encodingAliases maps normalised names onto each Encoding
+var encodingAliases = map[string]Encoding{
+	"utf8":        UTF8,
+	"utf16":       UTF16,
+	"utf16le":     UTF16LE,
+	"utf16be":     UTF16BE,
+	"latin1":      Latin1,
+	"l1":          Latin1,
+	"iso88591":    Latin1,
+	"windows1252": Windows1252,
+	"cp1252":      Windows1252,
+}
+
+// String returns the canonical name of the encoding, eg "UTF-16LE".
+func (e Encoding) String() string {
+	name, ok := encodingNames[e]
+	if !ok {
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+
+	return name
+}
+
+// LookupEncoding returns the Encoding with the given name.
+//
+// Names are not case-sensitive, and any '-' or '_' are ignored. As well
+// as the canonical names, we accept "latin1", "l1" and "cp1252".
+func LookupEncoding(name string) (Encoding, error) {
+	key := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	retval, ok := encodingAliases[key]
+	if !ok {
+		return UTF8, ErrUnknownEncoding{name}
+	}
+
+	return retval, nil
+}
+
+// InvalidSequencePolicy tells us what to do with bytes that are not
+// valid in the input's encoding, or characters that cannot be written in
+// the output's encoding.
+type InvalidSequencePolicy int
+
+const (
+	// ReplaceInvalidSequences replaces each one with U+FFFD when
+	// decoding, and with U+FFFD or '?' when encoding. This is the
+	// default.
+	ReplaceInvalidSequences InvalidSequencePolicy = iota
+
+	// SkipInvalidSequences leaves them out
+	SkipInvalidSequences
+
+	// RejectInvalidSequences stops at the first one, with an error
+	RejectInvalidSequences
+)
+
+// EncodingOption is the signature of any function that changes how we
+// convert between encodings.
+type EncodingOption = func(*encodingConfig)
+
+// encodingConfig holds the settings for converting between encodings
+type encodingConfig struct {
+	policy  InvalidSequencePolicy
+	withBOM bool
+}
+
+// OnInvalidSequence sets what happens when we find bytes that are not
+// valid in the input's encoding, or characters that cannot be written
+// in the output's encoding.
+func OnInvalidSequence(policy InvalidSequencePolicy) EncodingOption {
+	return func(c *encodingConfig) {
+		c.policy = policy
+	}
+}
+
+// WithBOM makes an encoder start its output with a byte order mark. It
+// only applies to UTF-8, UTF-16LE and UTF-16BE: UTF-16 always has a BOM,
+// and the single-byte encodings never do.
+func WithBOM() EncodingOption {
+	return func(c *encodingConfig) {
+		c.withBOM = true
+	}
+}
+
+// DecodeStdin returns a PipeCommand that replaces the pipe's Stdin with
+// a reader that converts it from the given encoding into UTF-8.
+//
+// If Stdin starts with a byte order mark, the BOM decides the encoding,
+// and it is removed. This means that a UTF-8 file with a BOM is always
+// read correctly, no matter which encoding you expected.
+//
+// You can use DecodeStdin as a PipeOption, to decode your program's
+// stdin. The conversion happens as Stdin is read, so it works with very
+// large inputs.
+func DecodeStdin(enc Encoding, options ...EncodingOption) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil {
+			return StatusOkay, nil
+		}
+
+		decoder, err := NewDecoder(p.Stdin, enc, options...)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+		p.Stdin = &textReader{decoder}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// EncodeStdout returns a PipeCommand that replaces the pipe's Stdout with
+// a writer that converts everything written to it from UTF-8 into the
+// given encoding.
+//
+// You can use EncodeStdout as a PipeOption, to encode your program's
+// stdout. It lasts until the pipe's Stdout is replaced.
+//
+// Anything that the encoder is holding back, such as the start of an
+// incomplete UTF-8 sequence, is written out when each PipeCommand run by
+// RunCommand finishes, when the pipe's Stdout is replaced or moved to
+// Stdin, and when you call Pipe.Close.
+func EncodeStdout(enc Encoding, options ...EncodingOption) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdout == nil {
+			return StatusOkay, nil
+		}
+
+		encoder, err := NewEncoder(p.Stdout, enc, options...)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+		p.Stdout = &interceptedTextReaderWriter{
+			TextReaderWriter: p.Stdout,
+			write:            encoder.Write,
+			flush:            encoder.Close,
+		}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// NewDecoder returns an io.Reader that reads from r, and converts what
+// it reads from the given encoding into UTF-8.
+//
+// If r starts with a byte order mark, the BOM decides the encoding, and
+// it is removed.
+func NewDecoder(r io.Reader, enc Encoding, options ...EncodingOption) (io.Reader, error) {
+	if _, ok := encodingNames[enc]; !ok {
+		return nil, ErrUnknownEncoding{enc.String()}
+	}
+
+	retval := decoder{r: bufio.NewReader(r), enc: enc}
+	for _, option := range options {
+		option(&retval.config)
+	}
+
+	return &retval, nil
+}
+
+// NewEncoder returns an io.WriteCloser that converts UTF-8 into the
+// given encoding, and writes the result to w.
+//
+// Call Close when you have finished writing, so that any incomplete
+// UTF-8 sequence at the end of the input is dealt with. Close does not
+// close w.
+func NewEncoder(w io.Writer, enc Encoding, options ...EncodingOption) (io.WriteCloser, error) {
+	if _, ok := encodingNames[enc]; !ok {
+		return nil, ErrUnknownEncoding{enc.String()}
+	}
+
+	retval := encoder{w: w, enc: enc}
+	for _, option := range options {
+		option(&retval.config)
+	}
+
+	return &retval, nil
+}
+
+// ================================================================
+//
+// Decoding
+//
+// ----------------------------------------------------------------
+
+// decoder converts from an encoding into UTF-8
+type decoder struct {
+	r      *bufio.Reader
+	enc    Encoding
+	config encodingConfig
+
+	// have we looked for a BOM yet?
+	sniffed bool
+
+	// how many bytes we have read from r
+	offset int64
+
+	// decoded UTF-8 that has not been read yet
+	pending []byte
+
+	// the error to return once pending is empty
+	err error
+}
+
+func (d *decoder) Read(buf []byte) (int, error) {
+	if !d.sniffed {
+		d.sniffBOM()
+		d.sniffed = true
+	}
+
+	// decode at least one character, and then whatever we can without
+	// waiting for more input
+	for len(d.pending) == 0 && d.err == nil {
+		d.decodeNext()
+	}
+	for len(d.pending) < len(buf) && d.err == nil && d.r.Buffered() > 0 {
+		d.decodeNext()
+	}
+
+	n := copy(buf, d.pending)
+	d.pending = d.pending[n:]
+	if n > 0 {
+		return n, nil
+	}
+
+	return 0, d.err
+}
+
+// sniffBOM looks for a byte order mark. If there is one, it decides
+// the encoding.
+func (d *decoder) sniffBOM() {
+	boms := []struct {
+		bom []byte
+		enc Encoding
+	}{
+		{[]byte{0xEF, 0xBB, 0xBF}, UTF8},
+		{[]byte{0xFF, 0xFE}, UTF16LE},
+		{[]byte{0xFE, 0xFF}, UTF16BE},
+	}
+
+	for _, candidate := range boms {
+		peeked, _ := d.r.Peek(len(candidate.bom))
+		if bytes.Equal(peeked, candidate.bom) {
+			d.r.Discard(len(candidate.bom))
+			d.offset += int64(len(candidate.bom))
+			d.enc = candidate.enc
+			return
+		}
+	}
+
+	// without a BOM, UTF-16 is big-endian
+	if d.enc == UTF16 {
+		d.enc = UTF16BE
+	}
+}
+
+// decodeNext decodes the next character into pending
+func (d *decoder) decodeNext() {
+	switch d.enc {
+	case UTF8:
+		r, size, err := d.r.ReadRune()
+		if err != nil {
+			d.err = err
+			return
+		}
+		if r == utf8.RuneError && size == 1 {
+			d.invalid(size)
+			return
+		}
+		d.appendRune(r, size)
+
+	case UTF16LE, UTF16BE:
+		unit, err := d.readUTF16()
+		if err == io.ErrUnexpectedEOF {
+			d.invalid(1)
+			if d.err == nil {
+				d.err = io.EOF
+			}
+			return
+		}
+		if err != nil {
+			d.err = err
+			return
+		}
+
+		// is this a character on its own?
+		if !utf16.IsSurrogate(unit) {
+			d.appendRune(unit, 2)
+			return
+		}
+
+		// we need a high surrogate followed by a low surrogate
+		peeked, err := d.r.Peek(2)
+		if unit >= 0xDC00 || err != nil {
+			d.invalid(2)
+			return
+		}
+		low := d.unitFrom(peeked)
+		r := utf16.DecodeRune(unit, low)
+		if r == utf8.RuneError {
+			d.invalid(2)
+			return
+		}
+		d.r.Discard(2)
+		d.appendRune(r, 4)
+
+	default:
+		// a single-byte encoding
+		c, err := d.r.ReadByte()
+		if err != nil {
+			d.err = err
+			return
+		}
+		r := rune(c)
+		if d.enc == Windows1252 && c >= 0x80 && c <= 0x9F {
+			r = windows1252Runes[c-0x80]
+		}
+		d.appendRune(r, 1)
+	}
+}
+
+// readUTF16 reads the next UTF-16 code unit
+func (d *decoder) readUTF16() (rune, error) {
+	var buf [2]byte
+	_, err := io.ReadFull(d.r, buf[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return d.unitFrom(buf[:]), nil
+}
+
+// unitFrom turns two bytes into a UTF-16 code unit, in our byte order
+func (d *decoder) unitFrom(buf []byte) rune {
+	if d.enc == UTF16LE {
+		return rune(buf[0]) | rune(buf[1])<<8
+	}
+
+	return rune(buf[0])<<8 | rune(buf[1])
+}
+
+// appendRune adds r to pending, and moves our offset along
+func (d *decoder) appendRune(r rune, size int) {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	d.pending = append(d.pending, buf[:n]...)
+	d.offset += int64(size)
+}
+
+// invalid deals with a sequence of bytes that is not valid in our
+// encoding
+func (d *decoder) invalid(size int) {
+	switch d.config.policy {
+	case ReplaceInvalidSequences:
+		d.appendRune(utf8.RuneError, size)
+	case SkipInvalidSequences:
+		d.offset += int64(size)
+	default:
+		d.err = ErrInvalidSequence{d.enc.String(), d.offset}
+	}
+}
+
+// ================================================================
+//
+// Encoding
+//
+// ----------------------------------------------------------------
+
+// encoder converts UTF-8 into an encoding
+type encoder struct {
+	w      io.Writer
+	enc    Encoding
+	config encodingConfig
+
+	// have we written anything yet?
+	started bool
+
+	// how many bytes of UTF-8 we have been given
+	offset int64
+
+	// an incomplete UTF-8 sequence, left over from the last write
+	pending []byte
+}
+
+func (e *encoder) Write(buf []byte) (int, error) {
+	input := append(e.pending, buf...)
+	e.pending = nil
+
+	var out []byte
+	if !e.started {
+		out = e.appendBOM(out)
+		e.started = true
+	}
+
+	var err error
+	i := 0
+	for i < len(input) && err == nil {
+		if !utf8.FullRune(input[i:]) {
+			break
+		}
+		out, i, err = e.encodeNext(out, input, i)
+	}
+	e.pending = append([]byte(nil), input[i:]...)
+
+	_, writeErr := e.w.Write(out)
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(buf), nil
+}
+
+// Close deals with any incomplete UTF-8 sequence that was left over
+// from the last write
+func (e *encoder) Close() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+
+	var out []byte
+	var err error
+	for i := 0; i < len(e.pending) && err == nil; {
+		out, i, err = e.encodeNext(out, e.pending, i)
+	}
+	e.pending = nil
+
+	_, writeErr := e.w.Write(out)
+	if err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+// encodeNext encodes the UTF-8 character at input[i:], and returns
+// where the next character starts
+func (e *encoder) encodeNext(out []byte, input []byte, i int) ([]byte, int, error) {
+	r, size := utf8.DecodeRune(input[i:])
+	if r == utf8.RuneError && size == 1 {
+		err := error(ErrInvalidSequence{UTF8.String(), e.offset})
+		out, err = e.invalid(out, err)
+		e.offset++
+		return out, i + 1, err
+	}
+
+	var err error
+	out, err = e.appendRune(out, r)
+	e.offset += int64(size)
+	return out, i + size, err
+}
+
+// appendBOM adds a byte order mark to out, if we need one
+func (e *encoder) appendBOM(out []byte) []byte {
+	switch {
+	case e.enc == UTF16:
+		// we always write UTF-16 as big-endian, with a BOM
+		return append(out, 0xFE, 0xFF)
+	case !e.config.withBOM:
+		return out
+	case e.enc == UTF8:
+		return append(out, 0xEF, 0xBB, 0xBF)
+	case e.enc == UTF16LE:
+		return append(out, 0xFF, 0xFE)
+	case e.enc == UTF16BE:
+		return append(out, 0xFE, 0xFF)
+	default:
+		return out
+	}
+}
+
+// appendRune adds r to out, in our encoding
+func (e *encoder) appendRune(out []byte, r rune) ([]byte, error) {
+	switch e.enc {
+	case UTF8:
+		var buf [utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], r)
+		return append(out, buf[:n]...), nil
+
+	case UTF16, UTF16BE, UTF16LE:
+		units := []rune{r}
+		if r >= 0x10000 {
+			high, low := utf16.EncodeRune(r)
+			units = []rune{high, low}
+		}
+		for _, unit := range units {
+			if e.enc == UTF16LE {
+				out = append(out, byte(unit), byte(unit>>8))
+			} else {
+				out = append(out, byte(unit>>8), byte(unit))
+			}
+		}
+		return out, nil
+
+	default:
+		// a single-byte encoding
+		c, ok := e.singleByteFor(r)
+		if !ok {
+			return e.invalid(out, ErrUnencodableCharacter{e.enc.String(), r})
+		}
+		return append(out, c), nil
+	}
+}
+
+// singleByteFor returns the byte that represents r in our single-byte
+// encoding
+func (e *encoder) singleByteFor(r rune) (byte, bool) {
+	if e.enc == Windows1252 {
+		if r >= 0x80 && r <= 0x9F {
+			// only the bytes that Windows-1252 leaves undefined
+			// map back onto the C1 control characters
+			return byte(r), windows1252Runes[r-0x80] == r
+		}
+		for i, candidate := range windows1252Runes {
+			if candidate == r {
+				return byte(0x80 + i), true
+			}
+		}
+	}
+
+	if r > 0xFF {
+		return 0, false
+	}
+	return byte(r), true
+}
+
+// invalid deals with input that cannot be encoded
+func (e *encoder) invalid(out []byte, err error) ([]byte, error) {
+	switch e.config.policy {
+	case ReplaceInvalidSequences:
+		switch e.enc {
+		case Latin1, Windows1252:
+			return append(out, '?'), nil
+		default:
+			return e.appendRune(out, utf8.RuneError)
+		}
+	case SkipInvalidSequences:
+		return out, nil
+	default:
+		return out, err
+	}
+}
+
+// windows1252Runes maps the bytes 0x80-0x9F onto the characters that
+// Windows-1252 uses them for. Undefined bytes map onto the matching C1
+// control character, as they do in web browsers.
+var windows1252Runes = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
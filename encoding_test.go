@@ -0,0 +1,618 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupEncodingAcceptsCommonNames(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]pipe.Encoding{
+		"UTF-8":        pipe.UTF8,
+		"utf8":         pipe.UTF8,
+		"UTF-16":       pipe.UTF16,
+		"utf-16le":     pipe.UTF16LE,
+		"UTF_16BE":     pipe.UTF16BE,
+		"ISO-8859-1":   pipe.Latin1,
+		"latin1":       pipe.Latin1,
+		"windows-1252": pipe.Windows1252,
+		"CP1252":       pipe.Windows1252,
+	}
+
+	for name, expectedResult := range testCases {
+		name := name
+		expectedResult := expectedResult
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			actualResult, err := pipe.LookupEncoding(name)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err)
+			assert.Equal(t, expectedResult, actualResult)
+		})
+	}
+}
+
+func TestLookupEncodingReturnsErrUnknownEncoding(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := pipe.LookupEncoding("EBCDIC")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.ErrUnknownEncoding{"EBCDIC"}, err)
+}
+
+func TestNewDecoderConvertsToUTF8(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		encoding       pipe.Encoding
+		options        []pipe.EncodingOption
+		input          string
+		expectedResult string
+		expectedErr    error
+	}{
+		{
+			name:           "UTF-8 is copied",
+			encoding:       pipe.UTF8,
+			input:          "café\r\n",
+			expectedResult: "café\r\n",
+		},
+		{
+			name:           "UTF-8 BOM is removed",
+			encoding:       pipe.UTF8,
+			input:          "\xef\xbb\xbfcafé",
+			expectedResult: "café",
+		},
+		{
+			name:           "UTF-16LE",
+			encoding:       pipe.UTF16LE,
+			input:          "c\x00a\x00f\x00\xe9\x00",
+			expectedResult: "café",
+		},
+		{
+			name:           "UTF-16BE with a surrogate pair",
+			encoding:       pipe.UTF16BE,
+			input:          "\x00a\xd8\x3d\xde\x00",
+			expectedResult: "a😀",
+		},
+		{
+			name:           "UTF-16 without a BOM is big-endian",
+			encoding:       pipe.UTF16,
+			input:          "\x00h\x00i",
+			expectedResult: "hi",
+		},
+		{
+			name:           "UTF-16 BOM decides the byte order",
+			encoding:       pipe.UTF16,
+			input:          "\xff\xfeh\x00i\x00",
+			expectedResult: "hi",
+		},
+		{
+			name:           "a BOM wins over the expected encoding",
+			encoding:       pipe.Latin1,
+			input:          "\xff\xfeh\x00i\x00",
+			expectedResult: "hi",
+		},
+		{
+			name:           "Latin-1",
+			encoding:       pipe.Latin1,
+			input:          "caf\xe9 \x80",
+			expectedResult: "café \u0080",
+		},
+		{
+			name:           "Windows-1252",
+			encoding:       pipe.Windows1252,
+			input:          "caf\xe9 \x80 \x93quoted\x94 \x81",
+			expectedResult: "café € “quoted” \u0081",
+		},
+		{
+			name:           "invalid UTF-8 is replaced by default",
+			encoding:       pipe.UTF8,
+			input:          "a\xffb",
+			expectedResult: "a�b",
+		},
+		{
+			name:           "a lone UTF-16 surrogate is replaced",
+			encoding:       pipe.UTF16LE,
+			input:          "\x3d\xd8a\x00",
+			expectedResult: "�a",
+		},
+		{
+			name:           "an odd trailing UTF-16 byte is replaced",
+			encoding:       pipe.UTF16LE,
+			input:          "a\x00b",
+			expectedResult: "a�",
+		},
+		{
+			name:           "invalid sequences can be skipped",
+			encoding:       pipe.UTF8,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.SkipInvalidSequences)},
+			input:          "a\xff\xfeb",
+			expectedResult: "ab",
+		},
+		{
+			name:           "invalid sequences can be rejected",
+			encoding:       pipe.UTF16BE,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.RejectInvalidSequences)},
+			input:          "\x00a\xdc\x00\x00b",
+			expectedResult: "a",
+			expectedErr:    pipe.ErrInvalidSequence{"UTF-16BE", 2},
+		},
+		{
+			name:           "an odd trailing UTF-16 byte can be rejected",
+			encoding:       pipe.UTF16BE,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.RejectInvalidSequences)},
+			input:          "\x00a\x00",
+			expectedResult: "a",
+			expectedErr:    pipe.ErrInvalidSequence{"UTF-16BE", 2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit, err := pipe.NewDecoder(strings.NewReader(testCase.input), testCase.encoding, testCase.options...)
+			assert.Nil(t, err)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			actualResult, err := ioutil.ReadAll(unit)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedErr, err)
+			assert.Equal(t, testCase.expectedResult, string(actualResult))
+		})
+	}
+}
+
+func TestNewEncoderConvertsFromUTF8(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		encoding       pipe.Encoding
+		options        []pipe.EncodingOption
+		input          string
+		expectedResult string
+		expectedErr    error
+	}{
+		{
+			name:           "UTF-8 is copied",
+			encoding:       pipe.UTF8,
+			input:          "café",
+			expectedResult: "café",
+		},
+		{
+			name:           "UTF-8 with a BOM",
+			encoding:       pipe.UTF8,
+			options:        []pipe.EncodingOption{pipe.WithBOM()},
+			input:          "café",
+			expectedResult: "\xef\xbb\xbfcafé",
+		},
+		{
+			name:           "UTF-16LE",
+			encoding:       pipe.UTF16LE,
+			input:          "a😀",
+			expectedResult: "a\x00\x3d\xd8\x00\xde",
+		},
+		{
+			name:           "UTF-16BE with a BOM",
+			encoding:       pipe.UTF16BE,
+			options:        []pipe.EncodingOption{pipe.WithBOM()},
+			input:          "hi",
+			expectedResult: "\xfe\xff\x00h\x00i",
+		},
+		{
+			name:           "UTF-16 always has a BOM",
+			encoding:       pipe.UTF16,
+			input:          "hi",
+			expectedResult: "\xfe\xff\x00h\x00i",
+		},
+		{
+			name:           "Latin-1",
+			encoding:       pipe.Latin1,
+			input:          "café",
+			expectedResult: "caf\xe9",
+		},
+		{
+			name:           "Windows-1252",
+			encoding:       pipe.Windows1252,
+			input:          "€ “quoted” \u0081",
+			expectedResult: "\x80 \x93quoted\x94 \x81",
+		},
+		{
+			name:           "unencodable characters are replaced by default",
+			encoding:       pipe.Latin1,
+			input:          "5€",
+			expectedResult: "5?",
+		},
+		{
+			name:           "invalid UTF-8 is replaced by default",
+			encoding:       pipe.UTF16BE,
+			input:          "a\xff",
+			expectedResult: "\x00a\xff\xfd",
+		},
+		{
+			name:           "unencodable characters can be skipped",
+			encoding:       pipe.Windows1252,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.SkipInvalidSequences)},
+			input:          "a→b",
+			expectedResult: "ab",
+		},
+		{
+			name:           "unencodable characters can be rejected",
+			encoding:       pipe.Latin1,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.RejectInvalidSequences)},
+			input:          "a€b",
+			expectedResult: "a",
+			expectedErr:    pipe.ErrUnencodableCharacter{"ISO-8859-1", '€'},
+		},
+		{
+			name:           "an incomplete UTF-8 sequence at the end is dealt with on Close",
+			encoding:       pipe.UTF8,
+			options:        []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.RejectInvalidSequences)},
+			input:          "a\xe2\x82",
+			expectedResult: "a",
+			expectedErr:    pipe.ErrInvalidSequence{"UTF-8", 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			var buf bytes.Buffer
+			unit, err := pipe.NewEncoder(&buf, testCase.encoding, testCase.options...)
+			assert.Nil(t, err)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			_, err = unit.Write([]byte(testCase.input))
+			if err == nil {
+				err = unit.Close()
+			}
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedErr, err)
+			assert.Equal(t, testCase.expectedResult, buf.String())
+		})
+	}
+}
+
+func TestNewEncoderCopesWithCharactersSplitAcrossWrites(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var buf bytes.Buffer
+	unit, err := pipe.NewEncoder(&buf, pipe.UTF16LE)
+	assert.Nil(t, err)
+	input := []byte("€!")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for _, b := range input {
+		unit.Write([]byte{b})
+	}
+	err = unit.Close()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "\xac\x20!\x00", buf.String())
+}
+
+func TestNewDecoderAndEncoderRejectUnknownEncodings(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, decoderErr := pipe.NewDecoder(strings.NewReader(""), pipe.Encoding(99))
+	_, encoderErr := pipe.NewEncoder(&bytes.Buffer{}, pipe.Encoding(99))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.ErrUnknownEncoding{"Encoding(99)"}, decoderErr)
+	assert.Equal(t, pipe.ErrUnknownEncoding{"Encoding(99)"}, encoderErr)
+}
+
+func TestDecodeStdinGivesDownstreamCommandsUTF8(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromBytes([]byte("\xff\xfea\x00\n\x00\xe9\x00\n\x00"))
+	expectedResult := []string{"a", "é"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.DecodeStdin(pipe.UTF16))
+	actualResult := unit.Stdin.Strings()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDecodeStdinWorksAsAPipeOption(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	defer r.Close()
+
+	useStdin := func(p *pipe.Pipe) (int, error) {
+		p.Stdin = ioextra.NewTextFile(r)
+		return pipe.StatusOkay, nil
+	}
+	unit := pipe.NewPipe(useStdin, pipe.DecodeStdin(pipe.Latin1))
+
+	seen := make(chan string)
+	done := make(chan struct{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	go func() {
+		unit.RunCommand(pipe.ForEachLine(func(p *pipe.Pipe, line string) error {
+			seen <- line
+			return nil
+		}))
+		close(done)
+	}()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// each line must be decoded before we have finished writing
+	w.WriteString("caf\xe9\n")
+	assert.Equal(t, "café", <-seen)
+
+	w.WriteString("na\xefve\n")
+	assert.Equal(t, "naïve", <-seen)
+
+	w.Close()
+	<-done
+	assert.Nil(t, unit.Error())
+}
+
+func TestDecodeStdinRejectsUnknownEncodings(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.DecodeStdin(pipe.Encoding(99)))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	assert.Equal(t, pipe.ErrUnknownEncoding{"Encoding(99)"}, err)
+}
+
+func TestEncodeStdoutEncodesEverythingWritten(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.EncodeStdout(pipe.UTF16LE, pipe.WithBOM()))
+	expectedResult := []byte("\xff\xfeh\x00\xe9\x00\n\x00")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.Stdout.WriteString("h")
+	unit.Stdout.WriteRune('é')
+	unit.Stdout.Write([]byte("\n"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	actualResult, err := unit.StdoutBytes()
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestEncodeStdoutFlushesTheEncoder(t *testing.T) {
+	t.Parallel()
+
+	// writeIncomplete writes the first byte of a three-byte UTF-8
+	// character, and nothing else
+	writeIncomplete := func(p *pipe.Pipe) (int, error) {
+		p.Stdout.Write([]byte("a\xe2"))
+		return pipe.StatusOkay, nil
+	}
+
+	testCases := []struct {
+		name               string
+		options            []pipe.EncodingOption
+		finish             func(p *pipe.Pipe) error
+		expectedResult     []byte
+		expectedStatusCode int
+		expectedErr        error
+	}{
+		{
+			name: "when the command finishes",
+			finish: func(p *pipe.Pipe) error {
+				p.RunCommand(writeIncomplete)
+				return nil
+			},
+			expectedResult: []byte("a\x00\xfd\xff"),
+		},
+		{
+			name: "when the pipe is closed",
+			finish: func(p *pipe.Pipe) error {
+				writeIncomplete(p)
+				return p.Close()
+			},
+			expectedResult: []byte("a\x00\xfd\xff"),
+		},
+		{
+			name: "when Stdout is moved to Stdin",
+			finish: func(p *pipe.Pipe) error {
+				writeIncomplete(p)
+				p.MoveStdoutToStdin()
+				return nil
+			},
+			expectedResult: []byte("a\x00\xfd\xff"),
+		},
+		{
+			name:    "and reports invalid sequences",
+			options: []pipe.EncodingOption{pipe.OnInvalidSequence(pipe.RejectInvalidSequences)},
+			finish: func(p *pipe.Pipe) error {
+				p.RunCommand(writeIncomplete)
+				return nil
+			},
+			expectedResult:     []byte("a\x00"),
+			expectedStatusCode: pipe.StatusNotOkay,
+			expectedErr:        pipe.ErrInvalidSequence{"UTF-8", 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe(pipe.EncodeStdout(pipe.UTF16LE, testCase.options...))
+			stdout := unit.Stdout
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			err := testCase.finish(unit)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.expectedStatusCode, unit.StatusCode())
+			assert.Equal(t, testCase.expectedErr, unit.Error())
+			actualResult, _ := ioutil.ReadAll(stdout)
+			assert.Equal(t, testCase.expectedResult, actualResult)
+		})
+	}
+}
+
+func TestDecodeStdinAndEncodeStdoutCopeWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	statusCode1, err1 := pipe.DecodeStdin(pipe.UTF8)(unit)
+	statusCode2, err2 := pipe.EncodeStdout(pipe.UTF8)(unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusOkay, statusCode1)
+	assert.Nil(t, err1)
+	assert.Equal(t, pipe.StatusOkay, statusCode2)
+	assert.Nil(t, err2)
+}
@@ -39,7 +39,89 @@
 
 package pipe
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrBadRecord is the error returned by the JSON Lines functions and
+// PipeCommands, when they cannot decode or process a record.
+type ErrBadRecord struct {
+	LineNo int
+	Err    error
+}
+
+func (e ErrBadRecord) Error() string {
+	return fmt.Sprintf("bad record on line %d: %v", e.LineNo, e.Err)
+}
+
+// Unwrap returns the reason why the record was bad.
+func (e ErrBadRecord) Unwrap() error {
+	return e.Err
+}
+
+// ErrBreak is the error returned by Break. It tells While, Until,
+// ForEach and ForEachLine to stop looping.
+type ErrBreak struct{}
+
+func (e ErrBreak) Error() string {
+	return "break: only meaningful in a loop"
+}
+
+// ErrContinue is the error returned by Continue. It tells While, Until,
+// ForEach and ForEachLine to skip to the next time around the loop.
+type ErrContinue struct{}
+
+func (e ErrContinue) Error() string {
+	return "continue: only meaningful in a loop"
+}
+
+// ErrInvalidSequence is the error returned when we find bytes that are
+// not valid in the encoding that we are reading, and we have been told to
+// reject them.
+type ErrInvalidSequence struct {
+	Encoding string
+	Offset   int64
+}
+
+func (e ErrInvalidSequence) Error() string {
+	return fmt.Sprintf("invalid %s sequence at byte offset %d", e.Encoding, e.Offset)
+}
+
+// ErrJSONFuncSignature is the error returned by MapJSON, FilterJSON and
+// ForEachJSON, when they are given a function that they cannot call.
+type ErrJSONFuncSignature struct {
+	Want string
+	Got  string
+}
+
+func (e ErrJSONFuncSignature) Error() string {
+	return fmt.Sprintf("JSON Lines function must be a %s, not a %s", e.Want, e.Got)
+}
+
+// ErrJSONLinesTarget is the error returned by DecodeJSONLines and
+// EncodeJSONLines, when they are given something other than a slice
+// (or a pointer to a slice) to work with.
+type ErrJSONLinesTarget struct {
+	Type string
+}
+
+func (e ErrJSONLinesTarget) Error() string {
+	return fmt.Sprintf("JSON Lines records must be in a slice, not a %s", e.Type)
+}
+
+// ErrLineTooLong is the error returned by MapLines, FilterLines and
+// ForEachLine, when the pipe's Stdin contains a line that is longer
+// than the maximum line length.
+type ErrLineTooLong struct {
+	MaxLength int
+}
+
+func (e ErrLineTooLong) Error() string {
+	return fmt.Sprintf("line is longer than the maximum of %d bytes", e.MaxLength)
+}
 
 // ErrNonZeroStatusCode is the error returned by Pipe.RunCommand when
 // a PipeCommand has finished with a non-zero status code, and no error
@@ -56,3 +138,201 @@ func (e ErrNonZeroStatusCode) Error() string {
 		e.StatusCode,
 	)
 }
+
+// ErrOutputLimitExceeded is the error returned when a PipeCommand writes
+// more than WithMaxOutput allows, and the policy is FailOnOutputLimit.
+type ErrOutputLimitExceeded struct {
+	Command string
+	Stream  string
+	Limit   int64
+}
+
+func (e ErrOutputLimitExceeded) Error() string {
+	return fmt.Sprintf("command %s: %s exceeded the limit of %d bytes", e.Command, e.Stream, e.Limit)
+}
+
+// ErrPanic is the error that Pipe.Exit gives the pipe, when it recovers
+// from a panic.
+type ErrPanic struct {
+	Value interface{}
+}
+
+func (e ErrPanic) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ErrParseEnvFile is the error returned by WithEnvFromFile when it finds
+// a line that it cannot understand.
+type ErrParseEnvFile struct {
+	Filename string
+	LineNo   int
+	Reason   string
+}
+
+func (e ErrParseEnvFile) Error() string {
+	return fmt.Sprintf(
+		"cannot parse env file %s, line %d: %s",
+		e.Filename,
+		e.LineNo,
+		e.Reason,
+	)
+}
+
+// ErrReplayedCommand is the error returned by a Replayer, when the
+// recorded PipeCommand returned an error.
+//
+// Its error message is the same as the recorded error's message.
+type ErrReplayedCommand struct {
+	Name    string
+	Message string
+}
+
+func (e ErrReplayedCommand) Error() string {
+	return e.Message
+}
+
+// ErrRetriesExhausted is the error returned by Retry, when every attempt
+// has failed.
+type ErrRetriesExhausted struct {
+	Attempts []RetryAttempt
+}
+
+func (e ErrRetriesExhausted) Error() string {
+	retval := fmt.Sprintf("gave up after %d attempt(s)", len(e.Attempts))
+	if len(e.Attempts) == 0 {
+		return retval
+	}
+
+	last := e.Attempts[len(e.Attempts)-1]
+	if last.Err != nil {
+		return retval + ": " + last.Err.Error()
+	}
+	return retval + fmt.Sprintf(": exited with status code %d", last.StatusCode)
+}
+
+// Unwrap returns the error returned by the last attempt.
+func (e ErrRetriesExhausted) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// ErrSignal is the error that RunCommand reports, once the pipe has
+// received an OS signal (see WithSignals).
+type ErrSignal struct {
+	Signal os.Signal
+}
+
+func (e ErrSignal) Error() string {
+	return fmt.Sprintf("interrupted by signal: %s", e.Signal)
+}
+
+// ErrTimeout is the error returned by Timeout, when a PipeCommand takes
+// too long.
+type ErrTimeout struct {
+	Timeout time.Duration
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// ErrTranscriptExhausted is the error returned by a Replayer, when
+// a PipeCommand runs more times than it did when it was recorded.
+type ErrTranscriptExhausted struct {
+	Name string
+}
+
+func (e ErrTranscriptExhausted) Error() string {
+	return fmt.Sprintf("no more recorded output for command %s", e.Name)
+}
+
+// ErrUnencodableCharacter is the error returned when we are asked to
+// write a character that the output encoding does not have, and we have
+// been told to reject it.
+type ErrUnencodableCharacter struct {
+	Encoding string
+	Char     rune
+}
+
+func (e ErrUnencodableCharacter) Error() string {
+	return fmt.Sprintf("cannot encode %U in %s", e.Char, e.Encoding)
+}
+
+// ErrUnknownColumn is the error returned by CSV and TSV, when we are
+// asked to select a column that the records do not have.
+type ErrUnknownColumn struct {
+	Column string
+}
+
+func (e ErrUnknownColumn) Error() string {
+	return fmt.Sprintf("unknown column %q", e.Column)
+}
+
+// ErrUnknownEncoding is the error returned when we are asked to use a
+// character encoding that we do not support.
+type ErrUnknownEncoding struct {
+	Name string
+}
+
+func (e ErrUnknownEncoding) Error() string {
+	return fmt.Sprintf("unknown character encoding %q", e.Name)
+}
+
+// ErrUnsupportedRecordFormat is the error returned by CSV and TSV, when
+// they are asked to read or write a RecordFormat that they do not support.
+type ErrUnsupportedRecordFormat struct {
+	Format RecordFormat
+}
+
+func (e ErrUnsupportedRecordFormat) Error() string {
+	return fmt.Sprintf("unsupported record format %d", e.Format)
+}
+
+// ErrUnsupportedTranscriptVersion is the error returned by ReadTranscript,
+// when the transcript was written in a format that we do not understand.
+type ErrUnsupportedTranscriptVersion struct {
+	Version int
+}
+
+func (e ErrUnsupportedTranscriptVersion) Error() string {
+	return fmt.Sprintf("unsupported transcript version %d", e.Version)
+}
+
+// ErrXargsBatchFailed is the error returned by Xargs, when one or more
+// of its batches fail. It describes the first batch that failed.
+type ErrXargsBatchFailed struct {
+	Args       []string
+	StatusCode int
+	Err        error
+}
+
+func (e ErrXargsBatchFailed) Error() string {
+	retval := fmt.Sprintf(
+		"xargs: batch [%s] exited with status code %d",
+		strings.Join(e.Args, " "),
+		e.StatusCode,
+	)
+	if e.Err != nil {
+		retval += ": " + e.Err.Error()
+	}
+
+	return retval
+}
+
+// Unwrap returns the error returned by the batch that failed.
+func (e ErrXargsBatchFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrXargsUnmatchedQuote is the error returned by Xargs, when a line of
+// its input has an opening quote, but no closing quote.
+type ErrXargsUnmatchedQuote struct {
+	Quote rune
+}
+
+func (e ErrXargsUnmatchedQuote) Error() string {
+	return fmt.Sprintf("xargs: unmatched %c quote", e.Quote)
+}
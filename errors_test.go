@@ -40,12 +40,154 @@
 package pipe_test
 
 import (
+	"errors"
+	"os"
 	"testing"
+	"time"
 
 	pipe "github.com/ganbarodigital/go_pipe/v7"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestErrBadRecord(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrBadRecord{
+		12,
+		errors.New("unexpected end of JSON input"),
+	}
+	expectedResult := "bad record on line 12: unexpected end of JSON input"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrBreak(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrBreak{}
+	expectedResult := "break: only meaningful in a loop"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrContinue(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrContinue{}
+	expectedResult := "continue: only meaningful in a loop"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrInvalidSequence(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrInvalidSequence{
+		"UTF-16LE",
+		42,
+	}
+	expectedResult := "invalid UTF-16LE sequence at byte offset 42"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrJSONFuncSignature(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrJSONFuncSignature{
+		"func(T) bool",
+		"func(int) string",
+	}
+	expectedResult := "JSON Lines function must be a func(T) bool, not a func(int) string"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrJSONLinesTarget(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrJSONLinesTarget{
+		"map[string]int",
+	}
+	expectedResult := "JSON Lines records must be in a slice, not a map[string]int"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrLineTooLong(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrLineTooLong{
+		10,
+	}
+	expectedResult := "line is longer than the maximum of 10 bytes"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
 func TestErrNonZeroStatusCode(t *testing.T) {
 	// ----------------------------------------------------------------
 	// setup your test
@@ -66,3 +208,361 @@ func TestErrNonZeroStatusCode(t *testing.T) {
 
 	assert.Equal(t, expectedResult, actualResult)
 }
+
+func TestErrOutputLimitExceeded(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrOutputLimitExceeded{
+		"Chatty",
+		"stdout",
+		1024,
+	}
+	expectedResult := "command Chatty: stdout exceeded the limit of 1024 bytes"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrPanic(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrPanic{
+		"index out of range",
+	}
+	expectedResult := "panic: index out of range"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrParseEnvFile(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrParseEnvFile{
+		"testdata/app.env",
+		3,
+		"missing '='",
+	}
+	expectedResult := "cannot parse env file testdata/app.env, line 3: missing '='"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrReplayedCommand(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrReplayedCommand{
+		"fetch",
+		"connection refused",
+	}
+	expectedResult := "connection refused"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrRetriesExhausted(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	lastErr := errors.New("connection refused")
+	testData := pipe.ErrRetriesExhausted{
+		[]pipe.RetryAttempt{
+			{Attempt: 1, StatusCode: 1, Err: errors.New("timed out")},
+			{Attempt: 2, StatusCode: 1, Err: lastErr},
+		},
+	}
+	expectedResult := "gave up after 2 attempt(s): connection refused"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, lastErr))
+}
+
+func TestErrRetriesExhaustedWithoutAnError(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrRetriesExhausted{
+		[]pipe.RetryAttempt{
+			{Attempt: 1, StatusCode: 3},
+		},
+	}
+	expectedResult := "gave up after 1 attempt(s): exited with status code 3"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Nil(t, errors.Unwrap(testData))
+}
+
+func TestErrSignal(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrSignal{
+		os.Interrupt,
+	}
+	expectedResult := "interrupted by signal: interrupt"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrTimeout(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrTimeout{
+		1500 * time.Millisecond,
+	}
+	expectedResult := "timed out after 1.5s"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrTranscriptExhausted(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrTranscriptExhausted{
+		"fetch",
+	}
+	expectedResult := "no more recorded output for command fetch"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrUnencodableCharacter(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrUnencodableCharacter{
+		"ISO-8859-1",
+		'€',
+	}
+	expectedResult := "cannot encode U+20AC in ISO-8859-1"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrUnknownColumn(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrUnknownColumn{
+		"price",
+	}
+	expectedResult := `unknown column "price"`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrUnknownEncoding(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrUnknownEncoding{
+		"EBCDIC",
+	}
+	expectedResult := `unknown character encoding "EBCDIC"`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrUnsupportedRecordFormat(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrUnsupportedRecordFormat{
+		pipe.JSONLinesFormat,
+	}
+	expectedResult := "unsupported record format 3"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrUnsupportedTranscriptVersion(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrUnsupportedTranscriptVersion{
+		99,
+	}
+	expectedResult := "unsupported transcript version 99"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrXargsBatchFailed(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrXargsBatchFailed{
+		[]string{"cp", "a", "b"},
+		1,
+		errors.New("permission denied"),
+	}
+	expectedResult := "xargs: batch [cp a b] exited with status code 1: permission denied"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrXargsBatchFailedWithoutAnError(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrXargsBatchFailed{
+		[]string{"cp", "a", "b"},
+		1,
+		nil,
+	}
+	expectedResult := "xargs: batch [cp a b] exited with status code 1"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrXargsUnmatchedQuote(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := pipe.ErrXargsUnmatchedQuote{
+		'"',
+	}
+	expectedResult := `xargs: unmatched " quote`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
@@ -5,5 +5,6 @@ go 1.13
 require (
 	github.com/ganbarodigital/go-ioextra/v2 v2.1.0
 	github.com/ganbarodigital/go_envish/v4 v4.0.1
+	github.com/ganbarodigital/go_glob v1.0.0
 	github.com/stretchr/testify v1.7.0
 )
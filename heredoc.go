@@ -0,0 +1,215 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"strings"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// HereDocOptions tells HereDoc how to treat its template, in the same
+// way that a UNIX shell treats the different kinds of heredoc.
+type HereDocOptions struct {
+	// StripTabs removes leading tab characters from every line, like
+	// a `<<-EOF` heredoc does
+	StripTabs bool
+
+	// Quoted turns off variable expansion and backslash escapes, like
+	// a `<<'EOF'` heredoc does
+	Quoted bool
+}
+
+// HereDoc returns a PipeOption that pushes the given template onto the
+// pipe's Stdin stack (see PushStdin), like a heredoc in a UNIX shell:
+//
+//	cat <<EOF
+//	Hello $NAME
+//	EOF
+//
+// Unless opts.Quoted is set, we expand `$VAR` and `${VAR}` against the
+// pipe's Env (`${VAR}` supports the same modifiers as Env.Expand), and
+// handle these backslash escapes:
+//
+//	\$          a literal '$'
+//	\\          a literal '\'
+//	\<newline>  joins the line with the next one
+//
+// Any other backslash is left alone. Command substitution (`$(...)` and
+// backticks) is not supported, and is left as it is.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func HereDoc(template string, opts HereDocOptions) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		// yes we do
+		input := template
+		if opts.StripTabs {
+			input = stripLeadingTabs(input)
+		}
+		if !opts.Quoted {
+			input = p.expandHereDoc(input)
+		}
+
+		p.pushStdinString(input)
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// HereString returns a PipeOption that pushes the given string onto the
+// pipe's Stdin stack (see PushStdin), like a `<<<` here-string in a UNIX
+// shell. We expand variables in the same way as HereDoc, and add a
+// trailing newline.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func HereString(s string) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		// yes we do
+		p.pushStdinString(p.expandHereDoc(s) + "\n")
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// pushStdinString pushes a new Stdin that contains the given input
+func (p *Pipe) pushStdinString(input string) {
+	buf := ioextra.NewTextBuffer()
+	buf.WriteString(input)
+
+	p.PushStdin(buf)
+}
+
+// expandHereDoc expands variables and backslash escapes in input, the
+// way that a UNIX shell does inside an unquoted heredoc
+func (p *Pipe) expandHereDoc(input string) string {
+	var retval strings.Builder
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+
+		switch {
+		case c == '\\' && i+1 < len(input):
+			switch input[i+1] {
+			case '$', '\\':
+				retval.WriteByte(input[i+1])
+				i++
+			case '\n':
+				i++
+			default:
+				retval.WriteByte(c)
+			}
+
+		case c == '$' && i+1 < len(input) && input[i+1] == '{':
+			end := strings.IndexByte(input[i:], '}')
+			if end < 0 {
+				retval.WriteString(input[i:])
+				return retval.String()
+			}
+			retval.WriteString(p.expandVar(input[i : i+end+1]))
+			i += end
+
+		case c == '$' && i+1 < len(input) && isVarNameStart(input[i+1]):
+			end := i + 1
+			for end < len(input) && isVarNameChar(input[end]) {
+				end++
+			}
+			retval.WriteString(p.getVar(input[i+1 : end]))
+			i = end - 1
+
+		default:
+			retval.WriteByte(c)
+		}
+	}
+
+	return retval.String()
+}
+
+// expandVar expands a `${...}` expression against the pipe's Env
+func (p *Pipe) expandVar(expr string) string {
+	if p.Env == nil {
+		return ""
+	}
+
+	return p.Env.Expand(expr)
+}
+
+// getVar returns the value of the named variable from the pipe's Env
+func (p *Pipe) getVar(name string) string {
+	if p.Env == nil {
+		return ""
+	}
+
+	return p.Env.Getenv(name)
+}
+
+// isVarNameStart returns true if c can start a variable name
+func isVarNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isVarNameChar returns true if c can appear in a variable name
+func isVarNameChar(c byte) bool {
+	return isVarNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// stripLeadingTabs removes the tab characters from the start of every
+// line in input
+func stripLeadingTabs(input string) string {
+	lines := strings.SplitAfter(input, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimLeft(line, "\t")
+	}
+
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,234 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHereDocExpandsTheTemplate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		template       string
+		opts           pipe.HereDocOptions
+		expectedResult string
+	}{
+		{
+			name:           "plain variables",
+			template:       "Hello $NAME,\nfrom $PLACE.\n",
+			expectedResult: "Hello Alice,\nfrom Paris.\n",
+		},
+		{
+			name:           "variable at the end of a line",
+			template:       "$NAME\n",
+			expectedResult: "Alice\n",
+		},
+		{
+			name:           "braced variables",
+			template:       "${NAME}s and ${MISSING:-nobody}\n",
+			expectedResult: "Alices and nobody\n",
+		},
+		{
+			name:           "unknown variables are empty",
+			template:       "[$MISSING]\n",
+			expectedResult: "[]\n",
+		},
+		{
+			name:           "escaped dollar signs",
+			template:       "it costs \\$5, \\\\$NAME\n",
+			expectedResult: "it costs $5, \\Alice\n",
+		},
+		{
+			name:           "line continuations",
+			template:       "one \\\ntwo\n",
+			expectedResult: "one two\n",
+		},
+		{
+			name:           "lone dollars and other backslashes",
+			template:       "$ 5 $(date) \\n\n",
+			expectedResult: "$ 5 $(date) \\n\n",
+		},
+		{
+			name:           "strip tabs",
+			template:       "\t\tHello $NAME\n\tbye\n",
+			opts:           pipe.HereDocOptions{StripTabs: true},
+			expectedResult: "Hello Alice\nbye\n",
+		},
+		{
+			name:           "keep tabs",
+			template:       "\tHello\n",
+			expectedResult: "\tHello\n",
+		},
+		{
+			name:           "quoted",
+			template:       "\tHello $NAME \\$ \\\n",
+			opts:           pipe.HereDocOptions{Quoted: true},
+			expectedResult: "\tHello $NAME \\$ \\\n",
+		},
+		{
+			name:           "quoted and strip tabs",
+			template:       "\tHello $NAME\n",
+			opts:           pipe.HereDocOptions{Quoted: true, StripTabs: true},
+			expectedResult: "Hello $NAME\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			p := pipe.NewPipe(pipe.WithEnvFromMap(map[string]string{
+				"NAME":  "Alice",
+				"PLACE": "Paris",
+			}))
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			statusCode, err := pipe.HereDoc(testCase.template, testCase.opts)(p)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err)
+			assert.Equal(t, pipe.StatusOkay, statusCode)
+			assert.Equal(t, testCase.expectedResult, p.Stdin.String())
+		})
+	}
+}
+
+func TestHereDocCanBeUsedAsAnOption(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := "Hello \n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p := pipe.NewPipe(
+		pipe.WithEmptyEnv,
+		pipe.HereDoc("Hello $NAME\n", pipe.HereDocOptions{}),
+	)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, p.Stdin.String())
+}
+
+func TestHereDocPushesStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe()
+	p.SetStdinFromString("original\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(pipe.HereDoc("heredoc\n", pipe.HereDocOptions{}))
+	heredoc := p.Stdin.String()
+	p.PopStdin()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "heredoc\n", heredoc)
+	assert.Equal(t, "original\n", p.Stdin.String())
+}
+
+func TestHereStringExpandsAndAddsANewline(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(pipe.WithEnvFromMap(map[string]string{"NAME": "Alice"}))
+	expectedResult := "Hello Alice.\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	statusCode, err := pipe.HereString("Hello $NAME.")(p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, pipe.StatusOkay, statusCode)
+	assert.Equal(t, expectedResult, p.Stdin.String())
+}
+
+func TestHereDocCopesWithNilPipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var p *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	heredocStatus, heredocErr := pipe.HereDoc("hello", pipe.HereDocOptions{})(p)
+	hereStringStatus, hereStringErr := pipe.HereString("hello")(p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, heredocErr)
+	assert.Equal(t, pipe.StatusOkay, heredocStatus)
+	assert.Nil(t, hereStringErr)
+	assert.Equal(t, pipe.StatusOkay, hereStringStatus)
+}
@@ -0,0 +1,346 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// BadRecordPolicy tells the JSON Lines PipeCommands what to do when
+// they find a record that they cannot decode or process.
+type BadRecordPolicy int
+
+const (
+	// AbortOnBadRecord stops the PipeCommand at the first bad record.
+	// This is the default.
+	AbortOnBadRecord BadRecordPolicy = iota
+
+	// SkipBadRecords reports each bad record to the pipe's Stderr, and
+	// carries on with the next record.
+	SkipBadRecords
+)
+
+// JSONOption is the signature of any function that changes how MapJSON,
+// FilterJSON and ForEachJSON work.
+type JSONOption = func(*jsonConfig)
+
+// jsonConfig holds the settings for the JSON Lines PipeCommands
+type jsonConfig struct {
+	badRecordPolicy BadRecordPolicy
+}
+
+// OnBadRecord sets what happens when a JSON Lines PipeCommand finds
+// a record that it cannot decode, or that your function returns an
+// error for.
+func OnBadRecord(policy BadRecordPolicy) JSONOption {
+	return func(c *jsonConfig) {
+		c.badRecordPolicy = policy
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var pipeType = reflect.TypeOf((*Pipe)(nil))
+
+// DecodeJSONLines reads JSON Lines from r, and appends each record to
+// the slice that records points to.
+//
+// records must be a pointer to a slice, eg *[]Order. Blank lines are
+// ignored. It stops at the first record that it cannot decode, and
+// returns an ErrBadRecord.
+func DecodeJSONLines(r io.Reader, records interface{}) error {
+	target := reflect.ValueOf(records)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Slice {
+		return ErrJSONLinesTarget{typeName(records)}
+	}
+	slice := target.Elem()
+	recordType := slice.Type().Elem()
+
+	return eachJSONLine(r, func(lineNo int, line []byte) error {
+		record := reflect.New(recordType)
+		err := json.Unmarshal(line, record.Interface())
+		if err != nil {
+			return ErrBadRecord{lineNo, err}
+		}
+
+		slice.Set(reflect.Append(slice, record.Elem()))
+		return nil
+	})
+}
+
+// EncodeJSONLines writes each record in the given slice to w, as
+// JSON Lines.
+//
+// records must be a slice, eg []Order.
+func EncodeJSONLines(w io.Writer, records interface{}) error {
+	slice := reflect.ValueOf(records)
+	if slice.Kind() != reflect.Slice {
+		return ErrJSONLinesTarget{typeName(records)}
+	}
+
+	for i := 0; i < slice.Len(); i++ {
+		err := writeJSONLine(w, slice.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MapJSON returns a PipeCommand that decodes each JSON Lines record in
+// the pipe's Stdin, passes it to fn, and writes whatever fn returns to
+// the pipe's Stdout as a JSON Lines record.
+//
+// fn must be a func(T) (U, error), where T is the type to decode each
+// record into. If fn returns a nil pointer, map, slice or interface,
+// nothing is written for that record.
+func MapJSON(fn interface{}, options ...JSONOption) PipeCommand {
+	fnValue := reflect.ValueOf(fn)
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil ||
+		fnType.Kind() != reflect.Func ||
+		fnType.NumIn() != 1 ||
+		fnType.NumOut() != 2 ||
+		fnType.Out(1) != errorType {
+		return jsonFuncSignatureError("func(T) (U, error)", fnType)
+	}
+
+	return jsonCommand(
+		fnType.In(0),
+		options,
+		func(p *Pipe, line []byte, record reflect.Value) error {
+			results := fnValue.Call([]reflect.Value{record})
+			if err, _ := results[1].Interface().(error); err != nil {
+				return err
+			}
+
+			// did fn drop the record?
+			if isNilValue(results[0]) {
+				return nil
+			}
+			return writeJSONLine(p.Stdout, results[0].Interface())
+		},
+	)
+}
+
+// FilterJSON returns a PipeCommand that decodes each JSON Lines record
+// in the pipe's Stdin, and copies it to the pipe's Stdout if fn returns
+// true for it.
+//
+// fn must be a func(T) bool, where T is the type to decode each record
+// into. The record is copied exactly as it was, so that no fields are
+// lost if T does not have them.
+func FilterJSON(fn interface{}, options ...JSONOption) PipeCommand {
+	fnValue := reflect.ValueOf(fn)
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil ||
+		fnType.Kind() != reflect.Func ||
+		fnType.NumIn() != 1 ||
+		fnType.NumOut() != 1 ||
+		fnType.Out(0).Kind() != reflect.Bool {
+		return jsonFuncSignatureError("func(T) bool", fnType)
+	}
+
+	return jsonCommand(
+		fnType.In(0),
+		options,
+		func(p *Pipe, line []byte, record reflect.Value) error {
+			results := fnValue.Call([]reflect.Value{record})
+			if results[0].Bool() {
+				p.Stdout.Write(line)
+				p.Stdout.WriteRune('\n')
+			}
+			return nil
+		},
+	)
+}
+
+// ForEachJSON returns a PipeCommand that decodes each JSON Lines record
+// in the pipe's Stdin, and passes it to fn.
+//
+// fn must be a func(*Pipe, T) error, where T is the type to decode each
+// record into. fn can write whatever it wants to the pipe's Stdout and
+// Stderr.
+func ForEachJSON(fn interface{}, options ...JSONOption) PipeCommand {
+	fnValue := reflect.ValueOf(fn)
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil ||
+		fnType.Kind() != reflect.Func ||
+		fnType.NumIn() != 2 ||
+		fnType.In(0) != pipeType ||
+		fnType.NumOut() != 1 ||
+		fnType.Out(0) != errorType {
+		return jsonFuncSignatureError("func(*Pipe, T) error", fnType)
+	}
+
+	return jsonCommand(
+		fnType.In(1),
+		options,
+		func(p *Pipe, line []byte, record reflect.Value) error {
+			results := fnValue.Call([]reflect.Value{reflect.ValueOf(p), record})
+			err, _ := results[0].Interface().(error)
+			return err
+		},
+	)
+}
+
+// jsonCommand builds a PipeCommand that decodes each JSON Lines record
+// in the pipe's Stdin into a new recordType, and passes it to fn
+func jsonCommand(
+	recordType reflect.Type,
+	options []JSONOption,
+	fn func(p *Pipe, line []byte, record reflect.Value) error,
+) PipeCommand {
+	config := jsonConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return StatusOkay, nil
+		}
+
+		err := eachJSONLine(p.Stdin, func(lineNo int, line []byte) error {
+			record := reflect.New(recordType)
+			err := json.Unmarshal(line, record.Interface())
+			if err == nil {
+				err = fn(p, line, record.Elem())
+			}
+			if err == nil {
+				return nil
+			}
+
+			// what do we do with a bad record?
+			err = ErrBadRecord{lineNo, err}
+			if config.badRecordPolicy == SkipBadRecords {
+				if p.Stderr != nil {
+					p.Stderr.WriteString(err.Error())
+					p.Stderr.WriteRune('\n')
+				}
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return StatusNotOkay, err
+		}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// eachJSONLine calls fn for each non-blank line in r, until fn returns
+// an error
+func eachJSONLine(r io.Reader, fn func(lineNo int, line []byte) error) error {
+	reader := bufio.NewReader(r)
+
+	lineNo := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if len(line) > 0 {
+			lineNo++
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			err := fn(lineNo, line)
+			if err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// writeJSONLine writes record to w as a single line of JSON
+func writeJSONLine(w io.Writer, record interface{}) error {
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// isNilValue returns true if v holds a nil pointer, map, slice or
+// interface
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// typeName returns the name of v's type, for use in error messages
+func typeName(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// jsonFuncSignatureError returns a PipeCommand that reports that we
+// were given a function with the wrong signature
+func jsonFuncSignatureError(want string, got reflect.Type) PipeCommand {
+	gotName := "nil"
+	if got != nil {
+		gotName = got.String()
+	}
+
+	return func(p *Pipe) (int, error) {
+		return StatusNotOkay, ErrJSONFuncSignature{want, gotName}
+	}
+}
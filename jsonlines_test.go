@@ -0,0 +1,376 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	ID    int     `json:"id"`
+	Total float64 `json:"total"`
+}
+
+type testOrderSummary struct {
+	ID  int  `json:"id"`
+	Big bool `json:"big"`
+}
+
+func TestDecodeJSONLinesAppendsEachRecord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "{\"id\":1,\"total\":9.5}\n\n  {\"id\":2,\"total\":120}  \n{\"id\":3}"
+	expectedResult := []testOrder{{1, 9.5}, {2, 120}, {3, 0}}
+	var actualResult []testOrder
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := pipe.DecodeJSONLines(strings.NewReader(input), &actualResult)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDecodeJSONLinesReturnsErrBadRecord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "{\"id\":1}\n\n{\"id\":\"two\"}\n{\"id\":3}\n"
+	var actualResult []testOrder
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := pipe.DecodeJSONLines(strings.NewReader(input), &actualResult)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	badRecord, ok := err.(pipe.ErrBadRecord)
+	assert.True(t, ok)
+	assert.Equal(t, 3, badRecord.LineNo)
+	assert.Equal(t, []testOrder{{ID: 1}}, actualResult)
+}
+
+func TestDecodeJSONLinesNeedsAPointerToASlice(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var records []testOrder
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err1 := pipe.DecodeJSONLines(strings.NewReader(""), records)
+	err2 := pipe.DecodeJSONLines(strings.NewReader(""), nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.ErrJSONLinesTarget{"[]pipe_test.testOrder"}, err1)
+	assert.Equal(t, pipe.ErrJSONLinesTarget{"nil"}, err2)
+}
+
+func TestEncodeJSONLinesWritesOneRecordPerLine(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	records := []testOrder{{1, 9.5}, {2, 120}}
+	expectedResult := "{\"id\":1,\"total\":9.5}\n{\"id\":2,\"total\":120}\n"
+	var buf bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := pipe.EncodeJSONLines(&buf, records)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, buf.String())
+}
+
+func TestEncodeJSONLinesNeedsASlice(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var buf bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := pipe.EncodeJSONLines(&buf, testOrder{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.ErrJSONLinesTarget{"pipe_test.testOrder"}, err)
+}
+
+func TestMapJSONWritesEachResult(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1,\"total\":9.5}\n{\"id\":2,\"total\":120}\n")
+	expectedResult := "{\"id\":1,\"big\":false}\n{\"id\":2,\"big\":true}\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapJSON(func(order testOrder) (testOrderSummary, error) {
+		return testOrderSummary{order.ID, order.Total > 100}, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Stdout.String())
+}
+
+func TestMapJSONDropsNilResults(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1}\n{\"id\":2}\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapJSON(func(order *testOrder) (*testOrder, error) {
+		if order.ID == 1 {
+			return nil, nil
+		}
+		return order, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "{\"id\":2,\"total\":0}\n", unit.Stdout.String())
+}
+
+func TestMapJSONAbortsOnBadRecordsByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1}\nnot json\n{\"id\":3}\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapJSON(func(order testOrder) (int, error) {
+		return order.ID, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	badRecord, ok := err.(pipe.ErrBadRecord)
+	assert.True(t, ok)
+	assert.Equal(t, 2, badRecord.LineNo)
+	assert.Equal(t, "1\n", unit.Stdout.String())
+}
+
+func TestMapJSONCanSkipBadRecords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1}\nnot json\n{\"id\":3}\n{\"id\":4}\n")
+	fnErr := errors.New("id 3 is not allowed")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapJSON(
+		func(order testOrder) (int, error) {
+			if order.ID == 3 {
+				return 0, fnErr
+			}
+			return order.ID, nil
+		},
+		pipe.OnBadRecord(pipe.SkipBadRecords),
+	))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "1\n4\n", unit.Stdout.String())
+	assert.Equal(
+		t,
+		"bad record on line 2: invalid character 'o' in literal null (expecting 'u')\n"+
+			"bad record on line 3: id 3 is not allowed\n",
+		unit.Stderr.String(),
+	)
+}
+
+func TestFilterJSONCopiesMatchingRecordsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1,\"total\":9.5,\"note\":\"small\"}\n{\"id\":2,\"total\":120,\"note\":\"big\"}\n")
+	expectedResult := "{\"id\":2,\"total\":120,\"note\":\"big\"}\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.FilterJSON(func(order testOrder) bool {
+		return order.Total > 100
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Stdout.String())
+}
+
+func TestForEachJSONPassesInThePipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("{\"id\":1}\n{\"id\":2}\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEachJSON(func(p *pipe.Pipe, order testOrder) error {
+		p.Stderr.WriteString("processed order\n")
+		return nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "processed order\nprocessed order\n", unit.Stderr.String())
+}
+
+func TestJSONCommandsCheckTheFunctionSignature(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		command     pipe.PipeCommand
+		expectedErr error
+	}{
+		{
+			name:        "MapJSON",
+			command:     pipe.MapJSON(func(order testOrder) int { return 0 }),
+			expectedErr: pipe.ErrJSONFuncSignature{"func(T) (U, error)", "func(pipe_test.testOrder) int"},
+		},
+		{
+			name:        "FilterJSON",
+			command:     pipe.FilterJSON(nil),
+			expectedErr: pipe.ErrJSONFuncSignature{"func(T) bool", "nil"},
+		},
+		{
+			name:        "ForEachJSON",
+			command:     pipe.ForEachJSON(func(order testOrder) error { return nil }),
+			expectedErr: pipe.ErrJSONFuncSignature{"func(*Pipe, T) error", "func(pipe_test.testOrder) error"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(testCase.command)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedErr, unit.Error())
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"errors"
+)
+
+// DefaultMaxLineLength is the longest line (in bytes, not including the
+// line ending) that MapLines, FilterLines and ForEachLine will read,
+// unless you tell them otherwise.
+const DefaultMaxLineLength = bufio.MaxScanTokenSize
+
+// LineOption is the signature of any function that changes how
+// MapLines, FilterLines and ForEachLine read the pipe's Stdin.
+type LineOption = func(*lineConfig)
+
+// lineConfig holds the settings for reading Stdin line by line
+type lineConfig struct {
+	maxLineLength int
+}
+
+// WithMaxLineLength sets the longest line (in bytes, not including the
+// line ending) that we will read from the pipe's Stdin.
+//
+// If Stdin contains a longer line, the PipeCommand stops, and returns
+// ErrLineTooLong.
+//
+// Anything less than 1 means 1.
+func WithMaxLineLength(maxLineLength int) LineOption {
+	// we need room for the line ending, without overflowing
+	switch {
+	case maxLineLength < 1:
+		maxLineLength = 1
+	case maxLineLength > maxInt-2:
+		maxLineLength = maxInt - 2
+	}
+
+	return func(c *lineConfig) {
+		c.maxLineLength = maxLineLength
+	}
+}
+
+// maxInt is the largest value that an int can hold
+const maxInt = int(^uint(0) >> 1)
+
+// MapLines returns a PipeCommand that calls fn for each line of the
+// pipe's Stdin, and writes whatever fn returns to the pipe's Stdout.
+//
+// Lines are read one at a time, and each result is written (followed
+// by a newline) as soon as fn returns it. If fn returns an error, the
+// PipeCommand stops, and returns that error.
+func MapLines(fn func(line string) (string, error), options ...LineOption) PipeCommand {
+	return ForEachLine(
+		func(p *Pipe, line string) error {
+			newLine, err := fn(line)
+			if err != nil {
+				return err
+			}
+
+			p.Stdout.WriteString(newLine)
+			p.Stdout.WriteRune('\n')
+			return nil
+		},
+		options...,
+	)
+}
+
+// FilterLines returns a PipeCommand that copies each line of the pipe's
+// Stdin to the pipe's Stdout, but only if fn returns true for that line.
+//
+// Lines are read one at a time, and each matching line is written
+// (followed by a newline) straight away.
+func FilterLines(fn func(line string) bool, options ...LineOption) PipeCommand {
+	return ForEachLine(
+		func(p *Pipe, line string) error {
+			if fn(line) {
+				p.Stdout.WriteString(line)
+				p.Stdout.WriteRune('\n')
+			}
+			return nil
+		},
+		options...,
+	)
+}
+
+// ForEachLine returns a PipeCommand that calls fn for each line of the
+// pipe's Stdin. fn can write whatever it wants to the pipe's Stdout and
+// Stderr.
+//
+// Lines are read one at a time, without their line endings. If fn
+// returns an error, the PipeCommand stops, and returns that error.
+//
+// fn can return ErrBreak to stop early without an error, or ErrContinue
+// to move on to the next line.
+func ForEachLine(fn func(p *Pipe, line string) error, options ...LineOption) PipeCommand {
+	config := lineConfig{
+		maxLineLength: DefaultMaxLineLength,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return StatusOkay, nil
+		}
+
+		// bufio.Scanner needs room for the line ending too
+		scanner := bufio.NewScanner(p.Stdin)
+		scanner.Buffer(
+			make([]byte, 0, minInt(config.maxLineLength+2, 4096)),
+			config.maxLineLength+2,
+		)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			// the scanner's limit includes the line ending, so we
+			// have to enforce ours here
+			if len(line) > config.maxLineLength {
+				return StatusNotOkay, ErrLineTooLong{config.maxLineLength}
+			}
+
+			err := fn(p, line)
+			switch {
+			case errors.As(err, &ErrBreak{}):
+				return StatusOkay, nil
+			case err != nil && !errors.As(err, &ErrContinue{}):
+				return StatusNotOkay, err
+			}
+		}
+
+		err := scanner.Err()
+		if err == bufio.ErrTooLong {
+			return StatusNotOkay, ErrLineTooLong{config.maxLineLength}
+		}
+		if err != nil {
+			return StatusNotOkay, err
+		}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
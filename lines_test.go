@@ -0,0 +1,349 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapLinesWritesEachResultToStdout(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("one\ntwo\r\nthree")
+	expectedResult := "ONE\nTWO\nTHREE\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapLines(func(line string) (string, error) {
+		return strings.ToUpper(line), nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Stdout.String())
+}
+
+func TestMapLinesStopsOnFirstError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("one\nbad\nthree\n")
+	expectedErr := errors.New("bad line")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.MapLines(func(line string) (string, error) {
+		if line == "bad" {
+			return "", expectedErr
+		}
+		return line, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, "one\n", unit.Stdout.String())
+}
+
+func TestFilterLinesOnlyWritesMatchingLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("apple\nbanana\navocado\n")
+	expectedResult := "apple\navocado\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.FilterLines(func(line string) bool {
+		return strings.HasPrefix(line, "a")
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Stdout.String())
+}
+
+func TestForEachLineReadsStdinIncrementally(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	defer r.Close()
+
+	unit := pipe.NewPipe()
+	unit.Stdin = ioextra.NewTextFile(r)
+
+	seen := make(chan string)
+	done := make(chan struct{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	go func() {
+		unit.RunCommand(pipe.ForEachLine(func(p *pipe.Pipe, line string) error {
+			seen <- line
+			return nil
+		}))
+		close(done)
+	}()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// the first line must arrive before we have finished writing
+	w.WriteString("first\n")
+	assert.Equal(t, "first", <-seen)
+
+	w.WriteString("second\n")
+	assert.Equal(t, "second", <-seen)
+
+	w.Close()
+	<-done
+	assert.Nil(t, unit.Error())
+}
+
+func TestForEachLineReturnsErrLineTooLong(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"with line ending", "short\n0123456789ABC\n"},
+		{"without line ending", "short\n0123456789A"},
+		{"much too long", "short\n" + strings.Repeat("x", 100)},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString(testCase.input)
+			var lines []string
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.ForEachLine(
+				func(p *pipe.Pipe, line string) error {
+					lines = append(lines, line)
+					return nil
+				},
+				pipe.WithMaxLineLength(10),
+			))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			statusCode, err := unit.StatusError()
+			assert.Equal(t, pipe.StatusNotOkay, statusCode)
+			assert.Equal(t, pipe.ErrLineTooLong{10}, err)
+			assert.Equal(t, []string{"short"}, lines)
+		})
+	}
+}
+
+func TestForEachLineAcceptsLinesOfMaxLength(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("0123456789\r\n0123456789")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.FilterLines(
+		func(line string) bool { return true },
+		pipe.WithMaxLineLength(10),
+	))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "0123456789\n0123456789\n", unit.Stdout.String())
+}
+
+func TestWithMaxLineLengthCopesWithOutOfRangeValues(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		maxLineLength  int
+		expectedResult string
+		expectedErr    error
+	}{
+		{
+			name:           "zero means 1",
+			maxLineLength:  0,
+			expectedResult: "a\n",
+			expectedErr:    pipe.ErrLineTooLong{1},
+		},
+		{
+			name:           "negative means 1",
+			maxLineLength:  -1,
+			expectedResult: "a\n",
+			expectedErr:    pipe.ErrLineTooLong{1},
+		},
+		{
+			name:           "the largest int",
+			maxLineLength:  int(^uint(0) >> 1),
+			expectedResult: "a\nbc\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString("a\nbc\n")
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.FilterLines(
+				func(line string) bool { return true },
+				pipe.WithMaxLineLength(testCase.maxLineLength),
+			))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedErr, unit.Error())
+			assert.Equal(t, testCase.expectedResult, unit.Stdout.String())
+		})
+	}
+}
+
+func TestForEachLineSupportsBreakAndContinue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("one\n# comment\ntwo\nstop\nthree\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.ForEachLine(func(p *pipe.Pipe, line string) error {
+		switch line {
+		case "# comment":
+			return pipe.ErrContinue{}
+		case "stop":
+			return pipe.ErrBreak{}
+		}
+
+		p.Stdout.WriteString(line + "\n")
+		return nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "one\ntwo\n", unit.Stdout.String())
+}
+
+func TestForEachLineCopesWithNilPipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.ForEachLine(func(p *pipe.Pipe, line string) error {
+		return nil
+	})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	statusCode, err := unit(nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusOkay, statusCode)
+	assert.Nil(t, err)
+}
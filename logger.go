@@ -0,0 +1,213 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"sort"
+	"sync"
+)
+
+// Logger is the interface that WithLogger needs from your logging
+// library.
+//
+// Each method takes a message, followed by alternating keys and values,
+// in the same style as log/slog. A *slog.Logger satisfies it as it is.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// WithLogger returns a PipeOption that makes the pipe tell the given
+// logger what it is doing.
+//
+// The pipe logs:
+//
+//   - each PipeCommand that RunCommand starts (debug), and how it finished
+//     (info if it worked, error if it did not)
+//   - the names of any environment variables that a PipeCommand set or
+//     unset (info); values are never logged, in case they are secrets
+//   - every push and pop on the Stdin, Stdout and Stderr stacks (debug)
+//   - Stdin, Stdout or Stderr being attached to the program's own (debug)
+//
+// Pass in nil to stop logging. Child pipes use the same logger.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithLogger(logger Logger) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		// yes we do
+		p.logger = logger
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// logCommand logs the start of the named PipeCommand, and returns
+// a function that logs how it finished
+func (p *Pipe) logCommand(name string) func() {
+	logger := p.logger
+	logger.Debug("pipe command started", "command", name)
+
+	var envBefore map[string]string
+	if p.Env != nil {
+		envBefore = environToMap(p.Env.Environ())
+	}
+
+	return func() {
+		if p.err != nil {
+			logger.Error("pipe command failed", "command", name, "status", p.statusCode, "error", p.err)
+		} else {
+			logger.Info("pipe command finished", "command", name, "status", p.statusCode)
+		}
+
+		// did the command change the environment?
+		if p.Env == nil {
+			return
+		}
+		diff := diffEnv(envBefore, environToMap(p.Env.Environ()))
+		if len(diff.Set) == 0 && len(diff.Unset) == 0 {
+			return
+		}
+
+		set := make([]string, 0, len(diff.Set))
+		for key := range diff.Set {
+			set = append(set, key)
+		}
+		sort.Strings(set)
+
+		logger.Info("pipe environment changed", "command", name, "set", set, "unset", diff.Unset)
+	}
+}
+
+// logDebug passes the given message to our logger, if we have one
+func (p *Pipe) logDebug(msg string, keyvals ...interface{}) {
+	if p.logger != nil {
+		p.logger.Debug(msg, keyvals...)
+	}
+}
+
+// ================================================================
+//
+// MemoryLogger
+//
+// ----------------------------------------------------------------
+
+// MemoryLogger is a Logger that keeps everything in memory. Use it in
+// your tests.
+type MemoryLogger struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// entries holds everything that has been logged, oldest first
+	entries []LogEntry
+}
+
+// LogEntry is a single event logged to a MemoryLogger.
+type LogEntry struct {
+	// Level is one of "debug", "info" or "error"
+	Level string
+
+	// Msg is the message that was logged
+	Msg string
+
+	// KeyVals holds the alternating keys and values that were logged
+	KeyVals []interface{}
+}
+
+// Get returns the value logged for the given key, and whether or not
+// there was one.
+func (e LogEntry) Get(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(e.KeyVals); i += 2 {
+		if e.KeyVals[i] == key {
+			return e.KeyVals[i+1], true
+		}
+	}
+
+	return nil, false
+}
+
+// NewMemoryLogger creates a new, empty MemoryLogger.
+func NewMemoryLogger() *MemoryLogger {
+	return &MemoryLogger{}
+}
+
+// Debug logs a debug event. It is part of the Logger interface.
+func (l *MemoryLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log("debug", msg, keyvals)
+}
+
+// Info logs an info event. It is part of the Logger interface.
+func (l *MemoryLogger) Info(msg string, keyvals ...interface{}) {
+	l.log("info", msg, keyvals)
+}
+
+// Error logs an error event. It is part of the Logger interface.
+func (l *MemoryLogger) Error(msg string, keyvals ...interface{}) {
+	l.log("error", msg, keyvals)
+}
+
+// Entries returns a copy of everything that has been logged, oldest
+// first.
+func (l *MemoryLogger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	retval := make([]LogEntry, len(l.entries))
+	copy(retval, l.entries)
+	return retval
+}
+
+func (l *MemoryLogger) log(level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, LogEntry{
+		Level:   level,
+		Msg:     msg,
+		KeyVals: append([]interface{}(nil), keyvals...),
+	})
+}
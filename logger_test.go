@@ -0,0 +1,228 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"errors"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLoggerLogsEachCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	logger := pipe.NewMemoryLogger()
+	unit := pipe.NewPipe(pipe.WithLogger(logger))
+	commandErr := errors.New("it went wrong")
+
+	expectedResult := []pipe.LogEntry{
+		{"debug", "pipe command started", []interface{}{"command", "works"}},
+		{"info", "pipe command finished", []interface{}{"command", "works", "status", 0}},
+		{"debug", "pipe command started", []interface{}{"command", "fails"}},
+		{"error", "pipe command failed", []interface{}{"command", "fails", "status", 2, "error", commandErr}},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("works", func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	})
+	unit.RunNamedCommand("fails", func(p *pipe.Pipe) (int, error) {
+		return 2, commandErr
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, logger.Entries())
+}
+
+func TestWithLoggerUsesTheCommandNameForRunCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	logger := pipe.NewMemoryLogger()
+	unit := pipe.NewPipe(pipe.WithLogger(logger))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(upperCaseStep)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	entries := logger.Entries()
+	assert.Len(t, entries, 2)
+	name, ok := entries[0].Get("command")
+	assert.True(t, ok)
+	assert.Equal(t, pipe.CommandName(upperCaseStep), name)
+}
+
+func TestWithLoggerLogsEnvironmentChanges(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	logger := pipe.NewMemoryLogger()
+	unit := pipe.NewPipe(
+		pipe.WithEmptyEnv,
+		pipe.WithEnvFromMap(map[string]string{"OLD": "1"}),
+		pipe.WithLogger(logger),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("setenv", func(p *pipe.Pipe) (int, error) {
+		p.Env.Setenv("PASSWORD", "secret")
+		p.Env.Setenv("API_KEY", "secret")
+		p.Env.Unsetenv("OLD")
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	entries := logger.Entries()
+	assert.Len(t, entries, 3)
+	assert.Equal(
+		t,
+		pipe.LogEntry{
+			Level: "info",
+			Msg:   "pipe environment changed",
+			KeyVals: []interface{}{
+				"command", "setenv",
+				"set", []string{"API_KEY", "PASSWORD"},
+				"unset", []string{"OLD"},
+			},
+		},
+		entries[2],
+	)
+}
+
+func TestWithLoggerLogsStackOperations(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	logger := pipe.NewMemoryLogger()
+	unit := pipe.NewPipe(pipe.WithLogger(logger))
+
+	expectedResult := []pipe.LogEntry{
+		{"debug", "pipe stack pushed", []interface{}{"stream", "stdin", "depth", 1}},
+		{"debug", "pipe stack pushed", []interface{}{"stream", "stdout", "depth", 1}},
+		{"debug", "pipe stack pushed", []interface{}{"stream", "stderr", "depth", 1}},
+		{"debug", "pipe stack popped", []interface{}{"stream", "stderr", "depth", 0}},
+		{"debug", "pipe stack popped", []interface{}{"stream", "stdout", "depth", 0}},
+		{"debug", "pipe stack popped", []interface{}{"stream", "stdin", "depth", 0}},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.PushStdin(unit.Stdin)
+	unit.PushStdout(unit.Stdout)
+	unit.PushStderr(unit.Stderr)
+	unit.PopStderr()
+	unit.PopStdout()
+	unit.PopStdin()
+
+	// nothing left to pop, so nothing gets logged
+	unit.PopStdin()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, logger.Entries())
+}
+
+func TestWithLoggerIsCopiedToChildPipes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	logger := pipe.NewMemoryLogger()
+	unit := pipe.NewPipe(pipe.WithLogger(logger))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.NewChildPipe().RunNamedCommand("child", func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, logger.Entries(), 2)
+}
+
+func TestLogEntryGetCopesWithMissingKeys(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.LogEntry{KeyVals: []interface{}{"command", "ls", "dangling"}}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, okay := unit.Get("dangling")
+	_, missing := unit.Get("status")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, okay)
+	assert.False(t, missing)
+}
@@ -0,0 +1,190 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"fmt"
+	"sync"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// OutputLimitPolicy tells WithMaxOutput what to do when a PipeCommand
+// writes more than it is allowed to.
+type OutputLimitPolicy int
+
+const (
+	// TruncateOutput quietly throws away anything written after the
+	// limit is reached. The PipeCommand is not told.
+	TruncateOutput OutputLimitPolicy = iota
+
+	// TruncateOutputWithMarker throws away anything written after the
+	// limit is reached, and writes a one-line marker to say so.
+	TruncateOutputWithMarker
+
+	// FailOnOutputLimit stops accepting writes once the limit is
+	// reached. Writes return an ErrOutputLimitExceeded, and so does the
+	// PipeCommand, with the status code StatusOutputLimitExceeded.
+	FailOnOutputLimit
+)
+
+// StatusOutputLimitExceeded is the status code returned when a
+// PipeCommand writes more than WithMaxOutput allows, and the policy is
+// FailOnOutputLimit.
+//
+// It is the status code that a UNIX shell reports when a process is
+// killed for writing to a pipe that nobody is reading any more.
+const StatusOutputLimitExceeded = 141
+
+// WithMaxOutput returns a PipeOption that limits how many bytes each
+// PipeCommand can write to the pipe's Stdout and Stderr. A limit of 0
+// means no limit.
+//
+// The limits apply to each PipeCommand separately, and are enforced by
+// middleware, so the PipeCommands must be run by RunCommand. If the
+// pipe's Stdout and Stderr are the same, the Stdout limit applies to
+// both of them.
+//
+// With TruncateOutputWithMarker, the marker looks like this:
+//
+//	[output truncated: exceeded 1024 bytes]
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithMaxOutput(stdoutBytes, stderrBytes int64, policy OutputLimitPolicy) PipeOption {
+	return WithMiddleware(func(name string, next PipeCommand) PipeCommand {
+		return func(p *Pipe) (int, error) {
+			stdout := &outputLimiter{command: name, stream: "stdout", limit: stdoutBytes, policy: policy}
+			stderr := &outputLimiter{command: name, stream: "stderr", limit: stderrBytes, policy: policy}
+
+			restore := interceptOutput(p, stdout.intercept, stderr.intercept)
+			statusCode, err := next(p)
+			restore()
+
+			// did the command go over its limit?
+			if policy == FailOnOutputLimit {
+				for _, limiter := range []*outputLimiter{stdout, stderr} {
+					if limiter.exceeded {
+						return StatusOutputLimitExceeded, limiter.err()
+					}
+				}
+			}
+
+			// all done
+			return statusCode, err
+		}
+	})
+}
+
+// outputLimiter counts the bytes written to one of the pipe's outputs
+type outputLimiter struct {
+	mu sync.Mutex
+
+	command string
+	stream  string
+	limit   int64
+	policy  OutputLimitPolicy
+
+	written  int64
+	exceeded bool
+
+	// the last byte that we let through
+	lastByte byte
+}
+
+// intercept is an outputInterceptor that enforces our limit
+func (l *outputLimiter) intercept(next ioextra.TextReaderWriter) func(b []byte) (int, error) {
+	return func(b []byte) (int, error) {
+		// are we limiting this stream at all?
+		if l.limit <= 0 {
+			return next.Write(b)
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// how much of b can we write?
+		allowed := l.limit - l.written
+		if int64(len(b)) <= allowed {
+			return l.write(next, b)
+		}
+
+		// we have gone over the limit
+		n, err := l.write(next, b[:allowed])
+		if err != nil {
+			return n, err
+		}
+
+		firstTime := !l.exceeded
+		l.exceeded = true
+
+		switch l.policy {
+		case TruncateOutputWithMarker:
+			if firstTime {
+				marker := fmt.Sprintf("[output truncated: exceeded %d bytes]\n", l.limit)
+				if l.written > 0 && l.lastByte != '\n' {
+					marker = "\n" + marker
+				}
+				next.WriteString(marker)
+			}
+			return len(b), nil
+		case FailOnOutputLimit:
+			return n, l.err()
+		default:
+			return len(b), nil
+		}
+	}
+}
+
+// write passes b through to next, and keeps track of what was written
+func (l *outputLimiter) write(next ioextra.TextReaderWriter, b []byte) (int, error) {
+	n, err := next.Write(b)
+	l.written += int64(n)
+	if n > 0 {
+		l.lastByte = b[n-1]
+	}
+
+	return n, err
+}
+
+// err returns the error that describes going over our limit
+func (l *outputLimiter) err() error {
+	return ErrOutputLimitExceeded{l.command, l.stream, l.limit}
+}
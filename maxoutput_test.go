@@ -0,0 +1,191 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// chattyCommand writes the given lines to the pipe's Stdout, and "oops"
+// to its Stderr
+func chattyCommand(lines ...string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		for _, line := range lines {
+			p.Stdout.WriteString(line)
+			p.Stdout.WriteRune('\n')
+		}
+		p.Stderr.WriteString("oops\n")
+
+		return pipe.StatusOkay, nil
+	}
+}
+
+func TestWithMaxOutputEnforcesTheLimits(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		option             pipe.PipeOption
+		expectedStdout     string
+		expectedStderr     string
+		expectedStatusCode int
+		expectedErr        error
+	}{
+		{
+			name:           "output under the limits is untouched",
+			option:         pipe.WithMaxOutput(100, 100, pipe.FailOnOutputLimit),
+			expectedStdout: "hello\nworld\n",
+			expectedStderr: "oops\n",
+		},
+		{
+			name:           "a limit of 0 means no limit",
+			option:         pipe.WithMaxOutput(0, 0, pipe.FailOnOutputLimit),
+			expectedStdout: "hello\nworld\n",
+			expectedStderr: "oops\n",
+		},
+		{
+			name:           "TruncateOutput quietly drops the rest",
+			option:         pipe.WithMaxOutput(8, 2, pipe.TruncateOutput),
+			expectedStdout: "hello\nwo",
+			expectedStderr: "oo",
+		},
+		{
+			name:           "TruncateOutputWithMarker adds a marker",
+			option:         pipe.WithMaxOutput(8, 0, pipe.TruncateOutputWithMarker),
+			expectedStdout: "hello\nwo\n[output truncated: exceeded 8 bytes]\n",
+			expectedStderr: "oops\n",
+		},
+		{
+			name:           "TruncateOutputWithMarker does not add a blank line",
+			option:         pipe.WithMaxOutput(6, 0, pipe.TruncateOutputWithMarker),
+			expectedStdout: "hello\n[output truncated: exceeded 6 bytes]\n",
+			expectedStderr: "oops\n",
+		},
+		{
+			name:               "FailOnOutputLimit returns an error",
+			option:             pipe.WithMaxOutput(100, 3, pipe.FailOnOutputLimit),
+			expectedStdout:     "hello\nworld\n",
+			expectedStderr:     "oop",
+			expectedStatusCode: pipe.StatusOutputLimitExceeded,
+			expectedErr:        pipe.ErrOutputLimitExceeded{"chattyCommand", "stderr", 3},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe(testCase.option)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(chattyCommand("hello", "world"))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			statusCode, err := unit.StatusError()
+			assert.Equal(t, testCase.expectedStatusCode, statusCode)
+			assert.Equal(t, testCase.expectedErr, err)
+			assert.Equal(t, testCase.expectedStdout, unit.Stdout.String())
+			assert.Equal(t, testCase.expectedStderr, unit.Stderr.String())
+		})
+	}
+}
+
+func TestWithMaxOutputFailsWritesOverTheLimit(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithMaxOutput(5, 0, pipe.FailOnOutputLimit))
+	var writeErrs []error
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("runaway", func(p *pipe.Pipe) (int, error) {
+		for i := 0; i < 3; i++ {
+			_, err := p.Stdout.WriteString("abc")
+			writeErrs = append(writeErrs, err)
+		}
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	expectedErr := pipe.ErrOutputLimitExceeded{"runaway", "stdout", 5}
+	assert.Equal(t, []error{nil, expectedErr, expectedErr}, writeErrs)
+	assert.Equal(t, expectedErr, unit.Error())
+	assert.Equal(t, "abcab", unit.Stdout.String())
+}
+
+func TestWithMaxOutputAppliesToEachCommandSeparately(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithMaxOutput(10, 0, pipe.FailOnOutputLimit))
+	line := strings.Repeat("x", 7)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(chattyCommand(line))
+	unit.RunCommand(chattyCommand(line))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, line+"\n"+line+"\n", unit.Stdout.String())
+}
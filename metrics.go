@@ -0,0 +1,383 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics is the interface that WithMetrics needs from your metrics
+// library.
+type Metrics interface {
+	// ObserveCommand is called each time that a PipeCommand finishes
+	ObserveCommand(m CommandMetrics)
+}
+
+// CommandMetrics describes a single run of a PipeCommand.
+type CommandMetrics struct {
+	// Command is the name of the PipeCommand, as seen by the middleware
+	Command string
+
+	// StatusCode is the status code that the PipeCommand returned
+	StatusCode int
+
+	// Duration is how long the PipeCommand took to run
+	Duration time.Duration
+
+	// BytesRead is how many bytes the PipeCommand read from Stdin
+	BytesRead int64
+
+	// StdoutBytes is how many bytes the PipeCommand wrote to Stdout
+	StdoutBytes int64
+
+	// StderrBytes is how many bytes the PipeCommand wrote to Stderr
+	StderrBytes int64
+}
+
+// WithMetrics returns a PipeOption that reports on every PipeCommand
+// that the pipe runs via RunCommand.
+//
+// PipeCommands that run on child pipes (see NewChildPipe) are not
+// reported on their own, so that their bytes are not counted twice.
+// They are part of the parent pipe's PipeCommand.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithMetrics(metrics Metrics) PipeOption {
+	return WithMiddleware(func(name string, next PipeCommand) PipeCommand {
+		return func(p *Pipe) (int, error) {
+			// the parent pipe will report this for us
+			if p.isChildPipe() {
+				return next(p)
+			}
+
+			counts := countIO(p)
+			start := time.Now()
+
+			statusCode, err := next(p)
+
+			duration := time.Since(start)
+			counts.stop()
+
+			metrics.ObserveCommand(CommandMetrics{
+				Command:     name,
+				StatusCode:  statusCode,
+				Duration:    duration,
+				BytesRead:   counts.bytesRead(),
+				StdoutBytes: counts.stdoutBytes(),
+				StderrBytes: counts.stderrBytes(),
+			})
+
+			// all done
+			return statusCode, err
+		}
+	})
+}
+
+// ================================================================
+//
+// MemoryMetrics
+//
+// ----------------------------------------------------------------
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the
+// histogram buckets that NewMemoryMetrics uses by default.
+//
+// They are the same as the Prometheus client library's default buckets.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MemoryMetrics is a Metrics that keeps running totals in memory, for
+// each PipeCommand.
+//
+// It can write them out in the Prometheus text exposition format, using
+// WriteText, or you can serve them over HTTP, using Handler.
+type MemoryMetrics struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// buckets holds the upper bounds of our duration histogram buckets
+	buckets []float64
+
+	// commands holds the totals for each command
+	commands map[string]*CommandStats
+}
+
+// CommandStats holds the running totals for a single PipeCommand.
+type CommandStats struct {
+	// Command is the name of the PipeCommand
+	Command string
+
+	// Invocations is how many times the PipeCommand has run
+	Invocations uint64
+
+	// StatusCodes counts how many times each status code was returned
+	StatusCodes map[int]uint64
+
+	// DurationBuckets counts how many runs took no longer than the
+	// matching entry in the MemoryMetrics' buckets
+	DurationBuckets []uint64
+
+	// TotalDuration is the time taken by all of the runs added together
+	TotalDuration time.Duration
+
+	// BytesRead is the total number of bytes read from Stdin
+	BytesRead int64
+
+	// StdoutBytes is the total number of bytes written to Stdout
+	StdoutBytes int64
+
+	// StderrBytes is the total number of bytes written to Stderr
+	StderrBytes int64
+}
+
+// NewMemoryMetrics creates a new, empty MemoryMetrics. buckets are the
+// upper bounds, in seconds, of the duration histogram buckets. If you
+// don't give us any, we use DefaultDurationBuckets.
+func NewMemoryMetrics(buckets ...float64) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+
+	retval := MemoryMetrics{
+		buckets:  append([]float64(nil), buckets...),
+		commands: map[string]*CommandStats{},
+	}
+	sort.Float64s(retval.buckets)
+
+	// all done
+	return &retval
+}
+
+// ObserveCommand adds the given run to our totals. It is part of the
+// Metrics interface.
+func (m *MemoryMetrics) ObserveCommand(run CommandMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.commands[run.Command]
+	if !ok {
+		stats = &CommandStats{
+			Command:         run.Command,
+			StatusCodes:     map[int]uint64{},
+			DurationBuckets: make([]uint64, len(m.buckets)),
+		}
+		m.commands[run.Command] = stats
+	}
+
+	stats.Invocations++
+	stats.StatusCodes[run.StatusCode]++
+	stats.TotalDuration += run.Duration
+	stats.BytesRead += run.BytesRead
+	stats.StdoutBytes += run.StdoutBytes
+	stats.StderrBytes += run.StderrBytes
+
+	seconds := run.Duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			stats.DurationBuckets[i]++
+		}
+	}
+}
+
+// Buckets returns the upper bounds, in seconds, of our duration
+// histogram buckets.
+func (m *MemoryMetrics) Buckets() []float64 {
+	return append([]float64(nil), m.buckets...)
+}
+
+// Commands returns a copy of the totals for every PipeCommand that we
+// have seen, sorted by name.
+func (m *MemoryMetrics) Commands() []CommandStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	retval := make([]CommandStats, 0, len(m.commands))
+	for _, stats := range m.commands {
+		statsCopy := *stats
+		statsCopy.StatusCodes = make(map[int]uint64, len(stats.StatusCodes))
+		for code, count := range stats.StatusCodes {
+			statsCopy.StatusCodes[code] = count
+		}
+		statsCopy.DurationBuckets = append([]uint64(nil), stats.DurationBuckets...)
+
+		retval = append(retval, statsCopy)
+	}
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Command < retval[j].Command
+	})
+
+	return retval
+}
+
+// Reset throws away all of our totals.
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.commands = map[string]*CommandStats{}
+}
+
+// WriteText writes our totals to w, in the Prometheus text exposition
+// format.
+func (m *MemoryMetrics) WriteText(w io.Writer) error {
+	commands := m.Commands()
+	out := bufio.NewWriter(w)
+
+	writeFamily := func(name, metricType, help string, series func(stats CommandStats)) {
+		fmt.Fprintf(out, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(out, "# TYPE %s %s\n", name, metricType)
+		for _, stats := range commands {
+			series(stats)
+		}
+	}
+
+	writeFamily(
+		"pipe_command_invocations_total", "counter",
+		"How many times each PipeCommand has run.",
+		func(stats CommandStats) {
+			fmt.Fprintf(out, "pipe_command_invocations_total{command=%s} %d\n", quoteLabel(stats.Command), stats.Invocations)
+		},
+	)
+
+	writeFamily(
+		"pipe_command_status_codes_total", "counter",
+		"How many times each PipeCommand has returned each status code.",
+		func(stats CommandStats) {
+			codes := make([]int, 0, len(stats.StatusCodes))
+			for code := range stats.StatusCodes {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+
+			for _, code := range codes {
+				fmt.Fprintf(
+					out,
+					"pipe_command_status_codes_total{command=%s,status_code=\"%d\"} %d\n",
+					quoteLabel(stats.Command), code, stats.StatusCodes[code],
+				)
+			}
+		},
+	)
+
+	writeFamily(
+		"pipe_command_duration_seconds", "histogram",
+		"How long each PipeCommand took to run.",
+		func(stats CommandStats) {
+			command := quoteLabel(stats.Command)
+			for i, bound := range m.buckets {
+				fmt.Fprintf(
+					out,
+					"pipe_command_duration_seconds_bucket{command=%s,le=\"%s\"} %d\n",
+					command, formatFloat(bound), stats.DurationBuckets[i],
+				)
+			}
+			fmt.Fprintf(out, "pipe_command_duration_seconds_bucket{command=%s,le=\"+Inf\"} %d\n", command, stats.Invocations)
+			fmt.Fprintf(out, "pipe_command_duration_seconds_sum{command=%s} %s\n", command, formatFloat(stats.TotalDuration.Seconds()))
+			fmt.Fprintf(out, "pipe_command_duration_seconds_count{command=%s} %d\n", command, stats.Invocations)
+		},
+	)
+
+	writeFamily(
+		"pipe_stdin_bytes_read_total", "counter",
+		"How many bytes each PipeCommand has read from Stdin.",
+		func(stats CommandStats) {
+			fmt.Fprintf(out, "pipe_stdin_bytes_read_total{command=%s} %d\n", quoteLabel(stats.Command), stats.BytesRead)
+		},
+	)
+
+	writeFamily(
+		"pipe_stdout_bytes_written_total", "counter",
+		"How many bytes each PipeCommand has written to Stdout.",
+		func(stats CommandStats) {
+			fmt.Fprintf(out, "pipe_stdout_bytes_written_total{command=%s} %d\n", quoteLabel(stats.Command), stats.StdoutBytes)
+		},
+	)
+
+	writeFamily(
+		"pipe_stderr_bytes_written_total", "counter",
+		"How many bytes each PipeCommand has written to Stderr.",
+		func(stats CommandStats) {
+			fmt.Fprintf(out, "pipe_stderr_bytes_written_total{command=%s} %d\n", quoteLabel(stats.Command), stats.StderrBytes)
+		},
+	)
+
+	// all done
+	return out.Flush()
+}
+
+// Handler returns an http.Handler that serves our totals in the
+// Prometheus text exposition format. Use it for a local /metrics
+// endpoint:
+//
+//	http.Handle("/metrics", metrics.Handler())
+func (m *MemoryMetrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteText(w)
+	})
+}
+
+// quoteLabel returns the given label value, quoted and escaped for the
+// Prometheus text exposition format
+func quoteLabel(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// formatFloat returns f in the Prometheus text exposition format
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
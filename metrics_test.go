@@ -0,0 +1,313 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMetricsObservesEveryCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	metrics := pipe.NewMemoryMetrics()
+	unit := pipe.NewPipe(pipe.WithMetrics(metrics))
+	failing := func(p *pipe.Pipe) (int, error) {
+		p.Stderr.WriteString("oops\n")
+		return 2, errors.New("oops")
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.SetStdinFromString("hello\n")
+	unit.RunNamedCommand("upper", upperCaseStep)
+	unit.SetStdinFromString("world\n")
+	unit.RunNamedCommand("upper", upperCaseStep)
+	unit.RunNamedCommand("fail", failing)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	commands := metrics.Commands()
+	assert.Len(t, commands, 2)
+
+	assert.Equal(t, "fail", commands[0].Command)
+	assert.Equal(t, uint64(1), commands[0].Invocations)
+	assert.Equal(t, map[int]uint64{2: 1}, commands[0].StatusCodes)
+	assert.Equal(t, int64(5), commands[0].StderrBytes)
+
+	assert.Equal(t, "upper", commands[1].Command)
+	assert.Equal(t, uint64(2), commands[1].Invocations)
+	assert.Equal(t, map[int]uint64{pipe.StatusOkay: 2}, commands[1].StatusCodes)
+	assert.Equal(t, int64(12), commands[1].BytesRead)
+	assert.Equal(t, int64(14), commands[1].StdoutBytes)
+	assert.Equal(t, int64(0), commands[1].StderrBytes)
+	assert.Len(t, commands[1].DurationBuckets, len(pipe.DefaultDurationBuckets))
+}
+
+func TestWithMetricsOnlyCountsBytesTakenFromStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	metrics := pipe.NewMemoryMetrics()
+	unit := pipe.NewPipe(pipe.WithMetrics(metrics))
+	unit.SetStdinFromString("hello\n")
+
+	// String() leaves the buffer's contents where they are
+	peekThenRead := func(p *pipe.Pipe) (int, error) {
+		peeked := p.Stdin.String() + p.Stdin.TrimmedString()
+		p.Stdin.ParseInt()
+		read, _ := p.StdinBytes()
+		if peeked != "hello\nhello" || string(read) != "hello\n" {
+			return pipe.StatusNotOkay, nil
+		}
+		return pipe.StatusOkay, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("peek", peekThenRead)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	commands := metrics.Commands()
+	assert.Len(t, commands, 1)
+	assert.Equal(t, map[int]uint64{pipe.StatusOkay: 1}, commands[0].StatusCodes)
+	assert.Equal(t, int64(6), commands[0].BytesRead)
+}
+
+func TestMemoryMetricsFillsTheDurationBuckets(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewMemoryMetrics(1, 0.1)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for _, duration := range []time.Duration{
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		500 * time.Millisecond,
+		2 * time.Second,
+	} {
+		unit.ObserveCommand(pipe.CommandMetrics{Command: "sleep", Duration: duration})
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	commands := unit.Commands()
+	assert.Equal(t, []float64{0.1, 1}, unit.Buckets())
+	assert.Equal(t, []uint64{2, 3}, commands[0].DurationBuckets)
+	assert.Equal(t, 2650*time.Millisecond, commands[0].TotalDuration)
+}
+
+func TestMemoryMetricsWriteTextUsesThePrometheusFormat(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewMemoryMetrics(0.1, 1)
+	unit.ObserveCommand(pipe.CommandMetrics{
+		Command:     "grep",
+		StatusCode:  0,
+		Duration:    50 * time.Millisecond,
+		BytesRead:   100,
+		StdoutBytes: 20,
+	})
+	unit.ObserveCommand(pipe.CommandMetrics{
+		Command:     "grep",
+		StatusCode:  1,
+		Duration:    250 * time.Millisecond,
+		BytesRead:   100,
+		StderrBytes: 7,
+	})
+
+	expectedResult := strings.Join([]string{
+		`# HELP pipe_command_invocations_total How many times each PipeCommand has run.`,
+		`# TYPE pipe_command_invocations_total counter`,
+		`pipe_command_invocations_total{command="grep"} 2`,
+		`# HELP pipe_command_status_codes_total How many times each PipeCommand has returned each status code.`,
+		`# TYPE pipe_command_status_codes_total counter`,
+		`pipe_command_status_codes_total{command="grep",status_code="0"} 1`,
+		`pipe_command_status_codes_total{command="grep",status_code="1"} 1`,
+		`# HELP pipe_command_duration_seconds How long each PipeCommand took to run.`,
+		`# TYPE pipe_command_duration_seconds histogram`,
+		`pipe_command_duration_seconds_bucket{command="grep",le="0.1"} 1`,
+		`pipe_command_duration_seconds_bucket{command="grep",le="1"} 2`,
+		`pipe_command_duration_seconds_bucket{command="grep",le="+Inf"} 2`,
+		`pipe_command_duration_seconds_sum{command="grep"} 0.3`,
+		`pipe_command_duration_seconds_count{command="grep"} 2`,
+		`# HELP pipe_stdin_bytes_read_total How many bytes each PipeCommand has read from Stdin.`,
+		`# TYPE pipe_stdin_bytes_read_total counter`,
+		`pipe_stdin_bytes_read_total{command="grep"} 200`,
+		`# HELP pipe_stdout_bytes_written_total How many bytes each PipeCommand has written to Stdout.`,
+		`# TYPE pipe_stdout_bytes_written_total counter`,
+		`pipe_stdout_bytes_written_total{command="grep"} 20`,
+		`# HELP pipe_stderr_bytes_written_total How many bytes each PipeCommand has written to Stderr.`,
+		`# TYPE pipe_stderr_bytes_written_total counter`,
+		`pipe_stderr_bytes_written_total{command="grep"} 7`,
+		``,
+	}, "\n")
+
+	var buf bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.WriteText(&buf)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, buf.String())
+}
+
+func TestMemoryMetricsWriteTextEscapesCommandNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewMemoryMetrics()
+	unit.ObserveCommand(pipe.CommandMetrics{Command: "say \"hi\"\\\n"})
+
+	var buf bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.WriteText(&buf)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Contains(t, buf.String(), `pipe_command_invocations_total{command="say \"hi\"\\\n"} 1`)
+}
+
+func TestMemoryMetricsHandlerServesTheMetrics(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewMemoryMetrics()
+	unit.ObserveCommand(pipe.CommandMetrics{Command: "ls"})
+
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	response := httptest.NewRecorder()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.Handler().ServeHTTP(response, request)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Contains(t, response.Body.String(), `pipe_command_invocations_total{command="ls"} 1`)
+}
+
+func TestMemoryMetricsResetThrowsAwayTheTotals(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewMemoryMetrics()
+	unit.ObserveCommand(pipe.CommandMetrics{Command: "ls"})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.Reset()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, unit.Commands())
+}
+
+func TestWithMetricsCountsChildPipeOutputOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	metrics := pipe.NewMemoryMetrics()
+	unit := pipe.NewPipe(pipe.WithMetrics(metrics))
+	unit.SetStdinFromString("a b c")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("xargs", bracketArgs())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	stats := metrics.Commands()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "xargs", stats[0].Command)
+	assert.Equal(t, uint64(1), stats[0].Invocations)
+	assert.Equal(t, int64(len("[a]\n[b]\n[c]\n")), stats[0].StdoutBytes)
+}
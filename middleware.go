@@ -0,0 +1,125 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Middleware is the signature of any function that wraps a PipeCommand.
+//
+// RunCommand passes every PipeCommand (and its name) into the pipe's
+// middleware before it runs the command. Your middleware returns the
+// PipeCommand to run instead: usually one that does some work before
+// and/or after calling next.
+type Middleware = func(name string, next PipeCommand) PipeCommand
+
+// WithMiddleware returns a PipeOption that adds the given middleware to
+// the pipe.
+//
+// Middleware runs in the order that it was added: the first middleware
+// added is the outermost one.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithMiddleware(middleware ...Middleware) PipeOption {
+	return func(p *Pipe) (int, error) {
+		p.middleware = append(p.middleware, middleware...)
+		return StatusOkay, nil
+	}
+}
+
+// CommandName returns a human-readable name for the given PipeCommand.
+//
+// It uses the name of the Golang function, without the package path.
+// Anonymous functions are named after the function that created them.
+// For example, the PipeCommand returned by WithEnvFromMap is named
+// "WithEnvFromMap".
+func CommandName(c PipeCommand) string {
+	// do we have a command to inspect?
+	if c == nil {
+		return ""
+	}
+
+	fn := runtime.FuncForPC(reflect.ValueOf(c).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+
+	// strip off the package path and the package name
+	if pos := strings.LastIndex(name, "/"); pos >= 0 {
+		name = name[pos+1:]
+	}
+	if pos := strings.Index(name, "."); pos >= 0 {
+		name = name[pos+1:]
+	}
+
+	// method values have a suffix
+	name = strings.TrimSuffix(name, "-fm")
+
+	// anonymous functions are called things like "NewThing.func1.2"
+	parts := strings.Split(name, ".")
+	for len(parts) > 1 && isAnonymousFuncName(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+
+	// all done
+	return strings.Join(parts, ".")
+}
+
+// isAnonymousFuncName returns true if the given part of a function name
+// was made up by the Golang compiler
+func isAnonymousFuncName(part string) bool {
+	part = strings.TrimPrefix(part, "func")
+	if part == "" {
+		return false
+	}
+
+	for _, c := range part {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
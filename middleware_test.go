@@ -0,0 +1,174 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMiddlewareRunsMiddlewareInTheOrderItWasAdded(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	trace := []string{}
+	tracer := func(label string) pipe.Middleware {
+		return func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+			return func(p *pipe.Pipe) (int, error) {
+				trace = append(trace, label+" before")
+				statusCode, err := next(p)
+				trace = append(trace, label+" after")
+				return statusCode, err
+			}
+		}
+	}
+	unit := pipe.NewPipe(pipe.WithMiddleware(tracer("outer"), tracer("inner")))
+
+	expectedResult := []string{
+		"outer before",
+		"inner before",
+		"command",
+		"inner after",
+		"outer after",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+		trace = append(trace, "command")
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, trace)
+}
+
+func TestWithMiddlewareAppliesToLaterOptions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	names := []string{}
+	middleware := func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+		names = append(names, name)
+		return next
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	pipe.NewPipe(pipe.WithMiddleware(middleware), pipe.WithEmptyEnv)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"WithEmptyEnv"}, names)
+}
+
+func TestMiddlewareCanReplaceTheCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	fake := pipetest.NewFake().WithStdout("replaced")
+	middleware := func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+		return fake.Run
+	}
+	unit := pipe.NewPipe(pipe.WithMiddleware(middleware))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.AttachOsStdout)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "replaced", unit.Stdout.String())
+}
+
+func exampleCommandFactory() pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	t.Parallel()
+
+	fake := pipetest.NewFake()
+
+	testCases := []struct {
+		name           string
+		command        pipe.PipeCommand
+		expectedResult string
+	}{
+		{"nil command", nil, ""},
+		{"package function", pipe.AttachOsStdin, "AttachOsStdin"},
+		{"closure from factory", pipe.WithEnvFromMap(nil), "WithEnvFromMap"},
+		{"closure from test factory", exampleCommandFactory(), "exampleCommandFactory"},
+		{"method value", fake.Run, "(*Fake).Run"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			// ----------------------------------------------------------------
+			// perform the change
+
+			actualResult := pipe.CommandName(testCase.command)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, testCase.expectedResult, actualResult)
+		})
+	}
+}
@@ -51,6 +51,7 @@ import (
 // PipeCommand.
 func AttachOsStdin(p *Pipe) (int, error) {
 	p.Stdin = ioextra.NewTextFile(os.Stdin)
+	p.logDebug("pipe stream attached", "stream", "stdin", "target", "os.Stdin")
 	return StatusOkay, nil
 }
 
@@ -60,6 +61,7 @@ func AttachOsStdin(p *Pipe) (int, error) {
 // PipeCommand.
 func AttachOsStdout(p *Pipe) (int, error) {
 	p.Stdout = ioextra.NewTextFile(os.Stdout)
+	p.logDebug("pipe stream attached", "stream", "stdout", "target", "os.Stdout")
 	return StatusOkay, nil
 }
 
@@ -69,5 +71,6 @@ func AttachOsStdout(p *Pipe) (int, error) {
 // PipeCommand.
 func AttachOsStderr(p *Pipe) (int, error) {
 	p.Stderr = ioextra.NewTextFile(os.Stderr)
+	p.logDebug("pipe stream attached", "stream", "stderr", "target", "os.Stderr")
 	return StatusOkay, nil
 }
@@ -0,0 +1,101 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// BufferFactory is the signature of any function that creates new, empty
+// buffers for the pipe's Stdout and Stderr.
+type BufferFactory = func() ioextra.TextReaderWriter
+
+// WithBufferFactory returns a PipeOption that makes the pipe use the
+// given factory whenever it needs a new Stdout or Stderr buffer.
+//
+// If the pipe's Stdout and/or Stderr are still empty, in-memory buffers,
+// they are replaced straight away.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithBufferFactory(factory BufferFactory) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		p.newBuffer = factory
+
+		// replace any buffers that nothing has used yet
+		if isEmptyTextBuffer(p.Stdout) {
+			p.SetNewStdout()
+		}
+		if isEmptyTextBuffer(p.Stderr) {
+			p.SetNewStderr()
+		}
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// WithSpillBuffers returns a PipeOption that makes the pipe use
+// SpillBuffers for its Stdout and Stderr. Each one keeps up to maxMemory
+// bytes in memory, and then spills into a temporary file in tempDir.
+// If tempDir is empty, it uses os.TempDir().
+//
+// Call Pipe.Close when you have finished with the pipe, to remove any
+// temporary files.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithSpillBuffers(maxMemory int64, tempDir string) PipeOption {
+	return WithBufferFactory(func() ioextra.TextReaderWriter {
+		return NewSpillBuffer(maxMemory, tempDir)
+	})
+}
+
+// isEmptyTextBuffer returns true if rw is an ioextra.TextBuffer with
+// nothing in it
+func isEmptyTextBuffer(rw ioextra.TextReaderWriter) bool {
+	buf, ok := rw.(*ioextra.TextBuffer)
+	return ok && buf.Len() == 0
+}
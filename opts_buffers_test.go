@@ -0,0 +1,360 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSpillBuffersUsesSpillBuffersForStdoutAndStderr(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithSpillBuffers(16, dir))
+	defer unit.Close()
+
+	unit.Stdout.WriteString(strings.Repeat("x", 100))
+	unit.Stderr.WriteString("small")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stdout)
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stderr)
+	assert.Equal(t, strings.Repeat("x", 100), unit.Stdout.String())
+	assert.Equal(t, "small", unit.Stderr.String())
+	assert.Len(t, spillFiles(t, dir), 1)
+
+	// new buffers are spill buffers too
+	unit.SetNewStdout()
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stdout)
+}
+
+func TestPipeCloseRemovesSpilledFiles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewPipe(pipe.WithSpillBuffers(4, dir))
+	unit.Stdout.WriteString("spilled stdout")
+	unit.Stderr.WriteString("spilled stderr")
+	unit.PushStdout(pipe.NewSpillBuffer(4, dir))
+	unit.Stdout.WriteString("spilled again")
+	assert.Len(t, spillFiles(t, dir), 3)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err = unit.Close()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Empty(t, spillFiles(t, dir))
+}
+
+func TestPipeResetBuffersRemovesSpilledFiles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewPipe(pipe.WithSpillBuffers(4, dir))
+	unit.Stdout.WriteString("spilled stdout")
+	assert.Len(t, spillFiles(t, dir), 1)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.ResetBuffers()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, spillFiles(t, dir))
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stdout)
+}
+
+func TestPipeClosesSpillBuffersThatItThrowsAway(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewPipe(pipe.WithSpillBuffers(4, dir))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for i := 0; i < 5; i++ {
+		unit.Stdout.WriteString("spilled stdout")
+		unit.Stderr.WriteString("spilled stderr")
+		unit.MoveStdoutToStdin()
+		unit.SetNewStderr()
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// only the last Stdin is still on disk
+	assert.Len(t, spillFiles(t, dir), 1)
+	assert.Equal(t, "spilled stdout", unit.Stdin.String())
+
+	unit.SetNewStdin()
+	assert.Empty(t, spillFiles(t, dir))
+
+	err = unit.Close()
+	assert.Nil(t, err)
+	assert.Empty(t, spillFiles(t, dir))
+}
+
+func TestPipeLeavesSpillBuffersThatItIsStillUsing(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewPipe(pipe.WithSpillBuffers(4, dir))
+	defer unit.Close()
+
+	unit.Stdout.WriteString("spilled stdout")
+	unit.PushStdout(unit.Stdout)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.SetNewStdout()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, spillFiles(t, dir), 1)
+	unit.PopStdout()
+	assert.Equal(t, "spilled stdout", unit.Stdout.String())
+}
+
+func TestPipeCloseRemovesSpilledFilesFromNestedCommands(t *testing.T) {
+	t.Parallel()
+
+	// writeBoth writes to Stdout and Stderr, and fails the first time
+	// that it is called
+	writeBoth := func() pipe.PipeCommand {
+		calls := 0
+		return func(p *pipe.Pipe) (int, error) {
+			calls++
+			p.Stdout.WriteString("stdout\n")
+			p.Stderr.WriteString("stderr\n")
+			if calls == 1 {
+				return pipe.StatusNotOkay, nil
+			}
+			return pipe.StatusOkay, nil
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		command pipe.PipeCommand
+	}{
+		{
+			name: "Xargs",
+			command: pipe.Xargs(
+				func(args []string) pipe.PipeCommand {
+					return func(p *pipe.Pipe) (int, error) {
+						p.Stdout.WriteString(strings.Join(args, " ") + "\n")
+						p.Stderr.WriteString("stderr\n")
+						return pipe.StatusOkay, nil
+					}
+				},
+				pipe.XargsOptions{MaxArgs: 1},
+			),
+		},
+		{
+			name:    "Retry",
+			command: pipe.Retry(writeBoth(), pipe.RetryPolicy{Attempts: 3}),
+		},
+		{
+			name:    "Timeout",
+			command: pipe.Timeout(time.Second, writeBoth()),
+		},
+		{
+			name: "Timeout that times out",
+			command: pipe.Timeout(10*time.Millisecond, func(p *pipe.Pipe) (int, error) {
+				<-p.Context().Done()
+				p.Stdout.WriteString("too late\n")
+				return pipe.StatusNotOkay, nil
+			}),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			dir, err := ioutil.TempDir("", "spillbuffer-test-")
+			assert.Nil(t, err)
+			defer os.RemoveAll(dir)
+
+			unit := pipe.NewPipe(pipe.WithSpillBuffers(0, dir))
+			unit.SetStdinFromString("a b c\n")
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(testCase.command)
+			err = unit.Close()
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err)
+			assert.Eventually(
+				t,
+				func() bool { return len(spillFiles(t, dir)) == 0 },
+				time.Second,
+				10*time.Millisecond,
+			)
+		})
+	}
+}
+
+func TestPipeCloseCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.Close()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+}
+
+func TestWithBufferFactoryLeavesUsedBuffersAlone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.Stdout.WriteString("keep me")
+	origStdout := unit.Stdout
+	factory := func() ioextra.TextReaderWriter {
+		return pipe.NewSpillBuffer(1024, "")
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.WithBufferFactory(factory))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, origStdout, unit.Stdout)
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stderr)
+}
+
+func TestNewChildPipeCopiesTheBufferFactory(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	parent := pipe.NewPipe(pipe.WithSpillBuffers(1024, ""))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := parent.NewChildPipe()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stdout)
+	assert.IsType(t, &pipe.SpillBuffer{}, unit.Stderr)
+}
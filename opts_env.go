@@ -0,0 +1,300 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	envish "github.com/ganbarodigital/go_envish/v4"
+)
+
+// WithEmptyEnv gives the pipe an environment that starts with no
+// variables at all.
+//
+// Anything that your PipeCommands set is kept inside the pipe, and never
+// leaks into your program's environment.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithEmptyEnv(p *Pipe) (int, error) {
+	p.Env, _ = newPipeLocalEnv()
+	return StatusOkay, nil
+}
+
+// WithEnvFromMap returns a PipeOption that gives the pipe an environment
+// that starts with (only) the given variables.
+//
+// The pipe takes a copy of the map. Anything that your PipeCommands set is
+// kept inside the pipe, and never leaks into your program's environment.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithEnvFromMap(vars map[string]string) PipeOption {
+	return func(p *Pipe) (int, error) {
+		env, localEnv := newPipeLocalEnv()
+		for key, value := range vars {
+			err := localEnv.Setenv(key, value)
+			if err != nil {
+				return StatusNotOkay, err
+			}
+		}
+
+		p.Env = env
+		return StatusOkay, nil
+	}
+}
+
+// WithEnvFromFile returns a PipeOption that gives the pipe an environment
+// that starts with (only) the variables defined in the given .env file.
+//
+// The file uses the common .env format:
+//
+//	# comments and blank lines are ignored
+//	KEY=value
+//	export KEY=value
+//	KEY="double-quoted value, with \n escapes"
+//	KEY='single-quoted value, taken literally'
+//
+// Anything that your PipeCommands set is kept inside the pipe, and never
+// leaks into your program's environment.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithEnvFromFile(filename string) PipeOption {
+	return func(p *Pipe) (int, error) {
+		f, err := os.Open(filename)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+		defer f.Close()
+
+		env, localEnv := newPipeLocalEnv()
+
+		scanner := bufio.NewScanner(f)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+
+			key, value, ok, reason := parseEnvFileLine(scanner.Text())
+			if reason != "" {
+				return StatusNotOkay, ErrParseEnvFile{filename, lineNo, reason}
+			}
+			if !ok {
+				continue
+			}
+
+			err = localEnv.Setenv(key, value)
+			if err != nil {
+				return StatusNotOkay, ErrParseEnvFile{filename, lineNo, err.Error()}
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return StatusNotOkay, err
+		}
+
+		p.Env = env
+		return StatusOkay, nil
+	}
+}
+
+// WithInheritedEnv returns a PipeOption that gives the pipe an environment
+// that starts with a copy of the allowed variables from your program's
+// environment.
+//
+// Each entry in the allowlist is either the name of a variable (e.g.
+// "PATH"), or a prefix followed by a '*' (e.g. "LC_*").
+//
+// We take a copy of the variables when the option runs. Anything that
+// your PipeCommands set is kept inside the pipe, and never leaks into
+// your program's environment.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithInheritedEnv(allowlist ...string) PipeOption {
+	return func(p *Pipe) (int, error) {
+		env, localEnv := newPipeLocalEnv()
+		for _, pair := range os.Environ() {
+			key := envish.GetKeyFromPair(pair)
+			if !isAllowedEnvKey(key, allowlist) {
+				continue
+			}
+
+			err := localEnv.Setenv(key, envish.GetValueFromPair(pair, key))
+			if err != nil {
+				return StatusNotOkay, err
+			}
+		}
+
+		p.Env = env
+		return StatusOkay, nil
+	}
+}
+
+// newPipeLocalEnv creates an OverlayEnv that is backed by a single
+// LocalEnv, instead of the program's environment.
+//
+// The LocalEnv is an exporter, so that its variables are included
+// whenever anyone calls Environ() on the pipe's Env.
+func newPipeLocalEnv() (*envish.OverlayEnv, *envish.LocalEnv) {
+	localEnv := envish.NewLocalEnv(envish.SetAsExporter)
+	env := envish.NewOverlayEnv(
+		[]envish.Expander{
+			localEnv,
+		},
+	)
+
+	return env, localEnv
+}
+
+// isAllowedEnvKey returns true if the given key matches any of the
+// entries in the allowlist.
+func isAllowedEnvKey(key string, allowlist []string) bool {
+	for _, allowed := range allowlist {
+		// special case - is this a prefix match?
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+
+		// general case - exact match
+		if key == allowed {
+			return true
+		}
+	}
+
+	// if we get here, the key is not allowed
+	return false
+}
+
+// parseEnvFileLine extracts the key and value from a single line of
+// a .env file.
+//
+// It returns ok == false for lines that do not define a variable
+// (blank lines and comments), and a non-empty reason if the line cannot
+// be parsed.
+func parseEnvFileLine(line string) (key, value string, ok bool, reason string) {
+	line = strings.TrimSpace(line)
+
+	// skip blank lines and comments
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, ""
+	}
+
+	// shell scripts often export their variables
+	if strings.HasPrefix(line, "export ") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+	}
+
+	pos := strings.Index(line, "=")
+	if pos < 0 {
+		return "", "", false, "missing '='"
+	}
+
+	key = strings.TrimSpace(line[:pos])
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false, "invalid variable name"
+	}
+
+	value = strings.TrimSpace(line[pos+1:])
+	switch {
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", "", false, "unterminated single-quoted value"
+		}
+		value = value[1 : end+1]
+
+	case strings.HasPrefix(value, "\""):
+		var terminated bool
+		value, terminated = unquoteEnvFileValue(value[1:])
+		if !terminated {
+			return "", "", false, "unterminated double-quoted value"
+		}
+
+	default:
+		// unquoted values can have a trailing comment
+		if pos = strings.Index(value, " #"); pos >= 0 {
+			value = strings.TrimSpace(value[:pos])
+		}
+	}
+
+	// all done
+	return key, value, true, ""
+}
+
+// unquoteEnvFileValue processes the escape sequences in a double-quoted
+// value, up to the closing double quote.
+func unquoteEnvFileValue(input string) (string, bool) {
+	var retval strings.Builder
+
+	escaped := false
+	for _, c := range input {
+		if escaped {
+			switch c {
+			case 'n':
+				retval.WriteRune('\n')
+			case 't':
+				retval.WriteRune('\t')
+			case 'r':
+				retval.WriteRune('\r')
+			default:
+				retval.WriteRune(c)
+			}
+			escaped = false
+			continue
+		}
+
+		switch c {
+		case '\\':
+			escaped = true
+		case '"':
+			return retval.String(), true
+		default:
+			retval.WriteRune(c)
+		}
+	}
+
+	// if we get here, we never found the closing quote
+	return "", false
+}
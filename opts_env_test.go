@@ -0,0 +1,242 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"os"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEmptyEnvStartsWithNoVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEmptyEnv)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Empty(t, unit.Env.Environ())
+	assert.Equal(t, "", unit.Env.Getenv("PATH"))
+}
+
+func TestWithEmptyEnvDoesNotLeakIntoProgramEnv(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithEmptyEnv)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.Env.Setenv("TestWithEmptyEnvDoesNotLeakIntoProgramEnv", "leaked")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "leaked", unit.Env.Getenv("TestWithEmptyEnvDoesNotLeakIntoProgramEnv"))
+	_, ok := os.LookupEnv("TestWithEmptyEnvDoesNotLeakIntoProgramEnv")
+	assert.False(t, ok)
+}
+
+func TestWithEnvFromMapStartsWithOnlyTheGivenVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"HOME":    "/home/pipe",
+		"APP_ENV": "test",
+	}
+	expectedResult := []string{
+		"APP_ENV=test",
+		"HOME=/home/pipe",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEnvFromMap(vars))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Env.Environ())
+}
+
+func TestWithEnvFromMapReturnsErrorForInvalidKeys(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"": "no key",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEnvFromMap(vars))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.Error(t, unit.Error())
+}
+
+func TestWithEnvFromFileLoadsTheGivenFile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := []string{
+		"APP_COMMENTED=value",
+		"APP_GREETING=hello\tworld",
+		"APP_LITERAL=hello\\tworld",
+		"APP_MODE=test",
+		"APP_NAME=pipe",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEnvFromFile("testdata/app.env"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Env.Environ())
+}
+
+func TestWithEnvFromFileReturnsErrorForMissingFile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEnvFromFile("testdata/does-not-exist.env"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.True(t, os.IsNotExist(unit.Error()))
+}
+
+func TestWithEnvFromFileReturnsErrorForBrokenFile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := pipe.ErrParseEnvFile{
+		Filename: "testdata/broken.env",
+		LineNo:   2,
+		Reason:   "missing '='",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithEnvFromFile("testdata/broken.env"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.Equal(t, expectedResult, unit.Error())
+}
+
+func TestWithInheritedEnvOnlyCopiesAllowedVariables(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	os.Setenv("TestWithInheritedEnv_ALLOWED", "yes")
+	os.Setenv("TestWithInheritedEnv_PREFIX_ONE", "one")
+	os.Setenv("TestWithInheritedEnv_PREFIX_TWO", "two")
+	os.Setenv("TestWithInheritedEnv_DENIED", "no")
+	defer os.Unsetenv("TestWithInheritedEnv_ALLOWED")
+	defer os.Unsetenv("TestWithInheritedEnv_PREFIX_ONE")
+	defer os.Unsetenv("TestWithInheritedEnv_PREFIX_TWO")
+	defer os.Unsetenv("TestWithInheritedEnv_DENIED")
+
+	expectedResult := []string{
+		"TestWithInheritedEnv_ALLOWED=yes",
+		"TestWithInheritedEnv_PREFIX_ONE=one",
+		"TestWithInheritedEnv_PREFIX_TWO=two",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(
+		pipe.WithInheritedEnv(
+			"TestWithInheritedEnv_ALLOWED",
+			"TestWithInheritedEnv_PREFIX_*",
+		),
+	)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, expectedResult, unit.Env.Environ())
+}
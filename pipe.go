@@ -40,7 +40,9 @@
 package pipe
 
 import (
+	"context"
 	"io"
+	"io/ioutil"
 
 	ioextra "github.com/ganbarodigital/go-ioextra/v2"
 	envish "github.com/ganbarodigital/go_envish/v4"
@@ -74,6 +76,34 @@ type Pipe struct {
 	// You can pass bitmask flags into PipeCommands. Their meaning
 	// is entirely yours to interpret.
 	Flags int
+
+	// RunCommand wraps every PipeCommand in these, outermost first
+	middleware []Middleware
+
+	// SetNewStdout and SetNewStderr use this to create their buffers
+	newBuffer BufferFactory
+
+	// if we have a logger, we tell it what the pipe is doing
+	logger Logger
+
+	// if errexit is true, our loops stop as soon as a PipeCommand fails
+	errexit bool
+
+	// PipeCommands to run when certain things happen
+	traps map[TrapEvent][]PipeCommand
+
+	// inTrap is true while traps are running
+	inTrap bool
+
+	// what WithSignals needs to keep track of
+	signals *signalState
+
+	// isChild is true if this pipe was created by NewChildPipe
+	isChild bool
+
+	// PipeCommands can find out about deadlines, cancellation and
+	// tracing from here
+	ctx context.Context
 }
 
 // NewPipe creates a new Pipe that's ready to use.
@@ -113,6 +143,56 @@ func NewPipe(options ...PipeOption) *Pipe {
 	return &retval
 }
 
+// NewChildPipe creates a new Pipe, for running PipeCommands on behalf
+// of this pipe.
+//
+// The child pipe starts with empty Stdin, Stdout and Stderr buffers. It
+// shares this pipe's Env, context and signal handling, and it has a copy
+// of this pipe's Flags, middleware, buffer factory, logger and errexit
+// setting.
+func (p *Pipe) NewChildPipe() *Pipe {
+	// do we have a pipe to work with?
+	if p == nil {
+		return NewPipe()
+	}
+
+	retval := Pipe{
+		Env:        p.Env,
+		Flags:      p.Flags,
+		middleware: append([]Middleware(nil), p.middleware...),
+		newBuffer:  p.newBuffer,
+		ctx:        p.ctx,
+		logger:     p.logger,
+		errexit:    p.errexit,
+		signals:    p.signals,
+		isChild:    true,
+	}
+	retval.ResetBuffers()
+	retval.ResetError()
+
+	// all done
+	return &retval
+}
+
+// isChildPipe returns true if the pipe was created by NewChildPipe
+func (p *Pipe) isChildPipe() bool {
+	return p != nil && p.isChild
+}
+
+// Context returns the pipe's context. If the pipe does not have one,
+// it returns context.Background().
+//
+// Use WithContext to set the pipe's context.
+func (p *Pipe) Context() context.Context {
+	// do we have a context to return?
+	if p == nil || p.ctx == nil {
+		return context.Background()
+	}
+
+	// yes we do
+	return p.ctx
+}
+
 // DrainStdinToStdout will copy everything that's left in the pipe's Stdin
 // over to the pipe's Stdout.
 func (p *Pipe) DrainStdinToStdout() {
@@ -164,12 +244,20 @@ func (p *Pipe) Okay() bool {
 //
 // It also empties the internal stacks used by PushStdin / PopStdin,
 // PushStdout / PopStdout, and PushStderr / PopStderr.
+//
+// Any SpillBuffers that the pipe was using are closed, and their
+// temporary files are removed. Any pooled buffers (see WithPooledBuffers)
+// are put back into the pool.
 func (p *Pipe) ResetBuffers() {
 	// do we have a pipe to work with?
 	if p == nil {
 		return
 	}
 
+	// clean up the buffers that we are about to throw away
+	p.closeSpillBuffers()
+	oldBuffers := p.buffers()
+
 	// set our input/output buffers
 	p.SetNewStdin()
 	p.SetNewStdout()
@@ -179,6 +267,76 @@ func (p *Pipe) ResetBuffers() {
 	p.stdinStack = make([]ioextra.TextReader, 0)
 	p.stdoutStack = make([]ioextra.TextReaderWriter, 0)
 	p.stderrStack = make([]ioextra.TextReaderWriter, 0)
+
+	// now that nothing points at them, we can recycle the old buffers
+	for _, buf := range oldBuffers {
+		p.releaseBuffer(buf)
+	}
+}
+
+// Close closes any SpillBuffers that the pipe is using, including any on
+// the Stdin, Stdout and Stderr stacks. This removes their temporary files.
+// Before that, it writes out anything that the pipe's Stdout and Stderr
+// are holding back (see EncodeStdout).
+//
+// The SpillBuffers are left empty, and the pipe can still be used.
+func (p *Pipe) Close() error {
+	// do we have a pipe to work with?
+	if p == nil {
+		return nil
+	}
+
+	// yes we do
+	flushErr := p.flushOutput()
+	closeErr := p.closeSpillBuffers()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// buffers returns the pipe's Stdin, Stdout and Stderr, and everything on
+// the pipe's stacks
+func (p *Pipe) buffers() []interface{} {
+	retval := []interface{}{p.Stdin, p.Stdout, p.Stderr}
+	for _, stdin := range p.stdinStack {
+		retval = append(retval, stdin)
+	}
+	for _, stdout := range p.stdoutStack {
+		retval = append(retval, stdout)
+	}
+	for _, stderr := range p.stderrStack {
+		retval = append(retval, stderr)
+	}
+
+	return retval
+}
+
+// closeSpillBuffers closes any SpillBuffers that the pipe is using, and
+// returns the first error that it finds
+func (p *Pipe) closeSpillBuffers() error {
+	var retval error
+	for _, candidate := range p.buffers() {
+		buf, ok := candidate.(*SpillBuffer)
+		if !ok {
+			continue
+		}
+		err := buf.Close()
+		if retval == nil {
+			retval = err
+		}
+	}
+
+	return retval
+}
+
+// newTextBuffer creates a new, empty buffer for Stdout or Stderr
+func (p *Pipe) newTextBuffer() ioextra.TextReaderWriter {
+	if p.newBuffer == nil {
+		return ioextra.NewTextBuffer()
+	}
+
+	return p.newBuffer()
 }
 
 // ResetError sets the pipe's status code and error to their zero values
@@ -196,22 +354,81 @@ func (p *Pipe) ResetError() {
 
 // RunCommand will run a function using this pipe. The function's return
 // values are stored in the pipe's StatusCode and Err fields.
+//
+// If the pipe has any middleware, RunCommand passes the command through
+// the middleware first. The middleware sees the command's name, as
+// worked out by CommandName.
 func (p *Pipe) RunCommand(c PipeCommand) {
 	// do we have a pipe to work with?
 	if p == nil || p.Stdin == nil || p.Stdout == nil {
 		return
 	}
 
+	// we only need the name if there's someone to pass it to
+	name := ""
+	if len(p.middleware) > 0 || p.logger != nil {
+		name = CommandName(c)
+	}
+
+	p.runCommand(name, c)
+}
+
+// RunNamedCommand will run a function using this pipe. The function's
+// return values are stored in the pipe's StatusCode and Err fields.
+//
+// Use RunNamedCommand instead of RunCommand when you want any middleware
+// to see a name of your choosing, instead of the name of the Golang
+// function.
+func (p *Pipe) RunNamedCommand(name string, c PipeCommand) {
+	// do we have a pipe to work with?
+	if p == nil || p.Stdin == nil || p.Stdout == nil {
+		return
+	}
+
 	// yes we do
+	p.runCommand(name, c)
+}
+
+func (p *Pipe) runCommand(name string, c PipeCommand) {
+	// anything held back belongs to this command's output
+	c = flushOutputAfter(c)
+
+	// wrap the command in our middleware
+	//
+	// we work backwards, so that the first middleware added is
+	// the outermost one
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		c = p.middleware[i](name, c)
+	}
+
+	// tell the logger what happens, if we have one
+	if p.logger != nil {
+		defer p.logCommand(name)()
+	}
+
 	p.statusCode, p.err = c(p)
 
 	// special case - do we have a non-zero status code, but no error?
 	if p.statusCode != StatusOkay && p.err == nil {
 		p.err = ErrNonZeroStatusCode{"command", p.statusCode}
 	}
+
+	// were we interrupted?
+	if sig := p.Signal(); sig != nil {
+		p.statusCode = signalStatusCode(sig)
+		p.err = ErrSignal{sig}
+	}
+
+	// do we need to set off any traps?
+	if len(p.traps) > 0 {
+		p.runTrapsAfterCommand()
+	}
 }
 
 // SetNewStdin creates a new, empty Stdin buffer on this pipe.
+//
+// If the old Stdin is a SpillBuffer that the pipe is not using anywhere
+// else, it is closed.
 func (p *Pipe) SetNewStdin() {
 	// do we have a pipe to work with?
 	if p == nil {
@@ -219,7 +436,41 @@ func (p *Pipe) SetNewStdin() {
 	}
 
 	// yes we do
+	oldStdin := p.Stdin
 	p.Stdin = ioextra.NewTextBuffer()
+	p.closeSpillBuffer(oldStdin)
+
+	// all done
+}
+
+// MoveStdoutToStdin makes the pipe's Stdout become the pipe's Stdin, and
+// gives the pipe a new, empty Stdout. Use it between PipeCommands, so that
+// the output of one becomes the input of the next.
+//
+// Nothing is copied: the next PipeCommand reads from the same buffer that
+// the last one wrote to. If Stdout and Stderr are the same, they both get
+// the new Stdout. If the old Stdin is a pooled buffer (see
+// WithPooledBuffers), it goes back into the pool. If it is a SpillBuffer,
+// it is closed.
+func (p *Pipe) MoveStdoutToStdin() {
+	// do we have a pipe to work with?
+	if p == nil {
+		return
+	}
+
+	// yes we do
+	oldStdin := p.Stdin
+	sharedStderr := p.Stdout == p.Stderr
+
+	p.Stdin = p.Stdout
+	p.SetNewStdout()
+	if sharedStderr {
+		p.Stderr = p.Stdout
+	}
+	p.logDebug("pipe stdout moved to stdin")
+
+	p.releaseBuffer(oldStdin)
+	p.closeSpillBuffer(oldStdin)
 
 	// all done
 }
@@ -240,6 +491,40 @@ func (p *Pipe) SetStdinFromString(input string) {
 	// all done
 }
 
+// SetStdinFromBytes sets the pipe's Stdin to be the given input bytes.
+//
+// The bytes are used exactly as they are. Use this for binary data, such
+// as compressed files or images.
+func (p *Pipe) SetStdinFromBytes(input []byte) {
+	// do we have a pipe to work with?
+	if p == nil {
+		return
+	}
+
+	// yes we do
+	buf := ioextra.NewTextBuffer()
+	buf.Write(input)
+
+	p.Stdin = buf
+
+	// all done
+}
+
+// StdinBytes reads everything that is left in the pipe's Stdin, and
+// returns it exactly as it is.
+//
+// Unlike the pipe's `Stdin.String()` and `Stdin.Strings()`, it is safe to
+// use on binary data.
+func (p *Pipe) StdinBytes() ([]byte, error) {
+	// do we have a pipe to work with?
+	if p == nil || p.Stdin == nil {
+		return nil, nil
+	}
+
+	// yes we do
+	return ioutil.ReadAll(p.Stdin)
+}
+
 // PushStdin adds the pipe's existing Stdin to an internal stack,
 // and then sets the pipe's Stdin to the given newStdin.
 //
@@ -255,6 +540,7 @@ func (p *Pipe) PushStdin(newStdin ioextra.TextReader) {
 	}
 
 	p.stdinStack = append(p.stdinStack, p.Stdin)
+	p.logDebug("pipe stack pushed", "stream", "stdin", "depth", len(p.stdinStack))
 	p.Stdin = newStdin
 }
 
@@ -281,6 +567,7 @@ func (p *Pipe) PopStdin() {
 
 	// remove the value we've just popped from the stack
 	p.stdinStack = p.stdinStack[:len(p.stdinStack)-1]
+	p.logDebug("pipe stack popped", "stream", "stdin", "depth", len(p.stdinStack))
 }
 
 // StdinStackLen returns the number of entries in the internal stack of
@@ -300,6 +587,10 @@ func (p *Pipe) StdinStackLen() int {
 }
 
 // SetNewStdout creates a new, empty Stdout buffer on this pipe.
+//
+// If the old Stdout is a pooled buffer (see WithPooledBuffers), and the
+// pipe is not using it anywhere else, it goes back into the pool. If it is
+// a SpillBuffer that the pipe is not using anywhere else, it is closed.
 func (p *Pipe) SetNewStdout() {
 	// do we have a pipe to work with?
 	if p == nil {
@@ -307,11 +598,36 @@ func (p *Pipe) SetNewStdout() {
 	}
 
 	// yes we do
-	p.Stdout = ioextra.NewTextBuffer()
+	oldStdout := p.Stdout
+	flushInterceptors(oldStdout)
+	p.Stdout = p.newTextBuffer()
+	p.releaseBuffer(oldStdout)
+	p.closeSpillBuffer(oldStdout)
 
 	// all done
 }
 
+// StdoutBytes returns everything in the pipe's Stdout, exactly as it
+// was written.
+//
+// Unlike the pipe's `Stdout.Strings()`, it is safe to use on binary data.
+// If Stdout is a buffer, the buffer is left as it is. Otherwise, we read
+// everything that is left in Stdout.
+func (p *Pipe) StdoutBytes() ([]byte, error) {
+	// do we have a pipe to work with?
+	if p == nil || p.Stdout == nil {
+		return nil, nil
+	}
+
+	// can we look without reading?
+	if buf, ok := p.Stdout.(interface{ Bytes() []byte }); ok {
+		return append([]byte(nil), buf.Bytes()...), nil
+	}
+
+	// no, we cannot
+	return ioutil.ReadAll(p.Stdout)
+}
+
 // PushStdout adds the pipe's existing Stdout to an internal stack,
 // and then sets the pipe's Stdout to the given newStdout.
 //
@@ -337,6 +653,7 @@ func (p *Pipe) PushStdout(newStdout ioextra.TextReaderWriter) {
 
 	// yes we do
 	p.stdoutStack = append(p.stdoutStack, p.Stdout)
+	p.logDebug("pipe stack pushed", "stream", "stdout", "depth", len(p.stdoutStack))
 	p.Stdout = newStdout
 }
 
@@ -369,6 +686,7 @@ func (p *Pipe) PopStdout() {
 
 	// remove the value we've just popped from the stack
 	p.stdoutStack = p.stdoutStack[:len(p.stdoutStack)-1]
+	p.logDebug("pipe stack popped", "stream", "stdout", "depth", len(p.stdoutStack))
 
 	// do Stdout and Stderr point at each other?
 	if p.Stdout == p.Stderr {
@@ -406,6 +724,7 @@ func (p *Pipe) PopStdoutOnly() {
 
 	// remove the value we've just popped from the stack
 	p.stdoutStack = p.stdoutStack[:len(p.stdoutStack)-1]
+	p.logDebug("pipe stack popped", "stream", "stdout", "depth", len(p.stdoutStack))
 }
 
 // StdoutStackLen returns the number of entries in the internal stack of
@@ -425,6 +744,10 @@ func (p *Pipe) StdoutStackLen() int {
 }
 
 // SetNewStderr creates a new, empty Stderr buffer on this pipe.
+//
+// If the old Stderr is a pooled buffer (see WithPooledBuffers), and the
+// pipe is not using it anywhere else, it goes back into the pool. If it is
+// a SpillBuffer that the pipe is not using anywhere else, it is closed.
 func (p *Pipe) SetNewStderr() {
 	// do we have a pipe to work with?
 	if p == nil {
@@ -432,7 +755,10 @@ func (p *Pipe) SetNewStderr() {
 	}
 
 	// yes we do
-	p.Stderr = ioextra.NewTextBuffer()
+	oldStderr := p.Stderr
+	p.Stderr = p.newTextBuffer()
+	p.releaseBuffer(oldStderr)
+	p.closeSpillBuffer(oldStderr)
 
 	// all done
 }
@@ -463,6 +789,7 @@ func (p *Pipe) PushStderr(newStderr ioextra.TextReaderWriter) {
 	}
 
 	p.stderrStack = append(p.stderrStack, p.Stderr)
+	p.logDebug("pipe stack pushed", "stream", "stderr", "depth", len(p.stderrStack))
 	p.Stderr = newStderr
 }
 
@@ -495,6 +822,7 @@ func (p *Pipe) PopStderr() {
 
 	// remove the value we've just popped from the stack
 	p.stderrStack = p.stderrStack[:len(p.stderrStack)-1]
+	p.logDebug("pipe stack popped", "stream", "stderr", "depth", len(p.stderrStack))
 
 	// special case - does the pipe's Stdout currently point at
 	// the pipe's Stderr?
@@ -532,6 +860,7 @@ func (p *Pipe) PopStderrOnly() {
 
 	// remove the value we've just popped from the stack
 	p.stderrStack = p.stderrStack[:len(p.stderrStack)-1]
+	p.logDebug("pipe stack popped", "stream", "stderr", "depth", len(p.stderrStack))
 }
 
 // StderrStackLen returns the number of entries in the internal stack of
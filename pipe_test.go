@@ -233,6 +233,82 @@ func TestNewPipeAppliesAnyOptionsWePassIn(t *testing.T) {
 	assert.NotNil(t, unit.Env)
 }
 
+func TestPipeNewChildPipeCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	child := unit.NewChildPipe()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, child)
+	assert.NotNil(t, child.Stdin)
+}
+
+func TestPipeNewChildPipeSharesEnvAndCopiesFlags(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithEnvFromMap(map[string]string{"NAME": "parent"}))
+	unit.Flags = 3
+	unit.SetStdinFromString("parent input")
+	unit.Stdout.WriteString("parent output")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	child := unit.NewChildPipe()
+	child.Env.Setenv("NAME", "child")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "child", unit.Env.Getenv("NAME"))
+	assert.Equal(t, 3, child.Flags)
+	assert.Equal(t, "", child.Stdin.String())
+	assert.Equal(t, "", child.Stdout.String())
+	assert.Equal(t, "", child.Stderr.String())
+	assert.Nil(t, child.Error())
+}
+
+func TestPipeNewChildPipeCopiesMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var names []string
+	unit := pipe.NewPipe(pipe.WithMiddleware(
+		func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+			names = append(names, name)
+			return next
+		},
+	))
+	child := unit.NewChildPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	child.RunNamedCommand("in the child", func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"in the child"}, names)
+}
+
 func TestPipeDrainStdinToStdoutCopiesStdinToStdout(t *testing.T) {
 	t.Parallel()
 
@@ -712,6 +788,103 @@ func TestPipeRunCommandSetsErrIfStatusCodeNotOkay(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestPipeRunCommandPassesCommandsThroughMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	names := []string{}
+	middleware := func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+		names = append(names, name)
+		return next
+	}
+	unit := pipe.NewPipe(pipe.WithMiddleware(middleware))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.AttachOsStdin)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"AttachOsStdin"}, names)
+}
+
+func TestPipeRunNamedCommandCopesWithNilPointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	op := func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("op", op)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// as long as it doesn't crash, the test has passed
+}
+
+func TestPipeRunNamedCommandUpdatesStatusCodeAndErr(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+
+	expectedErr := errors.New("status not okay")
+	op := func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusNotOkay, expectedErr
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("op", op)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestPipeRunNamedCommandPassesTheGivenNameToMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	names := []string{}
+	middleware := func(name string, next pipe.PipeCommand) pipe.PipeCommand {
+		names = append(names, name)
+		return next
+	}
+	unit := pipe.NewPipe(pipe.WithMiddleware(middleware))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("my-command", pipe.AttachOsStdin)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"my-command"}, names)
+}
+
 func TestPipeSetNewStdinCopesWithNilPipePointer(t *testing.T) {
 	t.Parallel()
 
@@ -750,6 +923,70 @@ func TestPipeSetNewStdinCopesWithEmptyPipe(t *testing.T) {
 	// as long as the code doesn't segfault, it works!
 }
 
+func TestPipeMoveStdoutToStdinCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.MoveStdoutToStdin()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// as long as it didn't crash, we're good
+}
+
+func TestPipeMoveStdoutToStdinMakesStdoutTheNewStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.Stdout.WriteString("hello world\n")
+	oldStdout := unit.Stdout
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.MoveStdoutToStdin()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Same(t, oldStdout, unit.Stdin)
+	assert.NotSame(t, oldStdout, unit.Stdout)
+	assert.Equal(t, "hello world\n", unit.Stdin.String())
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestPipeMoveStdoutToStdinKeepsStdoutAndStderrTogether(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.Stderr = unit.Stdout
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.MoveStdoutToStdin()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Same(t, unit.Stdout, unit.Stderr)
+	assert.NotSame(t, unit.Stdin, unit.Stdout)
+}
+
 func TestPipeSetStdinFromStringCopesWithNilPipePointer(t *testing.T) {
 	t.Parallel()
 
@@ -788,6 +1025,87 @@ func TestPipeSetStdinFromStringCopesWithEmptyPipe(t *testing.T) {
 	// as long as the code doesn't segfault, it works!
 }
 
+func TestPipeSetStdinFromBytesKeepsEveryByte(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	expectedResult := []byte("\x00\xff\r\nhello\r\n\x1f\x8b")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.SetStdinFromBytes(expectedResult)
+	actualResult, err := unit.StdinBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestPipeSetStdinFromBytesCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.SetStdinFromBytes([]byte{})
+
+	// ----------------------------------------------------------------
+	// test the results
+	//
+	// as long as the code doesn't segfault, it works!
+}
+
+func TestPipeStdinBytesCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.StdinBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Nil(t, actualResult)
+}
+
+func TestPipeStdinBytesCopesWithEmptyPipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.StdinBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Nil(t, actualResult)
+}
+
 func TestPipePushStdinCopesWithNilPipePointer(t *testing.T) {
 	t.Parallel()
 
@@ -1042,6 +1360,71 @@ func TestPipeSetNewStdoutCopesWithEmptyPipe(t *testing.T) {
 	// as long as the code doesn't segfault, it works!
 }
 
+func TestPipeStdoutBytesReturnsEveryByte(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	expectedResult := []byte("\x00\xff\r\nhello\r\n\x1f\x8b")
+	unit.Stdout.Write(expectedResult)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.StdoutBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+
+	// the buffer is left untouched
+	assert.Equal(t, string(expectedResult), unit.Stdout.String())
+}
+
+func TestPipeStdoutBytesCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.StdoutBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Nil(t, actualResult)
+}
+
+func TestPipeStdoutBytesCopesWithEmptyPipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit pipe.Pipe
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.StdoutBytes()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Nil(t, actualResult)
+}
+
 func TestPipePushStdoutCopesWithNilPipePointer(t *testing.T) {
 	t.Parallel()
 
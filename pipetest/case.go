@@ -0,0 +1,228 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// CommandBuilder is the signature of any function that turns a list of
+// arguments into a PipeCommand.
+type CommandBuilder = func(args []string) pipe.PipeCommand
+
+// TestingT is the subset of testing.TB that we use to report problems.
+//
+// *testing.T and *testing.B both satisfy it.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Case describes a single test of a PipeCommand: what goes into the pipe,
+// and what we expect to come out of it.
+type Case struct {
+	// Name is used as the name of the subtest
+	Name string
+
+	// Stdin is copied into the pipe's Stdin before the command runs
+	Stdin string
+
+	// Env holds the only environment variables that the command will see.
+	// The command never sees your program's environment.
+	Env map[string]string
+
+	// Args are passed into the CommandBuilder, to create the command
+	Args []string
+
+	// Flags are copied into the pipe's Flags before the command runs
+	Flags int
+
+	// WantStdout is what we expect the command to write to the pipe's
+	// Stdout. It is ignored if GoldenStdout is set.
+	WantStdout string
+
+	// WantStderr is what we expect the command to write to the pipe's
+	// Stderr. It is ignored if GoldenStderr is set.
+	WantStderr string
+
+	// GoldenStdout is the path to a file that holds what we expect the
+	// command to write to the pipe's Stdout
+	GoldenStdout string
+
+	// GoldenStderr is the path to a file that holds what we expect the
+	// command to write to the pipe's Stderr
+	GoldenStderr string
+
+	// WantStatus is the status code that we expect the command to return
+	WantStatus int
+
+	// WantErr is the error that we expect the command to return.
+	//
+	// If WantErr is nil and WantStatus is not StatusOkay, we expect the
+	// ErrNonZeroStatusCode that Pipe.RunCommand reports for you.
+	WantErr error
+}
+
+// Command turns a PipeCommand that takes no arguments into a
+// CommandBuilder, so that you can pass it into RunCases.
+func Command(c pipe.PipeCommand) CommandBuilder {
+	return func(args []string) pipe.PipeCommand {
+		return c
+	}
+}
+
+// RunCases runs each of the given cases as a subtest.
+//
+// Each case runs against a brand new pipe, with a brand new command
+// created by the given builder.
+func RunCases(t *testing.T, builder CommandBuilder, cases []Case) {
+	t.Helper()
+
+	for i, c := range cases {
+		// shorthand
+		c := c
+
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Helper()
+
+			p := c.Run(builder)
+			c.Assert(t, p)
+		})
+	}
+}
+
+// NewPipe creates a new pipe, ready for the test case to run against.
+//
+// The pipe's Stdin holds the case's Stdin, and the pipe's Env holds
+// (only) the case's Env.
+func (c Case) NewPipe() *pipe.Pipe {
+	p := pipe.NewPipe(pipe.WithEnvFromMap(c.Env))
+	p.SetStdinFromString(c.Stdin)
+	p.Flags = c.Flags
+
+	return p
+}
+
+// Run uses the builder to create the command under test, and runs it
+// against a new pipe created by NewPipe.
+//
+// It returns the pipe, so that you can make any further assertions of
+// your own.
+func (c Case) Run(builder CommandBuilder) *pipe.Pipe {
+	p := c.NewPipe()
+
+	// did we set up the pipe okay?
+	if p.Error() != nil {
+		return p
+	}
+
+	p.RunCommand(builder(c.Args))
+	return p
+}
+
+// Assert compares the given pipe against what the test case expects.
+//
+// It reports every mismatch to t, and returns false if there were any.
+func (c Case) Assert(t TestingT, p *pipe.Pipe) bool {
+	t.Helper()
+
+	okay := c.assertOutput(t, "stdout", p.Stdout.String(), c.WantStdout, c.GoldenStdout)
+	okay = c.assertOutput(t, "stderr", p.Stderr.String(), c.WantStderr, c.GoldenStderr) && okay
+
+	statusCode, err := p.StatusError()
+	if statusCode != c.WantStatus {
+		t.Errorf("status code mismatch: want %d, got %d", c.WantStatus, statusCode)
+		okay = false
+	}
+
+	wantErr := c.WantErr
+	if wantErr == nil && c.WantStatus != pipe.StatusOkay {
+		wantErr = pipe.ErrNonZeroStatusCode{
+			SequenceType: "command",
+			StatusCode:   c.WantStatus,
+		}
+	}
+	if !isSameError(wantErr, err) {
+		t.Errorf("error mismatch:\nwant: %v\ngot:  %v", wantErr, err)
+		okay = false
+	}
+
+	return okay
+}
+
+func (c Case) assertOutput(t TestingT, streamName, got, want, goldenFile string) bool {
+	t.Helper()
+
+	// are we comparing against a golden file?
+	if goldenFile != "" {
+		var err error
+		want, err = readGoldenFile(goldenFile, got)
+		if err != nil {
+			t.Errorf("%s golden file: %v", streamName, err)
+			return false
+		}
+	}
+
+	if got == want {
+		return true
+	}
+
+	t.Errorf("%s mismatch (-want +got):\n%s", streamName, Diff(want, got))
+	return false
+}
+
+// isSameError returns true if the actual error is, or looks exactly like,
+// the error that we want.
+func isSameError(want, got error) bool {
+	if want == nil || got == nil {
+		return want == got
+	}
+
+	return errors.Is(got, want) || got.Error() == want.Error()
+}
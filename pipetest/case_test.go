@@ -0,0 +1,275 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// upper is the PipeCommand that we use to test the test harness
+func upper(p *pipe.Pipe) (int, error) {
+	for line := range p.Stdin.ReadLines() {
+		p.Stdout.WriteString(strings.ToUpper(line))
+		p.Stdout.WriteRune('\n')
+	}
+
+	return pipe.StatusOkay, nil
+}
+
+// echoArgs writes its args and the value of $GREETING to the pipe
+func echoArgs(args []string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString(p.Env.Getenv("GREETING") + " " + strings.Join(args, " "))
+		p.Stderr.WriteString(fmt.Sprintf("flags %d", p.Flags))
+		return pipe.StatusOkay, nil
+	}
+}
+
+// recordingT lets us check what Case.Assert reports
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRunCasesRunsEachCase(t *testing.T) {
+	pipetest.RunCases(t, pipetest.Command(upper), []pipetest.Case{
+		{
+			Name:       "empty input",
+			WantStdout: "",
+		},
+		{
+			Name:       "single line",
+			Stdin:      "hello world\n",
+			WantStdout: "HELLO WORLD\n",
+		},
+		{
+			Stdin:        "hello world\n",
+			GoldenStdout: "testdata/upper.golden",
+		},
+	})
+}
+
+func TestRunCasesPassesArgsEnvAndFlags(t *testing.T) {
+	pipetest.RunCases(t, echoArgs, []pipetest.Case{
+		{
+			Name:       "args and env",
+			Env:        map[string]string{"GREETING": "hello"},
+			Args:       []string{"to", "you"},
+			Flags:      3,
+			WantStdout: "hello to you",
+			WantStderr: "flags 3",
+		},
+	})
+}
+
+func TestCaseNewPipeDoesNotUseTheProgramEnv(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p := unit.NewPipe()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, p.Env.Environ())
+}
+
+func TestCaseAssertReturnsTrueWhenEverythingMatches(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{
+		Stdin:      "hello\n",
+		WantStdout: "HELLO\n",
+	}
+	p := unit.Run(pipetest.Command(upper))
+	recorder := &recordingT{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Assert(recorder, p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, actualResult)
+	assert.Empty(t, recorder.errors)
+}
+
+func TestCaseAssertReportsStdoutMismatchAsADiff(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{
+		Stdin:      "one\ntwo\nthree\n",
+		WantStdout: "ONE\n2\nTHREE\n",
+	}
+	p := unit.Run(pipetest.Command(upper))
+	recorder := &recordingT{}
+
+	expectedResult := "stdout mismatch (-want +got):\n" +
+		" ONE\n" +
+		"-2\n" +
+		"+TWO\n" +
+		" THREE\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Assert(recorder, p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, actualResult)
+	assert.Equal(t, []string{expectedResult}, recorder.errors)
+}
+
+func TestCaseAssertReportsStatusCodeAndErrorMismatches(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{
+		WantStatus: 2,
+	}
+	p := unit.Run(pipetest.Command(func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusNotOkay, errors.New("alas")
+	}))
+	recorder := &recordingT{}
+
+	expectedResult := []string{
+		"status code mismatch: want 2, got 1",
+		"error mismatch:\nwant: command exited with non-zero status code 2\ngot:  alas",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Assert(recorder, p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, actualResult)
+	assert.Equal(t, expectedResult, recorder.errors)
+}
+
+func TestCaseAssertMatchesWantErrByValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{
+		WantStatus: pipe.StatusNotOkay,
+		WantErr:    errors.New("alas"),
+	}
+	p := unit.Run(pipetest.Command(func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusNotOkay, errors.New("alas")
+	}))
+	recorder := &recordingT{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Assert(recorder, p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, actualResult)
+	assert.Empty(t, recorder.errors)
+}
+
+func TestCaseAssertReportsMissingGoldenFile(t *testing.T) {
+	t.Parallel()
+
+	// when we are updating golden files, missing files get created
+	if pipetest.UpdatingGoldenFiles() {
+		t.Skip("not supported with -pipetest.update")
+	}
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.Case{
+		GoldenStderr: "testdata/does-not-exist.golden",
+	}
+	p := unit.Run(pipetest.Command(upper))
+	recorder := &recordingT{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Assert(recorder, p)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, actualResult)
+	assert.Len(t, recorder.errors, 1)
+	assert.True(t, strings.HasPrefix(recorder.errors[0], "stderr golden file: "))
+}
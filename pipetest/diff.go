@@ -0,0 +1,219 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines we show either side of
+// a change
+const diffContextLines = 3
+
+// diffMaxCells limits how much memory we use to compare outputs that are
+// very different from each other
+const diffMaxCells = 4 * 1024 * 1024
+
+// diffOp is a single line in a diff
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// Diff returns a line-by-line comparison of want and got.
+//
+// Lines that are only in want start with '-', lines that are only in got
+// start with '+', and unchanged lines start with ' '. Long runs of
+// unchanged lines are collapsed.
+//
+// It returns an empty string if want and got are identical.
+func Diff(want, got string) string {
+	// special case - nothing to report
+	if want == got {
+		return ""
+	}
+
+	ops := diffLines(splitLines(want), splitLines(got))
+	return renderDiff(ops)
+}
+
+// splitLines breaks the input into lines, keeping the trailing newline
+// on each line, so that we can spot missing newlines
+func splitLines(input string) []string {
+	retval := strings.SplitAfter(input, "\n")
+
+	// SplitAfter leaves an empty string after the last newline
+	if retval[len(retval)-1] == "" {
+		retval = retval[:len(retval)-1]
+	}
+
+	return retval
+}
+
+// diffLines works out the shortest edit script that turns a into b
+func diffLines(a, b []string) []diffOp {
+	retval := []diffOp{}
+
+	// skip over any common prefix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		retval = append(retval, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	a = a[prefix:]
+	b = b[prefix:]
+
+	// skip over any common suffix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a = a[:len(a)-suffix]
+	b = b[:len(b)-suffix]
+
+	// special case - the differences are too big to compare line by line
+	if len(a)*len(b) > diffMaxCells {
+		for _, line := range a {
+			retval = append(retval, diffOp{'-', line})
+		}
+		for _, line := range b {
+			retval = append(retval, diffOp{'+', line})
+		}
+	} else {
+		retval = append(retval, diffLCS(a, b)...)
+	}
+
+	for _, line := range common {
+		retval = append(retval, diffOp{' ', line})
+	}
+
+	return retval
+}
+
+// diffLCS uses the longest common subsequence of a and b to build
+// the edit script
+func diffLCS(a, b []string) []diffOp {
+	// lengths[i][j] is the LCS of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	retval := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			retval = append(retval, diffOp{' ', a[i]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			retval = append(retval, diffOp{'-', a[i]})
+			i++
+		default:
+			retval = append(retval, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		retval = append(retval, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		retval = append(retval, diffOp{'+', b[j]})
+	}
+
+	return retval
+}
+
+// renderDiff turns the edit script into something a human can read
+func renderDiff(ops []diffOp) string {
+	var retval strings.Builder
+
+	// which unchanged lines are close enough to a change to show?
+	show := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for j := i - diffContextLines; j <= i+diffContextLines; j++ {
+			if j >= 0 && j < len(ops) {
+				show[j] = true
+			}
+		}
+	}
+
+	skipped := 0
+	for i, op := range ops {
+		if !show[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			fmt.Fprintf(&retval, "  ... %d unchanged line(s) ...\n", skipped)
+			skipped = 0
+		}
+
+		retval.WriteByte(op.kind)
+		retval.WriteString(strings.TrimSuffix(op.line, "\n"))
+		retval.WriteByte('\n')
+		if !strings.HasSuffix(op.line, "\n") {
+			retval.WriteString("\\ no newline at end of output\n")
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(&retval, "  ... %d unchanged line(s) ...\n", skipped)
+	}
+
+	return retval.String()
+}
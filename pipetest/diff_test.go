@@ -0,0 +1,138 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffReturnsEmptyStringWhenInputsMatch(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := pipetest.Diff("one\ntwo\n", "one\ntwo\n")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "", actualResult)
+}
+
+func TestDiffShowsAddedAndRemovedLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	want := "one\ntwo\nthree\n"
+	got := "one\nthree\nfour\n"
+	expectedResult := " one\n" +
+		"-two\n" +
+		" three\n" +
+		"+four\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := pipetest.Diff(want, got)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDiffShowsMissingTrailingNewline(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := "-one\n" +
+		"+one\n" +
+		"\\ no newline at end of output\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := pipetest.Diff("one\n", "one")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDiffCollapsesLongRunsOfUnchangedLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	common := strings.Repeat("same\n", 10)
+	want := common + "old\n" + common
+	got := common + "new\n" + common
+
+	expectedResult := "  ... 7 unchanged line(s) ...\n" +
+		" same\n same\n same\n" +
+		"-old\n" +
+		"+new\n" +
+		" same\n same\n same\n" +
+		"  ... 7 unchanged line(s) ...\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := pipetest.Diff(want, got)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
@@ -0,0 +1,73 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package pipetest helps you write unit tests for your PipeCommands.
+
+Describe each test as a Case: what goes into the pipe, and what you expect
+to come out of it. RunCases runs each Case as a subtest, against a brand new
+pipe, and reports a line-by-line diff whenever the output is not what you
+expected.
+
+	func TestUpper(t *testing.T) {
+	    pipetest.RunCases(t, pipetest.Command(Upper), []pipetest.Case{
+	        {
+	            Name:       "converts stdin to upper case",
+	            Stdin:      "hello world\n",
+	            WantStdout: "HELLO WORLD\n",
+	        },
+	        {
+	            Name:         "long input",
+	            Stdin:        longInput,
+	            GoldenStdout: "testdata/upper-long.golden",
+	        },
+	    })
+	}
+
+# Golden Files
+
+Set a Case's GoldenStdout and/or GoldenStderr to compare the output against
+the contents of a file, instead of against WantStdout and/or WantStderr.
+
+Run your tests with the -pipetest.update flag to (re)write the golden files
+from the actual output:
+
+	go test ./... -args -pipetest.update
+*/
+package pipetest
@@ -0,0 +1,86 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest_test
+
+import (
+	"fmt"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+)
+
+func ExampleDiff() {
+	fmt.Print(pipetest.Diff("hello\nworld\n", "hello\nthere\n"))
+	// Output:
+	//  hello
+	// -world
+	// +there
+}
+
+func ExampleCase_Run() {
+	c := pipetest.Case{
+		Stdin:      "hello world\n",
+		WantStdout: "HELLO WORLD\n",
+	}
+
+	p := c.Run(pipetest.Command(upper))
+	fmt.Print(p.Stdout.String())
+	// Output:
+	// HELLO WORLD
+}
+
+func ExampleFake() {
+	// a command that fails the first time, and works the second time
+	fake := pipetest.NewFake().
+		WithStderr("try again\n").
+		WithStatus(pipe.StatusNotOkay).
+		Then().
+		WithStdout("hello world\n")
+
+	p := pipe.NewPipe()
+	p.RunCommand(fake.Run)
+	p.RunCommand(fake.Run)
+
+	fmt.Print(p.Stdout.String())
+	fmt.Printf("calls: %d\n", fake.CallCount())
+	// Output:
+	// hello world
+	// calls: 2
+}
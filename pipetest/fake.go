@@ -0,0 +1,297 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest
+
+import (
+	"io/ioutil"
+	"sync"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+)
+
+// Response is what a Fake does when it is called.
+type Response struct {
+	// Stdout is written to the pipe's Stdout
+	Stdout string
+
+	// Stderr is written to the pipe's Stderr
+	Stderr string
+
+	// StatusCode is the status code that the Fake returns
+	StatusCode int
+
+	// Err is the error that the Fake returns
+	Err error
+}
+
+// Call records what a Fake was given when it was called.
+type Call struct {
+	// Args holds the args passed into the Fake's Builder
+	Args []string
+
+	// Stdin holds everything that the Fake read from the pipe's Stdin
+	Stdin string
+
+	// Env holds the pipe's environment, in "key=value" form
+	Env []string
+
+	// Flags holds the pipe's Flags
+	Flags int
+}
+
+// Fake is a stand-in PipeCommand, for testing code that runs PipeCommands.
+//
+// It returns scripted responses, and it records every call that was made
+// to it.
+type Fake struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// called is signalled every time someone calls the Fake
+	called *sync.Cond
+
+	// responses holds what we do on each call. Once we have used them
+	// all up, we repeat the last one.
+	responses []Response
+
+	// block, if set, is a channel that we wait on before we respond
+	block <-chan struct{}
+
+	// calls holds what we were given each time we were called
+	calls []Call
+}
+
+// NewFake creates a new Fake.
+//
+// The Fake returns each of the given responses in turn, one per call, and
+// then repeats the last response for any further calls. If you don't give
+// it any responses, it returns StatusOkay with no output.
+//
+// You can also use the builder methods (WithStdout, WithStatus, Then, and
+// so on) to script the Fake's responses.
+func NewFake(responses ...Response) *Fake {
+	retval := &Fake{
+		responses: responses,
+	}
+	retval.called = sync.NewCond(&retval.mu)
+
+	// all done
+	return retval
+}
+
+// WithStdout sets what the Fake writes to the pipe's Stdout in the
+// current response.
+func (f *Fake) WithStdout(output string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.currentResponse().Stdout = output
+	return f
+}
+
+// WithStderr sets what the Fake writes to the pipe's Stderr in the
+// current response.
+func (f *Fake) WithStderr(output string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.currentResponse().Stderr = output
+	return f
+}
+
+// WithStatus sets the status code that the Fake returns in the current
+// response.
+func (f *Fake) WithStatus(statusCode int) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.currentResponse().StatusCode = statusCode
+	return f
+}
+
+// WithError sets the error that the Fake returns in the current response.
+func (f *Fake) WithError(err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.currentResponse().Err = err
+	return f
+}
+
+// Then starts a new response. Any builder methods that you call after
+// Then change what the Fake does on its next call.
+func (f *Fake) Then() *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// make sure the existing response is kept
+	f.currentResponse()
+
+	f.responses = append(f.responses, Response{})
+	return f
+}
+
+// BlockOn makes the Fake wait until it can receive from the given channel
+// (or the channel is closed) before it responds.
+//
+// The call is recorded before the Fake starts to wait.
+func (f *Fake) BlockOn(ch <-chan struct{}) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.block = ch
+	return f
+}
+
+// Builder returns a CommandBuilder, so that you can use the Fake wherever
+// code expects to build a PipeCommand from a list of args.
+//
+// The args are recorded in each Call.
+func (f *Fake) Builder() CommandBuilder {
+	return func(args []string) pipe.PipeCommand {
+		return func(p *pipe.Pipe) (int, error) {
+			return f.run(p, args)
+		}
+	}
+}
+
+// Run is a PipeCommand. It records the call, and then responds with the
+// next scripted response.
+func (f *Fake) Run(p *pipe.Pipe) (int, error) {
+	return f.run(p, nil)
+}
+
+// Calls returns a copy of the calls that have been made to the Fake,
+// oldest first.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	retval := make([]Call, len(f.calls))
+	copy(retval, f.calls)
+	return retval
+}
+
+// CallCount returns the number of times that the Fake has been called.
+func (f *Fake) CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.calls)
+}
+
+// LastCall returns the most recent call made to the Fake. It returns
+// false if the Fake has not been called yet.
+func (f *Fake) LastCall() (Call, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.calls) == 0 {
+		return Call{}, false
+	}
+
+	return f.calls[len(f.calls)-1], true
+}
+
+// WaitForCalls blocks until the Fake has been called at least n times.
+//
+// Use it with BlockOn to find out when the code under test has reached
+// the Fake.
+func (f *Fake) WaitForCalls(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.calls) < n {
+		f.called.Wait()
+	}
+}
+
+func (f *Fake) run(p *pipe.Pipe, args []string) (int, error) {
+	// what have we been given?
+	call := Call{
+		Args:  args,
+		Env:   p.Env.Environ(),
+		Flags: p.Flags,
+	}
+	if p.Stdin != nil {
+		stdin, _ := ioutil.ReadAll(p.Stdin)
+		call.Stdin = string(stdin)
+	}
+
+	// record the call, and work out how we are going to respond
+	f.mu.Lock()
+	callNo := len(f.calls)
+	f.calls = append(f.calls, call)
+	block := f.block
+
+	var response Response
+	switch {
+	case len(f.responses) == 0:
+		// use the zero value
+	case callNo < len(f.responses):
+		response = f.responses[callNo]
+	default:
+		response = f.responses[len(f.responses)-1]
+	}
+
+	f.called.Broadcast()
+	f.mu.Unlock()
+
+	// are we waiting for permission to respond?
+	if block != nil {
+		<-block
+	}
+
+	p.Stdout.WriteString(response.Stdout)
+	p.Stderr.WriteString(response.Stderr)
+
+	return response.StatusCode, response.Err
+}
+
+// currentResponse returns the response that the builder methods update.
+//
+// The caller must hold f.mu.
+func (f *Fake) currentResponse() *Response {
+	if len(f.responses) == 0 {
+		f.responses = append(f.responses, Response{})
+	}
+
+	return &f.responses[len(f.responses)-1]
+}
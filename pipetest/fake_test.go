@@ -0,0 +1,242 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest_test
+
+import (
+	"errors"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFakeRespondsWithStatusOkayByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.NewFake()
+	p := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(unit.Run)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := p.StatusError()
+	assert.Equal(t, pipe.StatusOkay, statusCode)
+	assert.Nil(t, err)
+	assert.Equal(t, "", p.Stdout.String())
+	assert.Equal(t, "", p.Stderr.String())
+}
+
+func TestFakeWritesTheScriptedResponse(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedErr := errors.New("alas")
+	unit := pipetest.NewFake().
+		WithStdout("hello\n").
+		WithStderr("warning\n").
+		WithStatus(3).
+		WithError(expectedErr)
+	p := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(unit.Run)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := p.StatusError()
+	assert.Equal(t, 3, statusCode)
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, "hello\n", p.Stdout.String())
+	assert.Equal(t, "warning\n", p.Stderr.String())
+}
+
+func TestFakeRespondsInTurnAndThenRepeatsTheLastResponse(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.NewFake().
+		WithStatus(pipe.StatusNotOkay).
+		Then().
+		WithStdout("ok\n")
+	expectedResult := []int{pipe.StatusNotOkay, pipe.StatusOkay, pipe.StatusOkay}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := []int{}
+	for i := 0; i < 3; i++ {
+		p := pipe.NewPipe()
+		p.RunCommand(unit.Run)
+		actualResult = append(actualResult, p.StatusCode())
+	}
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, 3, unit.CallCount())
+}
+
+func TestNewFakeAcceptsAListOfResponses(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.NewFake(
+		pipetest.Response{Stdout: "one"},
+		pipetest.Response{Stdout: "two"},
+	)
+	p := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(unit.Run)
+	p.RunCommand(unit.Run)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "onetwo", p.Stdout.String())
+}
+
+func TestFakeRecordsWhatItWasCalledWith(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.NewFake()
+	p := pipe.NewPipe(pipe.WithEnvFromMap(map[string]string{"HOME": "/tmp"}))
+	p.SetStdinFromString("hello world\n")
+	p.Flags = 7
+
+	expectedResult := pipetest.Call{
+		Args:  []string{"-n", "1"},
+		Stdin: "hello world\n",
+		Env:   []string{"HOME=/tmp"},
+		Flags: 7,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(unit.Builder()([]string{"-n", "1"}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	actualResult, ok := unit.LastCall()
+	assert.True(t, ok)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, []pipetest.Call{expectedResult}, unit.Calls())
+}
+
+func TestFakeLastCallReturnsFalseBeforeFirstCall(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipetest.NewFake()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, ok := unit.LastCall()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, ok)
+	assert.Empty(t, unit.Calls())
+}
+
+func TestFakeBlocksUntilTheChannelIsReady(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	release := make(chan struct{})
+	unit := pipetest.NewFake().WithStdout("done").BlockOn(release)
+	p := pipe.NewPipe()
+
+	finished := make(chan struct{})
+	go func() {
+		p.RunCommand(unit.Run)
+		close(finished)
+	}()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.WaitForCalls(1)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	select {
+	case <-finished:
+		t.Fatal("fake did not block")
+	default:
+	}
+
+	close(release)
+	<-finished
+	assert.Equal(t, "done", p.Stdout.String())
+}
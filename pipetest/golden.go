@@ -0,0 +1,85 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipetest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// updateGolden is set when you run your tests with -pipetest.update
+var updateGolden = flag.Bool(
+	"pipetest.update",
+	false,
+	"rewrite pipetest golden files from the actual output",
+)
+
+// UpdatingGoldenFiles returns true if the tests are (re)writing golden
+// files, instead of comparing against them.
+func UpdatingGoldenFiles() bool {
+	return *updateGolden
+}
+
+// readGoldenFile returns the contents of the given golden file.
+//
+// If we are updating golden files, it writes the actual output into the
+// golden file first, creating any parent folders as required.
+func readGoldenFile(filename string, actual string) (string, error) {
+	if UpdatingGoldenFiles() {
+		err := os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return "", err
+		}
+
+		err = ioutil.WriteFile(filename, []byte(actual), 0644)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
@@ -0,0 +1,204 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// Recorder is a Middleware that records what happens each time that
+// a pipe runs a PipeCommand.
+//
+// Add it to your pipe using WithMiddleware:
+//
+//	recorder := pipe.NewRecorder(transcriptFile)
+//	p := pipe.NewPipe(pipe.WithMiddleware(recorder.Middleware))
+//
+// You can then use a Replayer to re-run your pipeline using the recorded
+// output.
+//
+// To record the pipe's Stdin, the Recorder reads it all into memory
+// before the PipeCommand runs, and replaces the pipe's Stdin with a
+// TextBuffer that holds the same data.
+type Recorder struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// entries holds everything we have recorded
+	entries []TranscriptEntry
+
+	// encoder writes each entry out as JSON Lines, if we have somewhere
+	// to write to
+	encoder *json.Encoder
+
+	// err holds the first error that we hit writing to the encoder
+	err error
+}
+
+// NewRecorder creates a new Recorder.
+//
+// If w is not nil, the Recorder writes each TranscriptEntry to w as
+// a single line of JSON, as soon as the PipeCommand has finished.
+func NewRecorder(w io.Writer) *Recorder {
+	retval := Recorder{}
+	if w != nil {
+		retval.encoder = json.NewEncoder(w)
+	}
+
+	// all done
+	return &retval
+}
+
+// Middleware records what happens when the given PipeCommand runs.
+//
+// PipeCommands that run on child pipes (see NewChildPipe) are not
+// recorded on their own: what they do is part of the parent pipe's
+// PipeCommand, which is recorded instead.
+//
+// Pass it into WithMiddleware.
+func (r *Recorder) Middleware(name string, next PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// the parent pipe will record this for us
+		if p.isChildPipe() {
+			return next(p)
+		}
+
+		entry := TranscriptEntry{
+			Version: TranscriptVersion,
+			Name:    name,
+			Flags:   p.Flags,
+		}
+
+		// what does the command have to work with?
+		stdin, err := ioutil.ReadAll(p.Stdin)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+		entry.Stdin = string(stdin)
+
+		// we have used up the original Stdin, so the command needs
+		// a replacement
+		stdinBuf := ioextra.NewTextBuffer()
+		stdinBuf.Write(stdin)
+		p.Stdin = stdinBuf
+
+		envBefore := environToMap(p.Env.Environ())
+		entry.Env = diffEnvFromProgramEnv(envBefore)
+
+		// capture what the command writes
+		var stdout, stderr bytes.Buffer
+		restore := interceptOutput(p, teeTo(&stdout), teeTo(&stderr))
+
+		statusCode, err := next(p)
+		restore()
+
+		// what did the command do?
+		entry.Stdout = stdout.String()
+		entry.Stderr = stderr.String()
+		entry.EnvChanges = diffEnv(envBefore, environToMap(p.Env.Environ()))
+		entry.StatusCode = statusCode
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		r.record(entry)
+
+		return statusCode, err
+	}
+}
+
+// Entries returns a copy of everything that the Recorder has recorded,
+// oldest first.
+func (r *Recorder) Entries() []TranscriptEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retval := make([]TranscriptEntry, len(r.entries))
+	copy(retval, r.entries)
+	return retval
+}
+
+// Transcript returns everything that the Recorder has recorded, ready
+// to be saved or replayed.
+func (r *Recorder) Transcript() Transcript {
+	return Transcript{
+		Version: TranscriptVersion,
+		Entries: r.Entries(),
+	}
+}
+
+// Err returns the first error that the Recorder hit when writing
+// entries out as JSON Lines.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) record(entry TranscriptEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+
+	// do we need to write the entry out?
+	if r.encoder == nil || r.err != nil {
+		return
+	}
+	r.err = r.encoder.Encode(entry)
+}
+
+// teeTo returns an outputInterceptor that copies everything written to
+// the intercepted TextReaderWriter into buf
+func teeTo(buf *bytes.Buffer) outputInterceptor {
+	return func(next ioextra.TextReaderWriter) func(b []byte) (int, error) {
+		return func(b []byte) (int, error) {
+			n, err := next.Write(b)
+			buf.Write(b[:n])
+			return n, err
+		}
+	}
+}
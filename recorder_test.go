@@ -0,0 +1,233 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// shout is the PipeCommand that we record in our tests
+func shout(p *pipe.Pipe) (int, error) {
+	for line := range p.Stdin.ReadLines() {
+		p.Stdout.WriteString(strings.ToUpper(line) + "\n")
+	}
+	p.Stderr.WriteString("shouted\n")
+	p.Env.Setenv("SHOUTED", "yes")
+	p.Env.Unsetenv("QUIET")
+
+	return pipe.StatusOkay, nil
+}
+
+func TestRecorderRecordsEachCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	unit := pipe.NewPipe(
+		pipe.WithEnvFromMap(map[string]string{"QUIET": "yes"}),
+		pipe.WithMiddleware(recorder.Middleware),
+	)
+	unit.SetStdinFromString("hello\nworld\n")
+	unit.Flags = 4
+
+	expectedResult := []pipe.TranscriptEntry{
+		{
+			Version:    pipe.TranscriptVersion,
+			Name:       "shout",
+			Stdin:      "hello\nworld\n",
+			Env:        pipe.EnvDiff{Set: map[string]string{"QUIET": "yes"}},
+			EnvChanges: pipe.EnvDiff{Set: map[string]string{"SHOUTED": "yes"}, Unset: []string{"QUIET"}},
+			Flags:      4,
+			Stdout:     "HELLO\nWORLD\n",
+			Stderr:     "shouted\n",
+			StatusCode: pipe.StatusOkay,
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(shout)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	actualResult := recorder.Entries()
+
+	// the program's environment is not part of this test
+	actualResult[0].Env.Unset = nil
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, "HELLO\nWORLD\n", unit.Stdout.String())
+	assert.Equal(t, "shouted\n", unit.Stderr.String())
+}
+
+func TestRecorderRecordsStatusCodeAndError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	unit := pipe.NewPipe(pipe.WithMiddleware(recorder.Middleware))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("fail", func(p *pipe.Pipe) (int, error) {
+		return 3, errors.New("alas")
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	entries := recorder.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "fail", entries[0].Name)
+	assert.Equal(t, 3, entries[0].StatusCode)
+	assert.Equal(t, "alas", entries[0].Error)
+	assert.Equal(t, 3, unit.StatusCode())
+}
+
+func TestRecorderLeavesStdinForTheCommandToRead(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	unit := pipe.NewPipe(pipe.WithMiddleware(recorder.Middleware))
+	unit.SetStdinFromString("hello world\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+		p.DrainStdinToStdout()
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "hello world\n", unit.Stdout.String())
+}
+
+func TestRecorderCopesWithCombinedStdoutAndStderr(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	unit := pipe.NewPipe(pipe.WithMiddleware(recorder.Middleware))
+	unit.Stderr = unit.Stdout
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(shout)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "shouted\n", recorder.Entries()[0].Stdout)
+	assert.Equal(t, "shouted\n", unit.Stdout.String())
+	assert.True(t, unit.Stdout == unit.Stderr)
+}
+
+func TestRecorderWritesJSONLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var transcript bytes.Buffer
+	recorder := pipe.NewRecorder(&transcript)
+	unit := pipe.NewPipe(pipe.WithEmptyEnv, pipe.WithMiddleware(recorder.Middleware))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(shout)
+	unit.RunCommand(shout)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, recorder.Err())
+	assert.Equal(t, 2, strings.Count(transcript.String(), "\n"))
+
+	actualResult, err := pipe.ReadTranscript(&transcript)
+	assert.Nil(t, err)
+	assert.Equal(t, recorder.Transcript(), actualResult)
+}
+
+func TestRecorderRecordsChildPipeCommandsOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	unit := pipe.NewPipe(pipe.WithMiddleware(recorder.Middleware))
+	unit.SetStdinFromString("a b c")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("xargs", bracketArgs())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	entries := recorder.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "xargs", entries[0].Name)
+	assert.Equal(t, "[a]\n[b]\n[c]\n", entries[0].Stdout)
+}
@@ -0,0 +1,167 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Replayer is a Middleware that replaces PipeCommands with the output
+// that a Recorder recorded earlier.
+//
+// Use it to re-run a pipeline offline:
+//
+//	transcript, err := pipe.ReadTranscript(transcriptFile)
+//	replayer := pipe.NewReplayer(transcript, "fetchOrders", "fetchPrices")
+//	p := pipe.NewPipe(pipe.WithMiddleware(replayer.Middleware))
+//
+// Each time a replaced PipeCommand runs, the Replayer uses the next
+// recorded entry with the same name. It writes the recorded Stdout and
+// Stderr to the pipe, makes the recorded changes to the pipe's Env, and
+// returns the recorded status code and error. The real PipeCommand does
+// not run.
+type Replayer struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// entries holds the recorded entries that we have not used yet,
+	// grouped by name
+	entries map[string][]TranscriptEntry
+
+	// names holds the names of the PipeCommands that we replace
+	names map[string]bool
+}
+
+// NewReplayer creates a new Replayer from the given transcript.
+//
+// The Replayer replaces PipeCommands that have any of the given names.
+// If you do not give it any names, it replaces every PipeCommand that
+// appears in the transcript.
+func NewReplayer(transcript Transcript, names ...string) *Replayer {
+	retval := Replayer{
+		entries: map[string][]TranscriptEntry{},
+		names:   map[string]bool{},
+	}
+
+	for _, entry := range transcript.Entries {
+		retval.entries[entry.Name] = append(retval.entries[entry.Name], entry)
+	}
+
+	// replace everything we know about?
+	if len(names) == 0 {
+		for name := range retval.entries {
+			retval.names[name] = true
+		}
+	}
+	for _, name := range names {
+		retval.names[name] = true
+	}
+
+	// all done
+	return &retval
+}
+
+// Middleware replaces the given PipeCommand with its recorded output,
+// if it is one of the PipeCommands that the Replayer replaces.
+//
+// Pass it into WithMiddleware.
+func (r *Replayer) Middleware(name string, next PipeCommand) PipeCommand {
+	// are we replacing this command?
+	if !r.names[name] {
+		return next
+	}
+
+	return func(p *Pipe) (int, error) {
+		entry, ok := r.nextEntry(name)
+		if !ok {
+			return StatusNotOkay, ErrTranscriptExhausted{name}
+		}
+
+		// the real command would have used up its input
+		io.Copy(ioutil.Discard, p.Stdin)
+
+		p.Stdout.WriteString(entry.Stdout)
+		if p.Stderr != nil {
+			p.Stderr.WriteString(entry.Stderr)
+		}
+
+		err := applyEnvDiff(p.Env, entry.EnvChanges)
+		if err != nil {
+			return StatusNotOkay, err
+		}
+
+		// what did the real command return?
+		if entry.Error != "" {
+			return entry.StatusCode, ErrReplayedCommand{name, entry.Error}
+		}
+		return entry.StatusCode, nil
+	}
+}
+
+// Remaining returns how many recorded entries the Replayer has not used
+// yet, across all of the PipeCommands that it replaces.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retval := 0
+	for name, entries := range r.entries {
+		if r.names[name] {
+			retval += len(entries)
+		}
+	}
+
+	return retval
+}
+
+func (r *Replayer) nextEntry(name string) (TranscriptEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries[name]
+	if len(entries) == 0 {
+		return TranscriptEntry{}, false
+	}
+
+	r.entries[name] = entries[1:]
+	return entries[0], true
+}
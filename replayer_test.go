@@ -0,0 +1,160 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/ganbarodigital/go_pipe/v7/pipetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayerReplaysRecordedOutput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	recorder := pipe.NewRecorder(nil)
+	recording := pipe.NewPipe(
+		pipe.WithEnvFromMap(map[string]string{"QUIET": "yes"}),
+		pipe.WithMiddleware(recorder.Middleware),
+	)
+	recording.SetStdinFromString("hello\n")
+	recording.RunCommand(shout)
+
+	// the real command must not run during the replay
+	fake := pipetest.NewFake()
+	replayer := pipe.NewReplayer(recorder.Transcript())
+	unit := pipe.NewPipe(
+		pipe.WithEnvFromMap(map[string]string{"QUIET": "yes"}),
+		pipe.WithMiddleware(replayer.Middleware),
+	)
+	unit.SetStdinFromString("hello\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("shout", fake.Run)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Zero(t, fake.CallCount())
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "HELLO\n", unit.Stdout.String())
+	assert.Equal(t, "shouted\n", unit.Stderr.String())
+	assert.Equal(t, []string{"SHOUTED=yes"}, unit.Env.Environ())
+	assert.Zero(t, replayer.Remaining())
+}
+
+func TestReplayerReplaysEntriesInOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	transcript := pipe.Transcript{
+		Version: pipe.TranscriptVersion,
+		Entries: []pipe.TranscriptEntry{
+			{Version: 1, Name: "fetch", StatusCode: 1, Error: "connection refused"},
+			{Version: 1, Name: "fetch", Stdout: "data"},
+		},
+	}
+	replayer := pipe.NewReplayer(transcript)
+	unit := pipe.NewPipe(pipe.WithMiddleware(replayer.Middleware))
+	fake := pipetest.NewFake()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("fetch", fake.Run)
+	firstStatus, firstErr := unit.StatusError()
+
+	unit.RunNamedCommand("fetch", fake.Run)
+	secondStatus, secondErr := unit.StatusError()
+
+	unit.RunNamedCommand("fetch", fake.Run)
+	thirdStatus, thirdErr := unit.StatusError()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 1, firstStatus)
+	assert.Equal(t, pipe.ErrReplayedCommand{"fetch", "connection refused"}, firstErr)
+	assert.Equal(t, pipe.StatusOkay, secondStatus)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, pipe.StatusNotOkay, thirdStatus)
+	assert.Equal(t, pipe.ErrTranscriptExhausted{"fetch"}, thirdErr)
+	assert.Equal(t, "data", unit.Stdout.String())
+	assert.Zero(t, fake.CallCount())
+}
+
+func TestReplayerOnlyReplacesTheNamedCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	transcript := pipe.Transcript{
+		Version: pipe.TranscriptVersion,
+		Entries: []pipe.TranscriptEntry{
+			{Version: 1, Name: "fetch", Stdout: "recorded fetch\n"},
+			{Version: 1, Name: "parse", Stdout: "recorded parse\n"},
+		},
+	}
+	replayer := pipe.NewReplayer(transcript, "fetch")
+	unit := pipe.NewPipe(pipe.WithMiddleware(replayer.Middleware))
+	fake := pipetest.NewFake().WithStdout("real parse\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("fetch", fake.Run)
+	unit.RunNamedCommand("parse", fake.Run)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "recorded fetch\nreal parse\n", unit.Stdout.String())
+	assert.Equal(t, 1, fake.CallCount())
+	assert.Zero(t, replayer.Remaining())
+}
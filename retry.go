@@ -0,0 +1,282 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"time"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// RetryPolicy tells Retry how to retry a PipeCommand.
+type RetryPolicy struct {
+	// Attempts is the most times that we will run the PipeCommand,
+	// including the first time. Anything less than 1 means 1.
+	Attempts int
+
+	// InitialDelay is how long we wait before the second attempt
+	InitialDelay time.Duration
+
+	// Multiplier is how much longer we wait before each attempt after
+	// that. 0 means 2, for exponential backoff. Use 1 to always wait
+	// for InitialDelay.
+	Multiplier float64
+
+	// MaxDelay is the longest that we will wait between attempts.
+	// 0 means no limit.
+	MaxDelay time.Duration
+
+	// Jitter is how much of each delay (from 0 to 1) we take away at
+	// random. It stops lots of clients from retrying at the same time.
+	Jitter float64
+
+	// Retryable decides whether a failed attempt is worth retrying.
+	// If it is nil, we retry every failure.
+	Retryable func(statusCode int, err error) bool
+
+	// ReplayStdin makes every attempt start with the same Stdin. We have
+	// to read all of the pipe's Stdin into memory to do this.
+	//
+	// If it is false, each attempt gets whatever the previous attempts
+	// have not read.
+	ReplayStdin bool
+
+	// OnAttempt, if set, is called after every attempt
+	OnAttempt func(attempt RetryAttempt)
+}
+
+// RetryAttempt describes a single attempt made by Retry.
+type RetryAttempt struct {
+	// Attempt is which attempt this was, starting at 1
+	Attempt int
+
+	// StatusCode is the status code that the PipeCommand returned
+	StatusCode int
+
+	// Err is the error that the PipeCommand returned
+	Err error
+
+	// Duration is how long the attempt took
+	Duration time.Duration
+
+	// Delay is how long we waited before the next attempt. It is 0
+	// if there was no next attempt.
+	Delay time.Duration
+}
+
+// Retry returns a PipeCommand that runs cmd, and runs it again if it
+// fails, following the given policy.
+//
+// Each attempt writes into its own Stdout and Stderr buffers. Only the
+// output from the last attempt is copied to the pipe's Stdout and
+// Stderr.
+//
+// If every attempt fails, Retry returns the last attempt's status code,
+// and an ErrRetriesExhausted that lists every attempt. If an attempt
+// fails and the policy says that it is not worth retrying, Retry returns
+// that attempt's status code and error as they are.
+//
+// If the pipe's context is cancelled while we are waiting to retry, we
+// stop, and return the context's error.
+func Retry(cmd PipeCommand, policy RetryPolicy) PipeCommand {
+	// we always make at least one attempt
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		// do we need to keep the Stdin for later?
+		var stdin []byte
+		if policy.ReplayStdin {
+			var err error
+			stdin, err = p.StdinBytes()
+			if err != nil {
+				return StatusNotOkay, err
+			}
+		}
+
+		attempts := make([]RetryAttempt, 0, policy.Attempts)
+		for attemptNo := 1; ; attemptNo++ {
+			if policy.ReplayStdin {
+				p.SetStdinFromBytes(stdin)
+			}
+
+			start := time.Now()
+			statusCode, output, err := runRetryAttempt(p, cmd)
+			attempt := RetryAttempt{
+				Attempt:    attemptNo,
+				StatusCode: statusCode,
+				Err:        err,
+				Duration:   time.Since(start),
+			}
+
+			// are we done?
+			failed := err != nil || statusCode != StatusOkay
+			retryable := failed && policy.isRetryable(statusCode, err)
+			if !retryable || attemptNo >= policy.Attempts {
+				policy.recordAttempt(attempt)
+				output.copyTo(p)
+				output.discard(p)
+
+				if retryable {
+					return statusCode, ErrRetriesExhausted{append(attempts, attempt)}
+				}
+				return statusCode, err
+			}
+
+			// we do not need this attempt's output
+			output.discard(p)
+
+			// wait before we try again
+			attempt.Delay = policy.delay(attemptNo)
+			policy.recordAttempt(attempt)
+			attempts = append(attempts, attempt)
+
+			timer := time.NewTimer(attempt.Delay)
+			select {
+			case <-timer.C:
+			case <-p.Context().Done():
+				timer.Stop()
+				return StatusNotOkay, p.Context().Err()
+			}
+		}
+	}
+}
+
+// retryOutput holds what a single attempt wrote
+type retryOutput struct {
+	stdout ioextra.TextReaderWriter
+
+	// stderr is nil if the pipe's Stdout and Stderr are the same
+	stderr ioextra.TextReaderWriter
+}
+
+// copyTo writes the output into the pipe's Stdout and Stderr
+func (o retryOutput) copyTo(p *Pipe) {
+	io.Copy(p.Stdout, o.stdout)
+	if o.stderr != nil {
+		io.Copy(p.Stderr, o.stderr)
+	}
+}
+
+// discard closes the output's buffers, now that we have finished with
+// them
+func (o retryOutput) discard(p *Pipe) {
+	for _, buf := range []ioextra.TextReaderWriter{o.stdout, o.stderr} {
+		if buf != nil {
+			p.releaseBuffer(buf)
+			p.closeSpillBuffer(buf)
+		}
+	}
+}
+
+// runRetryAttempt runs cmd once, with its own Stdout and Stderr
+func runRetryAttempt(p *Pipe, cmd PipeCommand) (int, retryOutput, error) {
+	output := retryOutput{stdout: p.newTextBuffer()}
+
+	// do Stdout and Stderr point at each other?
+	if p.Stdout == p.Stderr {
+		// yes, so PushStdout takes care of both
+		p.PushStdout(output.stdout)
+		defer p.PopStdout()
+	} else {
+		output.stderr = p.newTextBuffer()
+		p.PushStdout(output.stdout)
+		p.PushStderr(output.stderr)
+		defer p.PopStdout()
+		defer p.PopStderr()
+	}
+
+	statusCode, err := cmd(p)
+	return statusCode, output, err
+}
+
+// isRetryable returns true if the policy says that a failure is worth
+// retrying
+func (policy RetryPolicy) isRetryable(statusCode int, err error) bool {
+	if policy.Retryable == nil {
+		return true
+	}
+
+	return policy.Retryable(statusCode, err)
+}
+
+// recordAttempt tells the policy's OnAttempt about the given attempt
+func (policy RetryPolicy) recordAttempt(attempt RetryAttempt) {
+	if policy.OnAttempt != nil {
+		policy.OnAttempt(attempt)
+	}
+}
+
+// delay works out how long to wait after the given attempt
+func (policy RetryPolicy) delay(attemptNo int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := float64(policy.InitialDelay) * math.Pow(multiplier, float64(attemptNo-1))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+
+	// don't go past what a time.Duration can hold
+	longest := float64(math.MaxInt64)
+	if !(delay < longest) {
+		delay = longest
+	}
+
+	jitter := math.Max(0, math.Min(1, policy.Jitter))
+	delay -= delay * jitter * rand.Float64()
+
+	// float64 cannot hold math.MaxInt64 exactly, so we check again
+	if delay >= longest {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,358 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyCommand returns a PipeCommand that fails the given number of
+// times, and then works. Each attempt echoes its Stdin to Stdout.
+func flakyCommand(failures int, err error) (pipe.PipeCommand, *int) {
+	calls := 0
+	return func(p *pipe.Pipe) (int, error) {
+		calls++
+		stdin, _ := p.StdinBytes()
+		p.Stdout.Write(stdin)
+		if calls <= failures {
+			p.Stderr.WriteString("attempt failed\n")
+			return pipe.StatusNotOkay, err
+		}
+
+		return pipe.StatusOkay, nil
+	}, &calls
+}
+
+func TestRetryRunsTheCommandAgainUntilItWorks(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmd, calls := flakyCommand(2, errors.New("connection refused"))
+	var attempts []pipe.RetryAttempt
+	policy := pipe.RetryPolicy{
+		Attempts:     5,
+		InitialDelay: time.Millisecond,
+		ReplayStdin:  true,
+		OnAttempt: func(attempt pipe.RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, policy))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, 3, *calls)
+
+	// only the output from the attempt that worked is kept
+	assert.Equal(t, "hello\n", unit.Stdout.String())
+	assert.Equal(t, "", unit.Stderr.String())
+
+	assert.Len(t, attempts, 3)
+	assert.Equal(t, 1, attempts[0].Attempt)
+	assert.EqualError(t, attempts[0].Err, "connection refused")
+	assert.Equal(t, time.Millisecond, attempts[0].Delay)
+	assert.Equal(t, 2*time.Millisecond, attempts[1].Delay)
+	assert.Equal(t, 3, attempts[2].Attempt)
+	assert.Nil(t, attempts[2].Err)
+	assert.Equal(t, time.Duration(0), attempts[2].Delay)
+}
+
+func TestRetryGivesUpAfterTheLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmdErr := errors.New("connection refused")
+	cmd, calls := flakyCommand(10, cmdErr)
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, pipe.RetryPolicy{Attempts: 3}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+
+	err, ok := unit.Error().(pipe.ErrRetriesExhausted)
+	assert.True(t, ok)
+	assert.Len(t, err.Attempts, 3)
+	assert.True(t, errors.Is(unit.Error(), cmdErr))
+
+	// the last attempt's output is kept
+	assert.Equal(t, "attempt failed\n", unit.Stderr.String())
+}
+
+func TestRetryAlwaysMakesAtLeastOneAttempt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		attempts int
+	}{
+		{"zero attempts", 0},
+		{"negative attempts", -1},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			cmdErr := errors.New("connection refused")
+			cmd, calls := flakyCommand(10, cmdErr)
+			unit := pipe.NewPipe()
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.Retry(cmd, pipe.RetryPolicy{Attempts: testCase.attempts}))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Equal(t, 1, *calls)
+			assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+
+			err, ok := unit.Error().(pipe.ErrRetriesExhausted)
+			assert.True(t, ok)
+			assert.Len(t, err.Attempts, 1)
+		})
+	}
+}
+
+func TestRetryStopsOnFailuresThatAreNotRetryable(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmdErr := errors.New("permission denied")
+	cmd, calls := flakyCommand(10, cmdErr)
+	policy := pipe.RetryPolicy{
+		Attempts: 3,
+		Retryable: func(statusCode int, err error) bool {
+			return err != cmdErr
+		},
+	}
+	unit := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, policy))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, cmdErr, unit.Error())
+}
+
+func TestRetryWithoutReplayStdinSharesTheStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmd, _ := flakyCommand(1, errors.New("try again"))
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, pipe.RetryPolicy{Attempts: 2}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// the first attempt used up the Stdin
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestRetryKeepsStdoutAndStderrTogether(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmd, _ := flakyCommand(1, errors.New("try again"))
+	unit := pipe.NewPipe()
+	unit.Stderr = unit.Stdout
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, pipe.RetryPolicy{Attempts: 2}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Same(t, unit.Stdout, unit.Stderr)
+	assert.Equal(t, 0, unit.StdoutStackLen())
+}
+
+func TestRetryBackoffIsCappedAndJittered(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cmd, _ := flakyCommand(10, errors.New("try again"))
+	var delays []time.Duration
+	policy := pipe.RetryPolicy{
+		Attempts:     6,
+		InitialDelay: time.Millisecond,
+		Multiplier:   3,
+		MaxDelay:     4 * time.Millisecond,
+		Jitter:       0.5,
+		OnAttempt: func(attempt pipe.RetryAttempt) {
+			delays = append(delays, attempt.Delay)
+		},
+	}
+	maxDelays := []time.Duration{1, 3, 4, 4, 4, 0}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	pipe.NewPipe().RunCommand(pipe.Retry(cmd, policy))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, delays, len(maxDelays))
+	for i, delay := range delays {
+		maxDelay := maxDelays[i] * time.Millisecond
+		assert.True(t, delay <= maxDelay, "delay %d is %s", i, delay)
+		assert.True(t, delay >= maxDelay/2, "delay %d is %s", i, delay)
+	}
+}
+
+func TestRetryStopsWhenTheContextIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, calls := flakyCommand(10, errors.New("try again"))
+	policy := pipe.RetryPolicy{
+		Attempts:     3,
+		InitialDelay: time.Hour,
+		OnAttempt: func(pipe.RetryAttempt) {
+			cancel()
+		},
+	}
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, policy))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, context.Canceled, unit.Error())
+}
+
+func TestRetryBackoffDoesNotOverflow(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, calls := flakyCommand(10, errors.New("try again"))
+	delays := []time.Duration{}
+	policy := pipe.RetryPolicy{
+		Attempts:     3,
+		InitialDelay: time.Nanosecond,
+		Multiplier:   1e300,
+		OnAttempt: func(attempt pipe.RetryAttempt) {
+			delays = append(delays, attempt.Delay)
+			if attempt.Attempt == 2 {
+				cancel()
+			}
+		},
+	}
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Retry(cmd, policy))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, context.Canceled, unit.Error())
+	assert.Equal(t, []time.Duration{time.Nanosecond, math.MaxInt64}, delays)
+}
@@ -0,0 +1,198 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// signalState is shared between a pipe and its child pipes, once
+// WithSignals has been used
+type signalState struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// received is the signal that we caught, or nil if we haven't
+	// caught one yet
+	received os.Signal
+
+	// processes are the external processes that we pass signals on to
+	processes map[*os.Process]struct{}
+}
+
+// WithSignals returns a PipeOption that binds the given OS signals to
+// the pipe. If you don't give us any signals, we use os.Interrupt
+// (SIGINT) and SIGTERM.
+//
+// When the program receives one of the signals, we:
+//
+//   - pass it on to every process registered with TrackProcess
+//   - cancel the pipe's context (see Pipe.Context)
+//   - make RunCommand report a status code of 128 plus the signal's
+//     number, and an ErrSignal, like a UNIX shell does
+//   - run the pipe's TrapCancel traps, when the PipeCommand that was
+//     running returns
+//
+// After the first signal, we stop catching the signals, so that sending
+// a second one does whatever it would normally do (such as stopping your
+// program).
+//
+// We stop watching for signals when Pipe.Exit is called, or when the
+// pipe's original context is done.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithSignals(signals ...os.Signal) PipeOption {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		if len(signals) == 0 {
+			signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+		}
+
+		// our context must outlive any span that we are running in
+		// (see WithTracer)
+		state := &signalState{processes: map[*os.Process]struct{}{}}
+		ctx, cancel := context.WithCancel(baseContext(p.Context()))
+		p.signals = state
+		p.ctx = ctx
+
+		caught := make(chan os.Signal, 1)
+		stopped := make(chan struct{})
+		signal.Notify(caught, signals...)
+
+		go func() {
+			defer signal.Stop(caught)
+
+			select {
+			case sig := <-caught:
+				state.forward(sig)
+				cancel()
+			case <-ctx.Done():
+			case <-stopped:
+			}
+		}()
+
+		// stop watching when the pipe exits
+		var once sync.Once
+		p.Trap(TrapExit, func(*Pipe) (int, error) {
+			once.Do(func() { close(stopped) })
+			return StatusOkay, nil
+		})
+
+		// all done
+		return StatusOkay, nil
+	}
+}
+
+// TrackProcess tells the pipe about an external process that one of its
+// PipeCommands has started, so that the pipe can pass on any signals
+// that it receives (see WithSignals). Call the function that it returns
+// once the process has finished.
+//
+// It does nothing if the pipe is not using WithSignals.
+func (p *Pipe) TrackProcess(proc *os.Process) func() {
+	// do we have anything to track?
+	if p == nil || p.signals == nil || proc == nil {
+		return func() {}
+	}
+
+	// yes we do
+	state := p.signals
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	// if we're too late, pass the signal straight on
+	if state.received != nil {
+		proc.Signal(state.received)
+	}
+	state.processes[proc] = struct{}{}
+
+	return func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		delete(state.processes, proc)
+	}
+}
+
+// Signal returns the OS signal that the pipe has received, or nil if it
+// has not received one (see WithSignals).
+func (p *Pipe) Signal() os.Signal {
+	// do we have anything to look at?
+	if p == nil || p.signals == nil {
+		return nil
+	}
+
+	// yes we do
+	p.signals.mu.Lock()
+	defer p.signals.mu.Unlock()
+
+	return p.signals.received
+}
+
+// forward remembers that we received sig, and passes it on to every
+// process that we are tracking
+func (s *signalState) forward(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received = sig
+	for proc := range s.processes {
+		proc.Signal(sig)
+	}
+}
+
+// signalStatusCode returns the status code that a UNIX shell reports
+// for a process that was stopped by sig
+func signalStatusCode(sig os.Signal) int {
+	if number, ok := sig.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+
+	return StatusNotOkay
+}
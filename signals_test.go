@@ -0,0 +1,224 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//go:build !windows
+// +build !windows
+
+package pipe_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// these tests send signals to the test process itself, so they must not
+// run in parallel with each other
+
+// waitForSignal returns a PipeCommand that sends sig to the test
+// process, and then waits for the pipe's context to be cancelled
+func waitForSignal(sig syscall.Signal) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		syscall.Kill(os.Getpid(), sig)
+
+		select {
+		case <-p.Context().Done():
+			return pipe.StatusNotOkay, p.Context().Err()
+		case <-time.After(5 * time.Second):
+			return pipe.StatusOkay, nil
+		}
+	}
+}
+
+func TestWithSignalsSetsStatusCodeFromTheSignal(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(pipe.WithSignals(syscall.SIGUSR1))
+	defer p.Exit()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(waitForSignal(syscall.SIGUSR1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 128+int(syscall.SIGUSR1), p.StatusCode())
+	assert.Equal(t, pipe.ErrSignal{Signal: syscall.SIGUSR1}, p.Error())
+	assert.Equal(t, syscall.SIGUSR1, p.Signal())
+}
+
+func TestWithSignalsRunsCancelTraps(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(pipe.WithSignals(syscall.SIGUSR1))
+	defer p.Exit()
+
+	seen := []string{}
+	p.Trap(pipe.TrapCancel, recordTrap("cancel", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(waitForSignal(syscall.SIGUSR1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"cancel:interrupted by signal: user defined signal 1"}, seen)
+}
+
+func TestWithSignalsForwardsSignalsToTrackedProcesses(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(pipe.WithSignals(syscall.SIGUSR2))
+	defer p.Exit()
+
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer cmd.Process.Kill()
+
+	untrack := p.TrackProcess(cmd.Process)
+	defer untrack()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(waitForSignal(syscall.SIGUSR2))
+	err = cmd.Wait()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	assert.True(t, status.Signaled())
+	assert.Equal(t, syscall.SIGUSR2, status.Signal())
+}
+
+func TestWithSignalsIsSharedWithChildPipes(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(pipe.WithSignals(syscall.SIGUSR1))
+	defer p.Exit()
+
+	child := p.NewChildPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	child.RunCommand(waitForSignal(syscall.SIGUSR1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 128+int(syscall.SIGUSR1), child.StatusCode())
+	assert.Equal(t, syscall.SIGUSR1, p.Signal())
+}
+
+func TestPipeSignalReturnsNilWithoutWithSignals(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := p.Signal()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+}
+
+func TestPipeTrackProcessDoesNothingWithoutWithSignals(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	untrack := p.TrackProcess(&os.Process{Pid: os.Getpid()})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, untrack)
+	untrack()
+}
+
+func TestWithSignalsWorksAfterWithTracer(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	p := pipe.NewPipe(
+		pipe.WithTracer(pipe.NewMemoryTracer()),
+		pipe.WithSignals(syscall.SIGUSR1),
+	)
+	defer p.Exit()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	p.RunCommand(waitForSignal(syscall.SIGUSR1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 128+int(syscall.SIGUSR1), p.StatusCode())
+	assert.Equal(t, context.Canceled, p.Context().Err())
+}
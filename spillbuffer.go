@@ -0,0 +1,294 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// SpillBuffer is a TextReaderWriter that keeps up to a fixed number of
+// bytes in memory. Once it holds more than that, it moves everything into
+// a temporary file, and carries on there.
+//
+// It behaves like ioextra.TextBuffer: reads take data out of the buffer,
+// and String() returns whatever is left without taking it out.
+//
+// Call Close when you have finished with it, to remove the temporary
+// file. Pipe.ResetBuffers and Pipe.Close do this for you.
+type SpillBuffer struct {
+	// how many bytes we keep in memory before spilling
+	maxMemory int64
+
+	// where to create our temporary file; "" means os.TempDir()
+	tempDir string
+
+	// our data, until we spill
+	mem bytes.Buffer
+
+	// our data, once we have spilled
+	file        *os.File
+	readOffset  int64
+	writeOffset int64
+}
+
+// NewSpillBuffer creates a new, empty SpillBuffer. It keeps up to
+// maxMemory bytes in memory, and spills into a temporary file in tempDir
+// after that. If tempDir is empty, it uses os.TempDir().
+func NewSpillBuffer(maxMemory int64, tempDir string) *SpillBuffer {
+	retval := &SpillBuffer{
+		maxMemory: maxMemory,
+		tempDir:   tempDir,
+	}
+
+	// make sure the temporary file goes, even if nobody calls Close
+	runtime.SetFinalizer(retval, (*SpillBuffer).Close)
+
+	// all done
+	return retval
+}
+
+// Spilled returns true if the buffer has moved its data into a
+// temporary file.
+func (b *SpillBuffer) Spilled() bool {
+	return b.file != nil
+}
+
+// Len returns how many unread bytes the buffer holds.
+func (b *SpillBuffer) Len() int64 {
+	if b.file == nil {
+		return int64(b.mem.Len())
+	}
+
+	return b.writeOffset - b.readOffset
+}
+
+// Close empties the buffer, and removes its temporary file (if it has
+// one). You can carry on using the buffer afterwards.
+func (b *SpillBuffer) Close() error {
+	b.mem.Reset()
+	if b.file == nil {
+		return nil
+	}
+
+	// we want both of these to happen, whatever goes wrong
+	closeErr := b.file.Close()
+	removeErr := os.Remove(b.file.Name())
+
+	b.file = nil
+	b.readOffset = 0
+	b.writeOffset = 0
+
+	if closeErr != nil {
+		return closeErr
+	}
+	return removeErr
+}
+
+// closeSpillBuffer closes the given buffer, if it is a SpillBuffer, and
+// if the pipe is no longer using it
+func (p *Pipe) closeSpillBuffer(buf interface{}) {
+	spill, ok := buf.(*SpillBuffer)
+	if !ok || p.isUsingBuffer(spill) {
+		return
+	}
+
+	spill.Close()
+}
+
+// ================================================================
+//
+// TextWriter
+//
+// ----------------------------------------------------------------
+
+// Write adds the given bytes to the end of the buffer.
+func (b *SpillBuffer) Write(buf []byte) (int, error) {
+	// does it still fit into memory?
+	if b.file == nil && int64(b.mem.Len()+len(buf)) <= b.maxMemory {
+		return b.mem.Write(buf)
+	}
+
+	// no, it does not
+	if b.file == nil {
+		err := b.spill()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := b.file.WriteAt(buf, b.writeOffset)
+	b.writeOffset += int64(n)
+	return n, err
+}
+
+// WriteString adds the given string to the end of the buffer.
+func (b *SpillBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// WriteRune adds the UTF-8 encoding of the given rune to the end of the
+// buffer.
+func (b *SpillBuffer) WriteRune(r rune) (int, error) {
+	return b.Write([]byte(string(r)))
+}
+
+// spill moves our data from memory into a temporary file
+func (b *SpillBuffer) spill() error {
+	file, err := ioutil.TempFile(b.tempDir, "go_pipe-spill-")
+	if err != nil {
+		return err
+	}
+
+	n, err := file.Write(b.mem.Bytes())
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	b.file = file
+	b.readOffset = 0
+	b.writeOffset = int64(n)
+	b.mem.Reset()
+
+	return nil
+}
+
+// ================================================================
+//
+// TextReader
+//
+// ----------------------------------------------------------------
+
+// Read takes the next len(buf) bytes out of the buffer.
+func (b *SpillBuffer) Read(buf []byte) (int, error) {
+	if b.file == nil {
+		return b.mem.Read(buf)
+	}
+
+	// have we read everything?
+	if b.readOffset >= b.writeOffset {
+		// start the file again, so that it does not keep growing
+		b.file.Truncate(0)
+		b.readOffset = 0
+		b.writeOffset = 0
+
+		if len(buf) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+
+	// never read past what has been written
+	if remaining := b.writeOffset - b.readOffset; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+
+	n, err := b.file.ReadAt(buf, b.readOffset)
+	b.readOffset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Bytes returns a copy of all of the unread data in the buffer. It does
+// not take the data out of the buffer.
+func (b *SpillBuffer) Bytes() []byte {
+	if b.file == nil {
+		return append([]byte(nil), b.mem.Bytes()...)
+	}
+
+	retval, _ := ioutil.ReadAll(io.NewSectionReader(b.file, b.readOffset, b.Len()))
+	return retval
+}
+
+// ParseInt returns the data in the buffer as an integer.
+//
+// If the buffer contains anything other than a valid number, an error
+// is returned.
+func (b *SpillBuffer) ParseInt() (int, error) {
+	return strconv.Atoi(b.TrimmedString())
+}
+
+// ReadLines returns a channel that you can `range` over to get each
+// line from the buffer
+func (b *SpillBuffer) ReadLines() <-chan string {
+	return ioextra.NewTextScanner(b, bufio.ScanLines)
+}
+
+// ReadWords returns a channel that you can `range` over to get each
+// word from the buffer
+func (b *SpillBuffer) ReadWords() <-chan string {
+	return ioextra.NewTextScanner(b, bufio.ScanWords)
+}
+
+// String returns all of the unread data in the buffer as a single
+// (possibly multi-line) string. It does not take the data out of the
+// buffer.
+func (b *SpillBuffer) String() string {
+	return string(b.Bytes())
+}
+
+// Strings returns all of the data in the buffer as an array of
+// strings, one line per array entry
+func (b *SpillBuffer) Strings() []string {
+	retval := []string{}
+	for line := range b.ReadLines() {
+		retval = append(retval, line)
+	}
+
+	return retval
+}
+
+// TrimmedString returns all of the unread data in the buffer as a
+// string, with any leading or trailing whitespace removed.
+func (b *SpillBuffer) TrimmedString() string {
+	return strings.TrimSpace(b.String())
+}
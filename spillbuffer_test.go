@@ -0,0 +1,227 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// spillFiles returns the names of the files in the given directory
+func spillFiles(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+
+	retval := []string{}
+	for _, entry := range entries {
+		retval = append(retval, entry.Name())
+	}
+
+	return retval
+}
+
+func TestSpillBufferIsATextReaderWriter(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit ioextra.TextReaderWriter
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit = pipe.NewSpillBuffer(10, "")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, unit)
+}
+
+func TestSpillBufferKeepsSmallOutputInMemory(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewSpillBuffer(10, dir)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.WriteString("0123456789")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, unit.Spilled())
+	assert.Equal(t, int64(10), unit.Len())
+	assert.Equal(t, "0123456789", unit.String())
+	assert.Empty(t, spillFiles(t, dir))
+}
+
+func TestSpillBufferSpillsLargeOutputToDisk(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewSpillBuffer(10, dir)
+	defer unit.Close()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.WriteString("line one\n")
+	unit.WriteString("line two\n")
+	unit.WriteRune('é')
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, unit.Spilled())
+	assert.Equal(t, int64(20), unit.Len())
+	assert.Equal(t, "line one\nline two\né", unit.String())
+	assert.Len(t, spillFiles(t, dir), 1)
+
+	// String() does not take anything out of the buffer
+	assert.Equal(t, []string{"line one", "line two", "é"}, unit.Strings())
+	assert.Equal(t, int64(0), unit.Len())
+}
+
+func TestSpillBufferReadTakesDataOutOfTheBuffer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewSpillBuffer(4, "")
+	defer unit.Close()
+	unit.WriteString("abcdefgh")
+	buf := make([]byte, 3)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	n, err := unit.Read(buf)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", string(buf[:n]))
+	assert.Equal(t, "defgh", unit.String())
+
+	rest, err := ioutil.ReadAll(unit)
+	assert.Nil(t, err)
+	assert.Equal(t, "defgh", string(rest))
+
+	// and we can carry on writing afterwards
+	unit.WriteString("ij")
+	assert.Equal(t, "ij", unit.String())
+}
+
+func TestSpillBufferCloseRemovesTheTemporaryFile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewSpillBuffer(4, dir)
+	unit.WriteString(strings.Repeat("x", 100))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err = unit.Close()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.False(t, unit.Spilled())
+	assert.Equal(t, "", unit.String())
+	assert.Empty(t, spillFiles(t, dir))
+}
+
+func TestSpillBufferReportsTemporaryFileErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "spillbuffer-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	unit := pipe.NewSpillBuffer(4, filepath.Join(dir, "does-not-exist"))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	n, err := unit.WriteString("too long for memory")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, unit.Spilled())
+}
@@ -0,0 +1,216 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// StatusTimeout is the status code returned by Timeout, when the
+// PipeCommand takes too long. It is the same status code that timeout(1)
+// uses.
+const StatusTimeout = 124
+
+// Timeout returns a PipeCommand that runs cmd, and gives up on it if it
+// has not finished within the given duration.
+//
+// If cmd takes too long, Timeout returns StatusTimeout and an ErrTimeout.
+// If the pipe's own context is cancelled first, Timeout returns
+// StatusNotOkay and the context's error.
+//
+// cmd runs against a child pipe (see NewChildPipe), whose context is
+// cancelled when the time is up. PipeCommands that watch Pipe.Context
+// can use this to stop early.
+//
+// cmd writes into its own Stdout and Stderr buffers. They are copied to
+// the pipe's Stdout and Stderr only if cmd finishes in time, so anything
+// written after the deadline is thrown away.
+//
+// If cmd panics, Timeout returns StatusNotOkay and an ErrPanic.
+//
+// Golang has no way to stop a goroutine, so a PipeCommand that does not
+// watch the context keeps running in the background. Once the time is
+// up, it cannot read any more from the pipe's Stdin: it sees the end of
+// its input instead. The pipe's Stdin holds whatever cmd did not read,
+// except that a read which was already under way when the time ran out
+// may still finish, so don't rely on what is left in it.
+func Timeout(d time.Duration, cmd PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil {
+			return StatusOkay, nil
+		}
+
+		ctx, cancel := context.WithTimeout(p.Context(), d)
+		defer cancel()
+
+		// the command gets a pipe of its own, so that it can carry on
+		// in the background without getting in our way
+		child := p.NewChildPipe()
+		child.ctx = ctx
+		var stdin *detachableTextReader
+		if p.Stdin != nil {
+			stdin = &detachableTextReader{TextReader: p.Stdin}
+			child.Stdin = stdin
+		}
+		if p.Stdout == p.Stderr {
+			child.Stderr = child.Stdout
+		}
+
+		type result struct {
+			statusCode int
+			err        error
+		}
+		done := make(chan result, 1)
+		go func() {
+			// a panic here would take the whole program down, without
+			// giving anyone the chance to recover it
+			defer func() {
+				if v := recover(); v != nil {
+					done <- result{StatusNotOkay, ErrPanic{v}}
+				}
+			}()
+
+			statusCode, err := cmd(child)
+			done <- result{statusCode, err}
+		}()
+
+		select {
+		case res := <-done:
+			// did it finish in time?
+			if ctx.Err() == nil {
+				io.Copy(p.Stdout, child.Stdout)
+				if child.Stderr != child.Stdout {
+					io.Copy(p.Stderr, child.Stderr)
+				}
+				child.Close()
+				return res.statusCode, res.err
+			}
+			child.Close()
+		case <-ctx.Done():
+			// tidy up once the command finally finishes
+			go func() {
+				<-done
+				child.Close()
+			}()
+		}
+
+		// if we get here, we have run out of time
+		if stdin != nil {
+			stdin.detach()
+		}
+
+		// whose deadline was it?
+		if err := p.Context().Err(); err != nil {
+			return StatusNotOkay, err
+		}
+		return StatusTimeout, ErrTimeout{d}
+	}
+}
+
+// detachableTextReader passes reads through to a TextReader, until it
+// is detached. After that, it behaves like an empty TextReader.
+type detachableTextReader struct {
+	ioextra.TextReader
+
+	// detached is 1 once detach has been called
+	detached int32
+}
+
+// detach stops any more reads reaching the underlying TextReader
+func (d *detachableTextReader) detach() {
+	atomic.StoreInt32(&d.detached, 1)
+}
+
+// isDetached returns true once detach has been called
+func (d *detachableTextReader) isDetached() bool {
+	return atomic.LoadInt32(&d.detached) == 1
+}
+
+func (d *detachableTextReader) Read(b []byte) (int, error) {
+	if d.isDetached() {
+		return 0, io.EOF
+	}
+
+	return d.TextReader.Read(b)
+}
+
+func (d *detachableTextReader) ParseInt() (int, error) {
+	return strconv.Atoi(d.TrimmedString())
+}
+
+// ReadLines reads through us, so that it stops once we are detached
+func (d *detachableTextReader) ReadLines() <-chan string {
+	return ioextra.NewTextScanner(d, bufio.ScanLines)
+}
+
+// ReadWords reads through us, so that it stops once we are detached
+func (d *detachableTextReader) ReadWords() <-chan string {
+	return ioextra.NewTextScanner(d, bufio.ScanWords)
+}
+
+func (d *detachableTextReader) String() string {
+	if d.isDetached() {
+		return ""
+	}
+
+	return d.TextReader.String()
+}
+
+func (d *detachableTextReader) Strings() []string {
+	retval := []string{}
+	for line := range d.ReadLines() {
+		retval = append(retval, line)
+	}
+
+	return retval
+}
+
+func (d *detachableTextReader) TrimmedString() string {
+	return strings.TrimSpace(d.String())
+}
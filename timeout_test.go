@@ -0,0 +1,234 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutLetsFastCommandsFinish(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(time.Minute, func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString(p.Stdin.String())
+		p.Stderr.WriteString("warning\n")
+		return 3, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 3, unit.StatusCode())
+	assert.Equal(t, "hello\n", unit.Stdout.String())
+	assert.Equal(t, "warning\n", unit.Stderr.String())
+}
+
+func TestTimeoutStopsSlowCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	release := make(chan struct{})
+	defer close(release)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(10*time.Millisecond, func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("before the deadline\n")
+		<-release
+		p.Stdout.WriteString("after the deadline\n")
+		return pipe.StatusOkay, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusTimeout, unit.StatusCode())
+	assert.Equal(t, pipe.ErrTimeout{10 * time.Millisecond}, unit.Error())
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestTimeoutCancelsThePipeContext(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	stopped := make(chan struct{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(10*time.Millisecond, func(p *pipe.Pipe) (int, error) {
+		<-p.Context().Done()
+		p.Stdout.WriteString("cleaning up\n")
+		close(stopped)
+		return pipe.StatusNotOkay, p.Context().Err()
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	<-stopped
+	assert.Equal(t, pipe.StatusTimeout, unit.StatusCode())
+	assert.Equal(t, pipe.ErrTimeout{10 * time.Millisecond}, unit.Error())
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestTimeoutReportsTheParentContextFirst(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(time.Minute, func(p *pipe.Pipe) (int, error) {
+		cancel()
+		<-p.Context().Done()
+		return pipe.StatusNotOkay, p.Context().Err()
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.Equal(t, context.Canceled, unit.Error())
+}
+
+func TestTimeoutKeepsStdoutAndStderrTogether(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.Stderr = unit.Stdout
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(time.Minute, func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("one\n")
+		p.Stderr.WriteString("two\n")
+		p.Stdout.WriteString("three\n")
+		return pipe.StatusOkay, nil
+	}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "one\ntwo\nthree\n", unit.Stdout.String())
+}
+
+func TestTimeoutRecoversPanics(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	cmd := func(p *pipe.Pipe) (int, error) {
+		panic("oh no")
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(time.Second, cmd))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusNotOkay, unit.StatusCode())
+	assert.Equal(t, pipe.ErrPanic{Value: "oh no"}, unit.Error())
+}
+
+func TestTimeoutStopsSlowCommandsReadingStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("hello\n")
+	release := make(chan struct{})
+	seen := make(chan string, 1)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Timeout(10*time.Millisecond, func(p *pipe.Pipe) (int, error) {
+		// this command does not watch the context
+		<-release
+		seen <- p.Stdin.String()
+		return pipe.StatusOkay, nil
+	}))
+	close(release)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusTimeout, unit.StatusCode())
+	assert.Equal(t, "", <-seen)
+	assert.Equal(t, "hello\n", unit.Stdin.String())
+}
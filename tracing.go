@@ -0,0 +1,361 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracer is the interface that WithTracer needs from your tracing
+// library.
+//
+// It is modelled on OpenTelemetry, and it is small enough that you can
+// write an adapter for any tracing library in a few lines of code.
+type Tracer interface {
+	// StartSpan starts a new span with the given name. If ctx holds
+	// a span, the new span is its child.
+	//
+	// It returns a context that holds the new span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single timed operation, started by a Tracer.
+type Span interface {
+	// SetAttribute adds a key/value pair to the span
+	SetAttribute(key string, value interface{})
+
+	// RecordError marks the span as having failed
+	RecordError(err error)
+
+	// End marks the span as finished
+	End()
+}
+
+// These are the attributes that WithTracer adds to each span.
+const (
+	// TraceAttrCommand is the name of the PipeCommand
+	TraceAttrCommand = "pipe.command"
+
+	// TraceAttrStatusCode is the status code that the PipeCommand returned
+	TraceAttrStatusCode = "pipe.status_code"
+
+	// TraceAttrError is the message of the error that the PipeCommand
+	// returned, if any
+	TraceAttrError = "pipe.error"
+
+	// TraceAttrBytesRead is how many bytes the PipeCommand read from Stdin
+	TraceAttrBytesRead = "pipe.stdin.bytes_read"
+
+	// TraceAttrStdoutBytesWritten is how many bytes the PipeCommand wrote
+	// to Stdout
+	TraceAttrStdoutBytesWritten = "pipe.stdout.bytes_written"
+
+	// TraceAttrStderrBytesWritten is how many bytes the PipeCommand wrote
+	// to Stderr
+	TraceAttrStderrBytesWritten = "pipe.stderr.bytes_written"
+
+	// TraceAttrStdinStackDepth is the depth of the pipe's Stdin stack
+	// when the PipeCommand started
+	TraceAttrStdinStackDepth = "pipe.stdin.stack_depth"
+
+	// TraceAttrStdoutStackDepth is the depth of the pipe's Stdout stack
+	// when the PipeCommand started
+	TraceAttrStdoutStackDepth = "pipe.stdout.stack_depth"
+
+	// TraceAttrStderrStackDepth is the depth of the pipe's Stderr stack
+	// when the PipeCommand started
+	TraceAttrStderrStackDepth = "pipe.stderr.stack_depth"
+)
+
+// WithTracer returns a PipeOption that opens a span around every
+// PipeCommand that the pipe runs via RunCommand.
+//
+// Each span is named after the PipeCommand (see CommandName), and has
+// the TraceAttr... attributes.
+//
+// While the PipeCommand runs, the pipe's context holds its span. Any
+// PipeCommands that it runs, on this pipe or on a child pipe, get spans
+// that are children of its span. If the PipeCommand sets the pipe's
+// context (as WithContext and WithSignals do), we keep its context, but
+// we take the span back out of it: a span never outlives its
+// PipeCommand.
+//
+// You can use this both as a functional option, and/or as a
+// PipeCommand.
+func WithTracer(tracer Tracer) PipeOption {
+	return WithMiddleware(func(name string, next PipeCommand) PipeCommand {
+		return func(p *Pipe) (int, error) {
+			parentCtx := p.ctx
+			spanCtx, span := tracer.StartSpan(p.Context(), name)
+			defer span.End()
+
+			// mark the context, so that we can find it again
+			scope := &traceScope{
+				base:   baseContext(p.Context()),
+				parent: p.Context(),
+			}
+			ctx := context.WithValue(spanCtx, traceScopeKey{}, scope)
+
+			span.SetAttribute(TraceAttrCommand, name)
+			span.SetAttribute(TraceAttrStdinStackDepth, p.StdinStackLen())
+			span.SetAttribute(TraceAttrStdoutStackDepth, p.StdoutStackLen())
+			span.SetAttribute(TraceAttrStderrStackDepth, p.StderrStackLen())
+
+			// count everything that goes in and out
+			counts := countIO(p)
+			p.ctx = ctx
+
+			// put the original context back afterwards, unless the
+			// PipeCommand has set a context of its own
+			defer func() {
+				switch {
+				case p.ctx == ctx:
+					p.ctx = parentCtx
+				case p.ctx != nil && p.ctx.Value(traceScopeKey{}) == scope:
+					// the PipeCommand's context is built on top of ours
+					p.ctx = spanlessContext{
+						Context: p.ctx,
+						span:    ctx,
+						parent:  scope.parent,
+					}
+				}
+			}()
+
+			statusCode, err := next(p)
+			counts.stop()
+
+			// what happened?
+			span.SetAttribute(TraceAttrStatusCode, statusCode)
+			span.SetAttribute(TraceAttrBytesRead, counts.bytesRead())
+			span.SetAttribute(TraceAttrStdoutBytesWritten, counts.stdoutBytes())
+			span.SetAttribute(TraceAttrStderrBytesWritten, counts.stderrBytes())
+			if err != nil {
+				span.SetAttribute(TraceAttrError, err.Error())
+				span.RecordError(err)
+			}
+
+			// all done
+			return statusCode, err
+		}
+	})
+}
+
+// traceScopeKey is the key that WithTracer uses to mark the contexts
+// that it creates
+type traceScopeKey struct{}
+
+// traceScope marks a context that WithTracer created for a span
+type traceScope struct {
+	// base is the context that the outermost span was started from
+	base context.Context
+
+	// parent is the context that this span was started from
+	parent context.Context
+}
+
+// baseContext returns the context that ctx was built from, before
+// WithTracer added any spans to it
+func baseContext(ctx context.Context) context.Context {
+	if scope, ok := ctx.Value(traceScopeKey{}).(*traceScope); ok {
+		return scope.base
+	}
+
+	return ctx
+}
+
+// spanlessContext hides the values that a span added to a context, so
+// that a context built on top of a span can outlive it
+type spanlessContext struct {
+	context.Context
+
+	// span is the context that the span added its values to
+	span context.Context
+
+	// parent is the context that the span was started from
+	parent context.Context
+}
+
+// Value returns the value for key, unless the value came from the span
+func (c spanlessContext) Value(key interface{}) interface{} {
+	retval := c.Context.Value(key)
+	if sameValue(retval, c.span.Value(key)) && !sameValue(retval, c.parent.Value(key)) {
+		return c.parent.Value(key)
+	}
+
+	return retval
+}
+
+// sameValue returns true if a and b are the same value. Values that
+// cannot be compared are never the same.
+func sameValue(a, b interface{}) (retval bool) {
+	defer func() {
+		if recover() != nil {
+			retval = false
+		}
+	}()
+
+	return a == b
+}
+
+// ================================================================
+//
+// MemoryTracer
+//
+// ----------------------------------------------------------------
+
+// MemoryTracer is a Tracer that keeps its spans in memory. Use it in
+// your tests.
+type MemoryTracer struct {
+	// mu protects everything below
+	mu sync.Mutex
+
+	// spans holds every span that we have started, in the order that
+	// they were started
+	spans []*MemorySpan
+}
+
+// MemorySpan is a span recorded by a MemoryTracer.
+type MemorySpan struct {
+	// ID identifies the span, starting at 1
+	ID int
+
+	// ParentID is the ID of the span's parent, or 0 if it has none
+	ParentID int
+
+	// Name is the name that the span was started with
+	Name string
+
+	// Attributes holds everything passed to SetAttribute
+	Attributes map[string]interface{}
+
+	// Err holds the last error passed to RecordError
+	Err error
+
+	// Start and End are when the span started and ended
+	Start time.Time
+	End   time.Time
+
+	// tracer is where we were created
+	tracer *MemoryTracer
+}
+
+// memorySpanKey is the key that MemoryTracer uses to store the current
+// span in a context
+type memorySpanKey struct{}
+
+// NewMemoryTracer creates a new MemoryTracer, with no spans.
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+// StartSpan starts a new span. It is part of the Tracer interface.
+func (t *MemoryTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &MemorySpan{
+		ID:         len(t.spans) + 1,
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		Start:      time.Now(),
+		tracer:     t,
+	}
+	if parent, ok := ctx.Value(memorySpanKey{}).(*memorySpanHandle); ok {
+		span.ParentID = parent.span.ID
+	}
+	t.spans = append(t.spans, span)
+
+	handle := &memorySpanHandle{span}
+	return context.WithValue(ctx, memorySpanKey{}, handle), handle
+}
+
+// Spans returns a copy of every span that the tracer has started, in the
+// order that they were started.
+func (t *MemoryTracer) Spans() []MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	retval := make([]MemorySpan, len(t.spans))
+	for i, span := range t.spans {
+		retval[i] = *span
+		retval[i].Attributes = make(map[string]interface{}, len(span.Attributes))
+		for key, value := range span.Attributes {
+			retval[i].Attributes[key] = value
+		}
+	}
+
+	return retval
+}
+
+// Ended returns true if the span has ended.
+func (s MemorySpan) Ended() bool {
+	return !s.End.IsZero()
+}
+
+// memorySpanHandle is the Span that MemoryTracer hands out
+//
+// it keeps MemorySpan's exported fields from clashing with the methods
+// of the Span interface
+type memorySpanHandle struct {
+	span *MemorySpan
+}
+
+func (h *memorySpanHandle) SetAttribute(key string, value interface{}) {
+	h.span.tracer.mu.Lock()
+	defer h.span.tracer.mu.Unlock()
+
+	h.span.Attributes[key] = value
+}
+
+func (h *memorySpanHandle) RecordError(err error) {
+	h.span.tracer.mu.Lock()
+	defer h.span.tracer.mu.Unlock()
+
+	h.span.Err = err
+}
+
+func (h *memorySpanHandle) End() {
+	h.span.tracer.mu.Lock()
+	defer h.span.tracer.mu.Unlock()
+
+	h.span.End = time.Now()
+}
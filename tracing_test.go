@@ -0,0 +1,325 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTracerOpensASpanPerCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+	unit.SetStdinFromString("hello\nworld\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("upper", upperCaseStep)
+	unit.RunNamedCommand("fail", func(p *pipe.Pipe) (int, error) {
+		p.Stderr.WriteString("oops\n")
+		return 3, errors.New("it went wrong")
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	spans := tracer.Spans()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "upper", spans[0].Name)
+	assert.Equal(t, 0, spans[0].ParentID)
+	assert.True(t, spans[0].Ended())
+	assert.Nil(t, spans[0].Err)
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			pipe.TraceAttrCommand:            "upper",
+			pipe.TraceAttrStatusCode:         pipe.StatusOkay,
+			pipe.TraceAttrBytesRead:          int64(12),
+			pipe.TraceAttrStdoutBytesWritten: int64(14),
+			pipe.TraceAttrStderrBytesWritten: int64(0),
+			pipe.TraceAttrStdinStackDepth:    0,
+			pipe.TraceAttrStdoutStackDepth:   0,
+			pipe.TraceAttrStderrStackDepth:   0,
+		},
+		spans[0].Attributes,
+	)
+
+	assert.Equal(t, "fail", spans[1].Name)
+	assert.Equal(t, 3, spans[1].Attributes[pipe.TraceAttrStatusCode])
+	assert.Equal(t, "it went wrong", spans[1].Attributes[pipe.TraceAttrError])
+	assert.Equal(t, int64(5), spans[1].Attributes[pipe.TraceAttrStderrBytesWritten])
+	assert.EqualError(t, spans[1].Err, "it went wrong")
+}
+
+func TestWithTracerRecordsStackDepths(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+	unit.PushStdin(unit.Stdin)
+	unit.PushStdout(unit.Stdout)
+	unit.PushStdout(unit.Stdout)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("noop", func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	spans := tracer.Spans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, 1, spans[0].Attributes[pipe.TraceAttrStdinStackDepth])
+	assert.Equal(t, 2, spans[0].Attributes[pipe.TraceAttrStdoutStackDepth])
+	assert.Equal(t, 0, spans[0].Attributes[pipe.TraceAttrStderrStackDepth])
+}
+
+func TestWithTracerNestsSpansForChildPipes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+	noop := func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusOkay, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunNamedCommand("outer", func(p *pipe.Pipe) (int, error) {
+		child := p.NewChildPipe()
+		child.RunNamedCommand("child", func(c *pipe.Pipe) (int, error) {
+			c.RunNamedCommand("grandchild", noop)
+			return pipe.StatusOkay, nil
+		})
+
+		p.RunNamedCommand("inner", noop)
+		return pipe.StatusOkay, nil
+	})
+	unit.RunNamedCommand("after", noop)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	spans := tracer.Spans()
+	assert.Len(t, spans, 5)
+
+	parents := map[string]int{}
+	ids := map[string]int{}
+	for _, span := range spans {
+		parents[span.Name] = span.ParentID
+		ids[span.Name] = span.ID
+		assert.True(t, span.Ended())
+	}
+
+	assert.Equal(t, 0, parents["outer"])
+	assert.Equal(t, ids["outer"], parents["child"])
+	assert.Equal(t, ids["child"], parents["grandchild"])
+	assert.Equal(t, ids["outer"], parents["inner"])
+	assert.Equal(t, 0, parents["after"])
+}
+
+func TestWithTracerRestoresThePipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+	stdin := unit.Stdin
+	stdout := unit.Stdout
+	ctx := unit.Context()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(upperCaseStep)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Same(t, stdin, unit.Stdin)
+	assert.Same(t, stdout, unit.Stdout)
+	assert.Equal(t, ctx, unit.Context())
+}
+
+func TestWithTracerKeepsAContextSetByTheCommand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	type ctxKey struct{}
+	tracer := pipe.NewMemoryTracer()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit := pipe.NewPipe(pipe.WithTracer(tracer), pipe.WithContext(ctx))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, ctx, unit.Context())
+}
+
+func TestWithTracerRestoresTheContextAfterAPanic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+	ctx := unit.Context()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	func() {
+		defer func() { recover() }()
+
+		unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+			panic("oh no")
+		})
+	}()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, ctx, unit.Context())
+}
+
+func TestWithTracerDoesNotLeakSpansIntoLaterCommands(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+
+	testCases := []struct {
+		name  string
+		setup pipe.PipeCommand
+	}{
+		{
+			name:  "WithSignals",
+			setup: pipe.WithSignals(),
+		},
+		{
+			name: "context built on the span",
+			setup: func(p *pipe.Pipe) (int, error) {
+				return pipe.WithContext(context.WithValue(p.Context(), ctxKey{}, "value"))(p)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			tracer := pipe.NewMemoryTracer()
+			unit := pipe.NewPipe(pipe.WithTracer(tracer))
+			defer unit.Exit()
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunNamedCommand("setup", testCase.setup)
+			unit.RunCommand(upperCaseStep)
+			unit.RunCommand(upperCaseStep)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			spans := tracer.Spans()
+			assert.Len(t, spans, 3)
+			for _, span := range spans {
+				assert.Equal(t, 0, span.ParentID, span.Name)
+			}
+		})
+	}
+}
+
+func TestWithTracerKeepsValuesFromAContextBuiltOnTheSpan(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	type ctxKey struct{}
+	tracer := pipe.NewMemoryTracer()
+	unit := pipe.NewPipe(pipe.WithTracer(tracer))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+		return pipe.WithContext(context.WithValue(p.Context(), ctxKey{}, "value"))(p)
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "value", unit.Context().Value(ctxKey{}))
+}
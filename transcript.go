@@ -0,0 +1,219 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	envish "github.com/ganbarodigital/go_envish/v4"
+)
+
+// TranscriptVersion is the version of the transcript format that
+// Recorder writes, and Replayer understands.
+const TranscriptVersion = 1
+
+// Transcript holds everything that a Recorder saw.
+//
+// You can save it as a single JSON document using WriteJSON, and load
+// it back using ReadTranscript.
+type Transcript struct {
+	Version int               `json:"version"`
+	Entries []TranscriptEntry `json:"entries"`
+}
+
+// TranscriptEntry holds what happened when a single PipeCommand ran.
+//
+// Recorder streams these out as JSON Lines, one entry per line.
+type TranscriptEntry struct {
+	// Version is the transcript format that this entry uses
+	Version int `json:"version"`
+
+	// Name is the name of the PipeCommand, as seen by the middleware
+	Name string `json:"name"`
+
+	// Stdin is what was in the pipe's Stdin when the command started
+	Stdin string `json:"stdin"`
+
+	// Env holds the differences between the pipe's environment and the
+	// program's environment, when the command started
+	Env EnvDiff `json:"env"`
+
+	// EnvChanges holds any changes that the command made to the pipe's
+	// environment
+	EnvChanges EnvDiff `json:"envChanges"`
+
+	// Flags holds the pipe's Flags when the command started
+	Flags int `json:"flags"`
+
+	// Stdout is everything that the command wrote to the pipe's Stdout
+	Stdout string `json:"stdout"`
+
+	// Stderr is everything that the command wrote to the pipe's Stderr
+	Stderr string `json:"stderr"`
+
+	// StatusCode is the status code that the command returned
+	StatusCode int `json:"statusCode"`
+
+	// Error is the message of the error that the command returned
+	Error string `json:"error,omitempty"`
+}
+
+// EnvDiff describes the differences between two environments.
+type EnvDiff struct {
+	// Set holds variables that were added or changed
+	Set map[string]string `json:"set,omitempty"`
+
+	// Unset holds the names of variables that were removed
+	Unset []string `json:"unset,omitempty"`
+}
+
+// WriteJSON writes the transcript to w, as a single JSON document.
+func (t Transcript) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(t)
+}
+
+// ReadTranscript loads a transcript from r.
+//
+// r can hold a single JSON document (as written by Transcript.WriteJSON),
+// or JSON Lines with one entry per line (as written by Recorder).
+func ReadTranscript(r io.Reader) (Transcript, error) {
+	retval := Transcript{Version: TranscriptVersion}
+
+	decoder := json.NewDecoder(r)
+	for {
+		// a document and an entry both have a version, and nothing
+		// else in common
+		var record struct {
+			TranscriptEntry
+			Entries *[]TranscriptEntry `json:"entries"`
+		}
+
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Transcript{}, err
+		}
+
+		if record.Version != TranscriptVersion {
+			return Transcript{}, ErrUnsupportedTranscriptVersion{record.Version}
+		}
+
+		// is this a whole document?
+		if record.Entries != nil {
+			for _, entry := range *record.Entries {
+				if entry.Version != TranscriptVersion {
+					return Transcript{}, ErrUnsupportedTranscriptVersion{entry.Version}
+				}
+			}
+			retval.Entries = append(retval.Entries, *record.Entries...)
+			continue
+		}
+
+		retval.Entries = append(retval.Entries, record.TranscriptEntry)
+	}
+
+	// all done
+	return retval, nil
+}
+
+// environToMap turns a list of "key=value" pairs into a map
+func environToMap(pairs []string) map[string]string {
+	retval := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key := envish.GetKeyFromPair(pair)
+		retval[key] = envish.GetValueFromPair(pair, key)
+	}
+
+	return retval
+}
+
+// diffEnv works out what needs to change to turn before into after
+func diffEnv(before, after map[string]string) EnvDiff {
+	retval := EnvDiff{}
+
+	for key, value := range after {
+		oldValue, ok := before[key]
+		if ok && oldValue == value {
+			continue
+		}
+
+		if retval.Set == nil {
+			retval.Set = map[string]string{}
+		}
+		retval.Set[key] = value
+	}
+
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			retval.Unset = append(retval.Unset, key)
+		}
+	}
+	sort.Strings(retval.Unset)
+
+	return retval
+}
+
+// diffEnvFromProgramEnv works out how the pipe's environment differs
+// from the program's environment
+func diffEnvFromProgramEnv(env map[string]string) EnvDiff {
+	return diffEnv(environToMap(os.Environ()), env)
+}
+
+// applyEnvDiff makes the changes described by diff to the given
+// environment
+func applyEnvDiff(env envish.ReaderWriter, diff EnvDiff) error {
+	for key, value := range diff.Set {
+		err := env.Setenv(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range diff.Unset {
+		env.Unsetenv(key)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscriptRoundTripsAsJSON(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := pipe.Transcript{
+		Version: pipe.TranscriptVersion,
+		Entries: []pipe.TranscriptEntry{
+			{
+				Version:    pipe.TranscriptVersion,
+				Name:       "fetch",
+				Stdin:      "query\n",
+				Env:        pipe.EnvDiff{Set: map[string]string{"HOST": "localhost"}},
+				Flags:      1,
+				Stdout:     "data\n",
+				StatusCode: 2,
+				Error:      "partial result",
+			},
+		},
+	}
+	var buf bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := expectedResult.WriteJSON(&buf)
+	assert.Nil(t, err)
+	actualResult, err := pipe.ReadTranscript(&buf)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestReadTranscriptReadsJSONLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `{"version":1,"name":"fetch","stdout":"one"}` + "\n" +
+		`{"version":1,"name":"parse","stdout":"two"}` + "\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := pipe.ReadTranscript(strings.NewReader(input))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, pipe.TranscriptVersion, actualResult.Version)
+	assert.Len(t, actualResult.Entries, 2)
+	assert.Equal(t, "fetch", actualResult.Entries[0].Name)
+	assert.Equal(t, "two", actualResult.Entries[1].Stdout)
+}
+
+func TestReadTranscriptRejectsUnknownVersions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `{"version":2,"entries":[]}`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := pipe.ReadTranscript(strings.NewReader(input))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.ErrUnsupportedTranscriptVersion{2}, err)
+}
+
+func TestReadTranscriptReturnsErrorForInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `{"version":1,`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := pipe.ReadTranscript(strings.NewReader(input))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+}
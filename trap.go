@@ -0,0 +1,176 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+// TrapEvent is something that can happen to a pipe, that you can set
+// a trap for. See Pipe.Trap.
+type TrapEvent int
+
+const (
+	// TrapExit happens when you call Pipe.Exit
+	TrapExit TrapEvent = iota
+
+	// TrapErr happens each time that a PipeCommand run by RunCommand
+	// fails
+	TrapErr
+
+	// TrapCancel happens the first time that RunCommand finds that the
+	// pipe's context has been cancelled, or has passed its deadline
+	TrapCancel
+)
+
+// String returns the name of the event, as "EXIT", "ERR" or "CANCEL".
+func (e TrapEvent) String() string {
+	switch e {
+	case TrapExit:
+		return "EXIT"
+	case TrapErr:
+		return "ERR"
+	case TrapCancel:
+		return "CANCEL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Trap adds a PipeCommand to run when the given event happens, like the
+// `trap` command in a UNIX shell. Use it for clean-up work, such as
+// removing temporary files or releasing locks.
+//
+// Traps for the same event run in the reverse order that they were
+// added, just like Golang's `defer`. Pass in a nil cmd to remove all of
+// the traps for the event.
+//
+// Traps run against this pipe. While they run, the pipe's StatusCode and
+// Error are those of the PipeCommand that caused the event. Afterwards,
+// they are put back, so that traps cannot hide a failure.
+//
+// Child pipes do not inherit any traps.
+func (p *Pipe) Trap(event TrapEvent, cmd PipeCommand) {
+	// do we have a pipe to work with?
+	if p == nil {
+		return
+	}
+
+	// are we removing the traps?
+	if cmd == nil {
+		delete(p.traps, event)
+		return
+	}
+
+	if p.traps == nil {
+		p.traps = map[TrapEvent][]PipeCommand{}
+	}
+	p.traps[event] = append(p.traps[event], cmd)
+}
+
+// Exit runs the pipe's TrapExit traps (and its TrapCancel traps, if the
+// pipe's context has been cancelled and they have not run yet). Each
+// trap only runs once.
+//
+// Call it with `defer`, straight after you create the pipe:
+//
+//	p := pipe.NewPipe()
+//	defer p.Exit()
+//
+// If a PipeCommand panics, Exit recovers the panic, sets the pipe's
+// status code to StatusNotOkay and its error to ErrPanic, and runs the
+// traps. It then panics again with the original value.
+func (p *Pipe) Exit() {
+	// do we have a pipe to work with?
+	if p == nil {
+		return
+	}
+
+	// are we here because something panicked?
+	//
+	// this only works when we have been called by `defer`
+	panicked := recover()
+	if panicked != nil {
+		p.statusCode = StatusNotOkay
+		p.err = ErrPanic{panicked}
+	}
+
+	if p.Context().Err() != nil {
+		p.runTraps(TrapCancel, true)
+	}
+	p.runTraps(TrapExit, true)
+
+	// we are not here to swallow panics
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// runTrapsAfterCommand runs any traps that the last PipeCommand set off
+func (p *Pipe) runTrapsAfterCommand() {
+	if p.statusCode != StatusOkay || p.err != nil {
+		p.runTraps(TrapErr, false)
+	}
+	if p.Context().Err() != nil {
+		p.runTraps(TrapCancel, true)
+	}
+}
+
+// runTraps runs the traps for the given event, newest first
+//
+// if once is true, the traps are removed before they run
+func (p *Pipe) runTraps(event TrapEvent, once bool) {
+	// traps cannot set off other traps
+	if p.inTrap {
+		return
+	}
+
+	traps := p.traps[event]
+	if once {
+		delete(p.traps, event)
+	}
+
+	statusCode, err := p.statusCode, p.err
+	p.inTrap = true
+	defer func() {
+		p.inTrap = false
+		p.statusCode, p.err = statusCode, err
+	}()
+
+	for i := len(traps) - 1; i >= 0; i-- {
+		traps[i](p)
+	}
+}
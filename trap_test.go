@@ -0,0 +1,281 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordTrap returns a PipeCommand that adds name, and the pipe's error,
+// to the given list
+func recordTrap(name string, seen *[]string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		*seen = append(*seen, name+":"+errorString(p.Error()))
+		return pipe.StatusNotOkay, errors.New("traps cannot change the result")
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return "nil"
+	}
+	return err.Error()
+}
+
+func TestTrapEventString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "EXIT", pipe.TrapExit.String())
+	assert.Equal(t, "ERR", pipe.TrapErr.String())
+	assert.Equal(t, "CANCEL", pipe.TrapCancel.String())
+	assert.Equal(t, "UNKNOWN", pipe.TrapEvent(99).String())
+}
+
+func TestTrapExitRunsOnceInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapExit, recordTrap("first", &seen))
+	unit.Trap(pipe.TrapExit, recordTrap("second", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(exitWith(0))
+	unit.Exit()
+	unit.Exit()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"second:nil", "first:nil"}, seen)
+	assert.Nil(t, unit.Error())
+}
+
+func TestTrapErrRunsEachTimeACommandFails(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapErr, recordTrap("err", &seen))
+	cmdErr := errors.New("it went wrong")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(exitWith(0))
+	unit.RunCommand(exitWith(2))
+	unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+		return pipe.StatusNotOkay, cmdErr
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(
+		t,
+		[]string{
+			"err:" + pipe.ErrNonZeroStatusCode{"command", 2}.Error(),
+			"err:it went wrong",
+		},
+		seen,
+	)
+
+	// the trap did not hide the failure
+	assert.Equal(t, cmdErr, unit.Error())
+}
+
+func TestTrapWithNilCommandRemovesTheTraps(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapErr, recordTrap("err", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.Trap(pipe.TrapErr, nil)
+	unit.RunCommand(exitWith(1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, seen)
+}
+
+func TestTrapCancelRunsOnceWhenTheContextIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+	unit.Trap(pipe.TrapCancel, recordTrap("cancel", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(exitWith(0))
+	cancel()
+	unit.RunCommand(exitWith(0))
+	unit.RunCommand(exitWith(0))
+	unit.Exit()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"cancel:nil"}, seen)
+}
+
+func TestExitRunsTheCancelTrapsIfTheyHaveNotRun(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := pipe.NewPipe(pipe.WithContext(ctx))
+	unit.Trap(pipe.TrapCancel, recordTrap("cancel", &seen))
+	unit.Trap(pipe.TrapExit, recordTrap("exit", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	cancel()
+	unit.Exit()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"cancel:nil", "exit:nil"}, seen)
+}
+
+func TestTrapsDoNotSetOffOtherTraps(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapErr, recordTrap("err", &seen))
+	unit.Trap(pipe.TrapExit, func(p *pipe.Pipe) (int, error) {
+		p.RunCommand(exitWith(1))
+		return pipe.StatusOkay, nil
+	})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.Exit()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, seen)
+}
+
+func TestExitRunsTheTrapsAfterAPanic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapExit, recordTrap("exit", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer unit.Exit()
+
+		unit.RunCommand(func(p *pipe.Pipe) (int, error) {
+			panic("boom")
+		})
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"exit:panic: boom"}, seen)
+	assert.Equal(t, pipe.ErrPanic{"boom"}, unit.Error())
+}
+
+func TestChildPipesDoNotInheritTraps(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var seen []string
+	unit := pipe.NewPipe()
+	unit.Trap(pipe.TrapErr, recordTrap("err", &seen))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.NewChildPipe().RunCommand(exitWith(1))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, seen)
+}
@@ -0,0 +1,193 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// interceptedTextReaderWriter passes every write through a function of
+// our choosing. Reads go straight to the underlying TextReaderWriter.
+type interceptedTextReaderWriter struct {
+	ioextra.TextReaderWriter
+
+	write func(b []byte) (int, error)
+
+	// owner is whoever asked for the interception, if they want to be
+	// able to find it again
+	owner interface{}
+
+	// flush writes out anything that write is holding back. It is nil
+	// if write never holds anything back.
+	flush func() error
+}
+
+func (w *interceptedTextReaderWriter) Write(b []byte) (int, error) {
+	return w.write(b)
+}
+
+func (w *interceptedTextReaderWriter) WriteString(s string) (int, error) {
+	return w.write([]byte(s))
+}
+
+func (w *interceptedTextReaderWriter) WriteRune(r rune) (int, error) {
+	return w.write([]byte(string(r)))
+}
+
+// outputInterceptor builds the write function for an interceptor, given
+// the TextReaderWriter that is being intercepted.
+type outputInterceptor = func(next ioextra.TextReaderWriter) func(b []byte) (int, error)
+
+// interceptOutput replaces the pipe's Stdout and Stderr with interceptors,
+// and returns a function that puts the originals back again.
+//
+// If the pipe's Stdout and Stderr are the same, only the Stdout
+// interceptor is used, and it is used for both of them.
+//
+// If a PipeCommand replaces the pipe's Stdout and/or Stderr while it
+// runs, the restore function leaves the replacement alone.
+func interceptOutput(p *Pipe, stdout, stderr outputInterceptor) func() {
+	return interceptOutputFor(p, nil, stdout, stderr)
+}
+
+// interceptOutputFor works just like interceptOutput, and marks the
+// interceptors as belonging to owner (see isInterceptedBy).
+func interceptOutputFor(p *Pipe, owner interface{}, stdout, stderr outputInterceptor) func() {
+	origStdout := p.Stdout
+	origStderr := p.Stderr
+
+	var newStdout, newStderr ioextra.TextReaderWriter
+	if origStdout != nil {
+		newStdout = &interceptedTextReaderWriter{
+			TextReaderWriter: origStdout,
+			write:            stdout(origStdout),
+			owner:            owner,
+		}
+	}
+
+	switch {
+	case origStderr == nil:
+		// nothing to intercept
+	case origStderr == origStdout:
+		newStderr = newStdout
+	default:
+		newStderr = &interceptedTextReaderWriter{
+			TextReaderWriter: origStderr,
+			write:            stderr(origStderr),
+			owner:            owner,
+		}
+	}
+
+	p.Stdout = newStdout
+	p.Stderr = newStderr
+
+	return func() {
+		if p.Stdout == newStdout {
+			p.Stdout = origStdout
+		}
+		if p.Stderr == newStderr {
+			p.Stderr = origStderr
+		}
+	}
+}
+
+// isInterceptedBy returns true if w, or anything that w passes its
+// writes on to, is an interceptor that belongs to owner
+func isInterceptedBy(w ioextra.TextReaderWriter, owner interface{}) bool {
+	for {
+		intercepted, ok := w.(*interceptedTextReaderWriter)
+		if !ok {
+			return false
+		}
+		if intercepted.owner == owner {
+			return true
+		}
+		w = intercepted.TextReaderWriter
+	}
+}
+
+// flushOutput writes out anything that the interceptors on the pipe's
+// Stdout and Stderr are holding back, and returns the first error that
+// it finds
+func (p *Pipe) flushOutput() error {
+	retval := flushInterceptors(p.Stdout)
+	if p.Stderr != p.Stdout {
+		err := flushInterceptors(p.Stderr)
+		if retval == nil {
+			retval = err
+		}
+	}
+
+	return retval
+}
+
+// flushInterceptors writes out anything that w, or anything that w
+// passes its writes on to, is holding back
+func flushInterceptors(w ioextra.TextReaderWriter) error {
+	var retval error
+	for {
+		intercepted, ok := w.(*interceptedTextReaderWriter)
+		if !ok {
+			return retval
+		}
+		if intercepted.flush != nil {
+			err := intercepted.flush()
+			if retval == nil {
+				retval = err
+			}
+		}
+		w = intercepted.TextReaderWriter
+	}
+}
+
+// flushOutputAfter returns a PipeCommand that runs c, and then writes
+// out anything that the pipe's Stdout and Stderr are holding back
+func flushOutputAfter(c PipeCommand) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		statusCode, err := c(p)
+
+		flushErr := p.flushOutput()
+		if flushErr != nil && err == nil && statusCode == StatusOkay {
+			return StatusNotOkay, flushErr
+		}
+
+		return statusCode, err
+	}
+}
@@ -0,0 +1,182 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// ioCounter counts the bytes that a PipeCommand reads from the pipe's
+// Stdin, and writes to the pipe's Stdout and Stderr
+type ioCounter struct {
+	stdin   *countingTextReader
+	stdout  int64
+	stderr  int64
+	restore func()
+	pipe    *Pipe
+}
+
+// countIO starts counting the bytes that go in and out of the given
+// pipe. Call stop() when the PipeCommand has finished.
+func countIO(p *Pipe) *ioCounter {
+	retval := &ioCounter{pipe: p}
+
+	if p.Stdin != nil {
+		retval.stdin = &countingTextReader{TextReader: p.Stdin}
+		p.Stdin = retval.stdin
+	}
+	retval.restore = interceptOutput(p, countWrites(&retval.stdout), countWrites(&retval.stderr))
+
+	return retval
+}
+
+// stop puts the pipe's Stdin, Stdout and Stderr back the way they were,
+// unless the PipeCommand has replaced them
+func (c *ioCounter) stop() {
+	c.restore()
+	if c.stdin != nil && c.pipe.Stdin == c.stdin {
+		c.pipe.Stdin = c.stdin.TextReader
+	}
+}
+
+// bytesRead returns how many bytes have been read from Stdin
+func (c *ioCounter) bytesRead() int64 {
+	if c.stdin == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&c.stdin.n)
+}
+
+// stdoutBytes returns how many bytes have been written to Stdout
+func (c *ioCounter) stdoutBytes() int64 {
+	return atomic.LoadInt64(&c.stdout)
+}
+
+// stderrBytes returns how many bytes have been written to Stderr
+func (c *ioCounter) stderrBytes() int64 {
+	return atomic.LoadInt64(&c.stderr)
+}
+
+// countWrites returns an outputInterceptor that adds the number of bytes
+// written to n
+func countWrites(n *int64) outputInterceptor {
+	return func(next ioextra.TextReaderWriter) func(b []byte) (int, error) {
+		return func(b []byte) (int, error) {
+			written, err := next.Write(b)
+			atomic.AddInt64(n, int64(written))
+			return written, err
+		}
+	}
+}
+
+// countingTextReader counts the bytes read from a TextReader
+type countingTextReader struct {
+	ioextra.TextReader
+
+	// n is how many bytes have been read
+	n int64
+}
+
+func (c *countingTextReader) Read(b []byte) (int, error) {
+	n, err := c.TextReader.Read(b)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+func (c *countingTextReader) ParseInt() (int, error) {
+	return strconv.Atoi(c.TrimmedString())
+}
+
+// ReadLines reads through us, so that the lines are counted
+func (c *countingTextReader) ReadLines() <-chan string {
+	return ioextra.NewTextScanner(c, bufio.ScanLines)
+}
+
+// ReadWords reads through us, so that the words are counted
+func (c *countingTextReader) ReadWords() <-chan string {
+	return ioextra.NewTextScanner(c, bufio.ScanWords)
+}
+
+// String only counts what it takes out of the TextReader. Buffers
+// (such as ioextra.TextBuffer) leave their contents where they are.
+func (c *countingTextReader) String() string {
+	before, hasLen := unreadLen(c.TextReader)
+	retval := c.TextReader.String()
+
+	consumed := int64(len(retval))
+	if hasLen {
+		after, _ := unreadLen(c.TextReader)
+		consumed = before - after
+	}
+	atomic.AddInt64(&c.n, consumed)
+
+	return retval
+}
+
+func (c *countingTextReader) Strings() []string {
+	retval := []string{}
+	for line := range c.ReadLines() {
+		retval = append(retval, line)
+	}
+
+	return retval
+}
+
+func (c *countingTextReader) TrimmedString() string {
+	return strings.TrimSpace(c.String())
+}
+
+// unreadLen returns how many unread bytes r holds, if r can tell us
+func unreadLen(r ioextra.TextReader) (int64, bool) {
+	switch buf := r.(type) {
+	case interface{ Len() int }:
+		return int64(buf.Len()), true
+	case interface{ Len() int64 }:
+		return buf.Len(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,87 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	ioextra "github.com/ganbarodigital/go-ioextra/v2"
+)
+
+// textReader turns any io.Reader into an ioextra.TextReader
+//
+// Like ioextra.TextFile, every method reads from the underlying reader.
+type textReader struct {
+	io.Reader
+}
+
+func (t *textReader) ParseInt() (int, error) {
+	return strconv.Atoi(t.TrimmedString())
+}
+
+func (t *textReader) ReadLines() <-chan string {
+	return ioextra.NewTextScanner(t, bufio.ScanLines)
+}
+
+func (t *textReader) ReadWords() <-chan string {
+	return ioextra.NewTextScanner(t, bufio.ScanWords)
+}
+
+func (t *textReader) String() string {
+	buf, _ := ioutil.ReadAll(t.Reader)
+	return string(buf)
+}
+
+func (t *textReader) Strings() []string {
+	retval := []string{}
+	for line := range t.ReadLines() {
+		retval = append(retval, line)
+	}
+
+	return retval
+}
+
+func (t *textReader) TrimmedString() string {
+	return strings.TrimSpace(t.String())
+}
@@ -0,0 +1,516 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Xargs returns these status codes, in the same way that UNIX xargs does.
+const (
+	// XargsStatusCommandFailed means that at least one batch finished
+	// with a status code between 1 and 125
+	XargsStatusCommandFailed = 123
+
+	// XargsStatusCommandStopped means that a batch finished with status
+	// code 255. Xargs does not start any more batches after that.
+	XargsStatusCommandStopped = 124
+
+	// XargsStatusCommandKilled means that a batch finished with a status
+	// code between 126 and 254 (for example, 128+n when it was killed by
+	// signal n). Xargs does not start any more batches after that.
+	XargsStatusCommandKilled = 125
+)
+
+// XargsOptions tells Xargs how to split up the pipe's Stdin, and how to
+// run the resulting batches.
+//
+// If you set more than one of ReplaceStr, MaxLines and MaxArgs, the
+// first one (in that order) wins.
+type XargsOptions struct {
+	// Args are passed to the builder in front of the args read from
+	// Stdin
+	Args []string
+
+	// MaxArgs is the most args read from Stdin to put in each batch
+	// (like xargs -n). 0 means no limit.
+	MaxArgs int
+
+	// MaxLines is the number of non-blank lines of Stdin to put in each
+	// batch (like xargs -L). 0 means that lines do not matter. A line
+	// that ends in a blank continues onto the next line.
+	MaxLines int
+
+	// NullDelimited means that the args in Stdin are separated by NUL
+	// characters, and that quotes and backslashes are not special (like
+	// xargs -0).
+	NullDelimited bool
+
+	// ReplaceStr turns on replace mode (like xargs -I). Each line of
+	// Stdin is a single arg, and it runs in its own batch. Every
+	// occurrence of ReplaceStr in Args is replaced by the arg.
+	ReplaceStr string
+
+	// MaxProcs is how many batches can run at the same time (like
+	// xargs -P). 0 and 1 both mean one batch at a time.
+	MaxProcs int
+
+	// NoRunIfEmpty means do not run anything if Stdin has no args in
+	// it (like xargs -r). Otherwise, we run a single batch with just
+	// Args.
+	NoRunIfEmpty bool
+}
+
+// Xargs returns a PipeCommand that splits up the pipe's Stdin into
+// batches of args, and runs the command created by builder for each
+// batch.
+//
+// Each batch runs against its own child pipe (see NewChildPipe), with
+// an empty Stdin. Xargs copies each batch's Stdout and Stderr into the
+// pipe's Stdout and Stderr, in the same order as the batches appear in
+// Stdin, even when batches run in parallel.
+//
+// Batches share the pipe's Env. If they run in parallel, they must not
+// change it.
+//
+// If every batch succeeds, Xargs returns StatusOkay. Otherwise, it
+// returns one of the XargsStatus* status codes, along with an
+// ErrXargsBatchFailed for the first batch that failed.
+func Xargs(builder func(args []string) PipeCommand, opts XargsOptions) PipeCommand {
+	return func(p *Pipe) (int, error) {
+		// do we have a pipe to work with?
+		if p == nil || p.Stdin == nil || p.Stdout == nil {
+			return StatusOkay, nil
+		}
+
+		runner := newXargsRunner(p, builder, opts)
+		reader := newXargsReader(p.Stdin, opts)
+
+		ranBatch := false
+		for !runner.stopped() {
+			args, err := reader.nextBatch()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				runner.wait()
+				return StatusNotOkay, err
+			}
+
+			runner.start(args)
+			ranBatch = true
+		}
+
+		// special case - nothing in Stdin
+		if !ranBatch && !opts.NoRunIfEmpty && opts.ReplaceStr == "" {
+			runner.start(nil)
+		}
+
+		runner.wait()
+		return runner.result()
+	}
+}
+
+// xargsBatch holds the results of running a single batch
+type xargsBatch struct {
+	args       []string
+	stdout     string
+	stderr     string
+	statusCode int
+	err        error
+	done       bool
+}
+
+// xargsRunner runs batches, and collects their output in order
+type xargsRunner struct {
+	parent  *Pipe
+	builder func(args []string) PipeCommand
+	opts    XargsOptions
+
+	// slots limits how many batches run at once
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	// mu protects everything below
+	mu sync.Mutex
+
+	// batches holds every batch that we've started, in order
+	batches []*xargsBatch
+
+	// nextOutput is the first batch whose output we have not written yet
+	nextOutput int
+
+	// statusCode is the status code that Xargs will return
+	statusCode int
+
+	// firstFailure is the first batch (in Stdin order) that failed
+	firstFailure *xargsBatch
+
+	// stop is true once a batch has returned 255, or been killed
+	stop bool
+}
+
+func newXargsRunner(parent *Pipe, builder func(args []string) PipeCommand, opts XargsOptions) *xargsRunner {
+	maxProcs := opts.MaxProcs
+	if maxProcs < 1 {
+		maxProcs = 1
+	}
+
+	return &xargsRunner{
+		parent:  parent,
+		builder: builder,
+		opts:    opts,
+		slots:   make(chan struct{}, maxProcs),
+	}
+}
+
+// start runs a batch, once there is a free slot for it
+func (r *xargsRunner) start(args []string) {
+	r.slots <- struct{}{}
+
+	// has an earlier batch told us to stop?
+	r.mu.Lock()
+	if r.stop {
+		r.mu.Unlock()
+		<-r.slots
+		return
+	}
+	batch := &xargsBatch{args: r.buildArgs(args)}
+	r.batches = append(r.batches, batch)
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() { <-r.slots }()
+
+		child := r.parent.NewChildPipe()
+		child.RunCommand(r.builder(batch.args))
+
+		r.finish(batch, child)
+		child.Close()
+	}()
+}
+
+// buildArgs works out the full list of args for a batch
+func (r *xargsRunner) buildArgs(args []string) []string {
+	// are we in replace mode?
+	if r.opts.ReplaceStr != "" {
+		retval := make([]string, len(r.opts.Args))
+		for i, arg := range r.opts.Args {
+			retval[i] = strings.Replace(arg, r.opts.ReplaceStr, args[0], -1)
+		}
+		return retval
+	}
+
+	retval := make([]string, 0, len(r.opts.Args)+len(args))
+	retval = append(retval, r.opts.Args...)
+	return append(retval, args...)
+}
+
+// finish records the results of a batch, and writes out any output
+// that is now ready
+func (r *xargsRunner) finish(batch *xargsBatch, child *Pipe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch.stdout = child.Stdout.String()
+	batch.stderr = child.Stderr.String()
+	batch.statusCode, batch.err = child.StatusError()
+	batch.done = true
+
+	// write out everything that is ready, in order
+	for r.nextOutput < len(r.batches) && r.batches[r.nextOutput].done {
+		next := r.batches[r.nextOutput]
+		r.parent.Stdout.WriteString(next.stdout)
+		if r.parent.Stderr != nil {
+			r.parent.Stderr.WriteString(next.stderr)
+		}
+		r.nextOutput++
+
+		r.recordStatus(next)
+	}
+}
+
+// recordStatus works out what the batch's status code means for Xargs
+func (r *xargsRunner) recordStatus(batch *xargsBatch) {
+	var statusCode int
+	switch {
+	case batch.statusCode == 255:
+		statusCode = XargsStatusCommandStopped
+		r.stop = true
+	case batch.statusCode > 125:
+		statusCode = XargsStatusCommandKilled
+		r.stop = true
+	case batch.statusCode != StatusOkay || batch.err != nil:
+		statusCode = XargsStatusCommandFailed
+	default:
+		return
+	}
+
+	if r.firstFailure == nil {
+		r.firstFailure = batch
+	}
+	if statusCode > r.statusCode {
+		r.statusCode = statusCode
+	}
+}
+
+// stopped returns true if we should not start any more batches
+func (r *xargsRunner) stopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stop
+}
+
+// wait waits for all of the running batches to finish
+func (r *xargsRunner) wait() {
+	r.wg.Wait()
+}
+
+// result returns what Xargs should return
+func (r *xargsRunner) result() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.firstFailure == nil {
+		return StatusOkay, nil
+	}
+
+	return r.statusCode, ErrXargsBatchFailed{
+		Args:       r.firstFailure.args,
+		StatusCode: r.firstFailure.statusCode,
+		Err:        r.firstFailure.err,
+	}
+}
+
+// xargsReader splits Stdin up into batches of args
+type xargsReader struct {
+	reader *bufio.Reader
+	opts   XargsOptions
+
+	// pending holds args that we have read, but not batched yet
+	pending []string
+
+	// eof is true once we have read everything
+	eof bool
+}
+
+func newXargsReader(r io.Reader, opts XargsOptions) *xargsReader {
+	return &xargsReader{
+		reader: bufio.NewReader(r),
+		opts:   opts,
+	}
+}
+
+// nextBatch returns the next batch of args, or io.EOF if there are
+// none left
+func (x *xargsReader) nextBatch() ([]string, error) {
+	switch {
+	case x.opts.ReplaceStr != "":
+		return x.nextReplaceBatch()
+	case x.opts.MaxLines > 0:
+		return x.nextLinesBatch()
+	default:
+		return x.nextArgsBatch()
+	}
+}
+
+// nextReplaceBatch returns the next line of Stdin, as a single arg
+func (x *xargsReader) nextReplaceBatch() ([]string, error) {
+	for {
+		item, err := x.readItem()
+		if err != nil {
+			return nil, err
+		}
+
+		if !x.opts.NullDelimited {
+			item = strings.TrimLeftFunc(item, unicode.IsSpace)
+		}
+		if item != "" {
+			return []string{item}, nil
+		}
+	}
+}
+
+// nextLinesBatch returns the args from the next MaxLines non-blank lines
+// of Stdin
+func (x *xargsReader) nextLinesBatch() ([]string, error) {
+	var retval []string
+
+	lines := 0
+	for lines < x.opts.MaxLines {
+		item, err := x.readItem()
+		if err == io.EOF && len(retval) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := x.splitItem(item)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		retval = append(retval, args...)
+
+		// a trailing blank means that the line carries on
+		if x.opts.NullDelimited || !strings.HasSuffix(item, " ") && !strings.HasSuffix(item, "\t") {
+			lines++
+		}
+	}
+
+	return retval, nil
+}
+
+// nextArgsBatch returns the next MaxArgs args from Stdin
+func (x *xargsReader) nextArgsBatch() ([]string, error) {
+	for !x.eof && (x.opts.MaxArgs < 1 || len(x.pending) < x.opts.MaxArgs) {
+		item, err := x.readItem()
+		if err == io.EOF {
+			x.eof = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := x.splitItem(item)
+		if err != nil {
+			return nil, err
+		}
+		x.pending = append(x.pending, args...)
+	}
+
+	if len(x.pending) == 0 {
+		return nil, io.EOF
+	}
+
+	size := len(x.pending)
+	if x.opts.MaxArgs > 0 && size > x.opts.MaxArgs {
+		size = x.opts.MaxArgs
+	}
+	retval := x.pending[:size]
+	x.pending = x.pending[size:]
+
+	return retval, nil
+}
+
+// readItem returns the next line (or NUL-delimited item) from Stdin,
+// without its delimiter
+func (x *xargsReader) readItem() (string, error) {
+	delim := byte('\n')
+	if x.opts.NullDelimited {
+		delim = 0
+	}
+
+	item, err := x.reader.ReadString(delim)
+	if err == io.EOF && item != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(item, string(delim)), nil
+}
+
+// splitItem splits an item up into args
+func (x *xargsReader) splitItem(item string) ([]string, error) {
+	// NUL-delimited items are never split up
+	if x.opts.NullDelimited {
+		return []string{item}, nil
+	}
+
+	return splitXargsLine(item)
+}
+
+// splitXargsLine splits a line up into args, in the same way that xargs
+// does: args are separated by blanks, and you can use single quotes,
+// double quotes and backslashes to put blanks into an arg.
+func splitXargsLine(line string) ([]string, error) {
+	var retval []string
+	var arg strings.Builder
+	inArg := false
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			if inArg {
+				retval = append(retval, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '\\' && i+1 < len(runes):
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != c {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, ErrXargsUnmatchedQuote{c}
+			}
+			arg.WriteString(string(runes[i+1 : end]))
+			inArg = true
+			i = end
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		retval = append(retval, arg.String())
+	}
+	return retval, nil
+}
@@ -0,0 +1,352 @@
+// pipe is a library to help you write UNIX-like pipelines of operations
+//
+// inspired by:
+//
+// - http://labix.org/pipe
+// - https://github.com/bitfield/script
+//
+// Copyright 2021-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package pipe_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pipe "github.com/ganbarodigital/go_pipe/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoArgs is a builder that writes its args to Stdout, one batch per line
+func echoArgs(args []string) pipe.PipeCommand {
+	return func(p *pipe.Pipe) (int, error) {
+		p.Stdout.WriteString("[" + strings.Join(args, "|") + "]\n")
+		return pipe.StatusOkay, nil
+	}
+}
+
+func TestXargsBatching(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		input          string
+		opts           pipe.XargsOptions
+		expectedResult string
+	}{
+		{
+			name:           "puts every arg in a single batch by default",
+			input:          "one two\nthree\n\nfour",
+			expectedResult: "[one|two|three|four]\n",
+		},
+		{
+			name:           "puts Args in front of the args from Stdin",
+			input:          "one two\n",
+			opts:           pipe.XargsOptions{Args: []string{"-v"}},
+			expectedResult: "[-v|one|two]\n",
+		},
+		{
+			name:           "supports quotes and backslashes",
+			input:          `'one two' "three four" five\ six` + "\n",
+			expectedResult: "[one two|three four|five six]\n",
+		},
+		{
+			name:           "MaxArgs limits the size of each batch",
+			input:          "a b c\nd e\n",
+			opts:           pipe.XargsOptions{MaxArgs: 2},
+			expectedResult: "[a|b]\n[c|d]\n[e]\n",
+		},
+		{
+			name:           "MaxLines batches by line",
+			input:          "a b\n\nc\nd e \nf\ng\n",
+			opts:           pipe.XargsOptions{MaxLines: 1},
+			expectedResult: "[a|b]\n[c]\n[d|e|f]\n[g]\n",
+		},
+		{
+			name:           "MaxLines can batch several lines",
+			input:          "a b\nc\nd\n",
+			opts:           pipe.XargsOptions{MaxLines: 2},
+			expectedResult: "[a|b|c]\n[d]\n",
+		},
+		{
+			name:           "NullDelimited splits on NUL characters",
+			input:          "one two\x00'three'\x00",
+			opts:           pipe.XargsOptions{NullDelimited: true},
+			expectedResult: "[one two|'three']\n",
+		},
+		{
+			name:  "ReplaceStr runs one batch per line",
+			input: "  first file\nsecond\n\n",
+			opts: pipe.XargsOptions{
+				Args:       []string{"cp", "{}", "{}.bak"},
+				ReplaceStr: "{}",
+			},
+			expectedResult: "[cp|first file|first file.bak]\n[cp|second|second.bak]\n",
+		},
+		{
+			name:           "runs once with no args when Stdin is empty",
+			input:          "",
+			opts:           pipe.XargsOptions{Args: []string{"ls"}},
+			expectedResult: "[ls]\n",
+		},
+		{
+			name:           "NoRunIfEmpty does not run anything when Stdin is empty",
+			input:          " \n",
+			opts:           pipe.XargsOptions{NoRunIfEmpty: true},
+			expectedResult: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString(testCase.input)
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.Xargs(echoArgs, testCase.opts))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, unit.Error())
+			assert.Equal(t, testCase.expectedResult, unit.Stdout.String())
+		})
+	}
+}
+
+func TestXargsRunsEachBatchAgainstAChildPipe(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe(pipe.WithEnvFromMap(map[string]string{"GREETING": "hello"}))
+	unit.Flags = 5
+	unit.SetStdinFromString("a\nb\n")
+
+	builder := func(args []string) pipe.PipeCommand {
+		return func(p *pipe.Pipe) (int, error) {
+			p.Stdout.WriteString(p.Env.Getenv("GREETING") + " " + args[0] + "\n")
+			p.Stderr.WriteString("stdin was [" + p.Stdin.String() + "]\n")
+			if p.Flags != 5 {
+				return pipe.StatusNotOkay, nil
+			}
+			return pipe.StatusOkay, nil
+		}
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Xargs(builder, pipe.XargsOptions{MaxArgs: 1}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "hello a\nhello b\n", unit.Stdout.String())
+	assert.Equal(t, "stdin was []\nstdin was []\n", unit.Stderr.String())
+}
+
+func TestXargsKeepsOutputInOrderWhenRunningInParallel(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("1 2 3 4 5 6 7 8\n")
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	builder := func(args []string) pipe.PipeCommand {
+		return func(p *pipe.Pipe) (int, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			// later batches finish first
+			time.Sleep(time.Duration(9-int(args[0][0]-'0')) * time.Millisecond)
+			p.Stdout.WriteString(args[0] + "\n")
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return pipe.StatusOkay, nil
+		}
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Xargs(builder, pipe.XargsOptions{MaxArgs: 1, MaxProcs: 3}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, unit.Error())
+	assert.Equal(t, "1\n2\n3\n4\n5\n6\n7\n8\n", unit.Stdout.String())
+	assert.True(t, maxRunning > 1)
+	assert.True(t, maxRunning <= 3)
+}
+
+func TestXargsStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	batchErr := errors.New("batch failed")
+
+	testCases := []struct {
+		name               string
+		statusCodes        map[string]int
+		expectedStatusCode int
+		expectedErr        error
+		expectedOutput     string
+	}{
+		{
+			name:               "returns 123 when a batch fails",
+			statusCodes:        map[string]int{"b": 1, "c": 2},
+			expectedStatusCode: pipe.XargsStatusCommandFailed,
+			expectedErr:        pipe.ErrXargsBatchFailed{[]string{"b"}, 1, batchErr},
+			expectedOutput:     "a\nb\nc\nd\n",
+		},
+		{
+			name:               "returns 124 and stops when a batch returns 255",
+			statusCodes:        map[string]int{"b": 255},
+			expectedStatusCode: pipe.XargsStatusCommandStopped,
+			expectedErr:        pipe.ErrXargsBatchFailed{[]string{"b"}, 255, batchErr},
+			expectedOutput:     "a\nb\n",
+		},
+		{
+			name:               "returns 125 and stops when a batch is killed",
+			statusCodes:        map[string]int{"a": 1, "c": 130},
+			expectedStatusCode: pipe.XargsStatusCommandKilled,
+			expectedErr:        pipe.ErrXargsBatchFailed{[]string{"a"}, 1, batchErr},
+			expectedOutput:     "a\nb\nc\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// ----------------------------------------------------------------
+			// setup your test
+
+			unit := pipe.NewPipe()
+			unit.SetStdinFromString("a b c d\n")
+
+			builder := func(args []string) pipe.PipeCommand {
+				return func(p *pipe.Pipe) (int, error) {
+					p.Stdout.WriteString(args[0] + "\n")
+					statusCode := testCase.statusCodes[args[0]]
+					if statusCode != pipe.StatusOkay {
+						return statusCode, batchErr
+					}
+					return pipe.StatusOkay, nil
+				}
+			}
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			unit.RunCommand(pipe.Xargs(builder, pipe.XargsOptions{MaxArgs: 1}))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			statusCode, err := unit.StatusError()
+			assert.Equal(t, testCase.expectedStatusCode, statusCode)
+			assert.Equal(t, testCase.expectedErr, err)
+			assert.True(t, errors.Is(err, batchErr))
+			assert.Equal(t, testCase.expectedOutput, unit.Stdout.String())
+		})
+	}
+}
+
+func TestXargsReturnsErrorForUnmatchedQuotes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.NewPipe()
+	unit.SetStdinFromString("one 'two\n")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.RunCommand(pipe.Xargs(echoArgs, pipe.XargsOptions{}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	statusCode, err := unit.StatusError()
+	assert.Equal(t, pipe.StatusNotOkay, statusCode)
+	assert.Equal(t, pipe.ErrXargsUnmatchedQuote{'\''}, err)
+	assert.Equal(t, "", unit.Stdout.String())
+}
+
+func TestXargsCopesWithNilPipePointer(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := pipe.Xargs(echoArgs, pipe.XargsOptions{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	statusCode, err := unit(nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, pipe.StatusOkay, statusCode)
+	assert.Nil(t, err)
+}